@@ -0,0 +1,64 @@
+// Package cli contains what the commands that generate output from a TypeSet have in common.
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+)
+
+// Run parses the command line, loads the TypeSet that is named by its only argument, and calls the given
+// generate function with that TypeSet. Flags that are specific to a command must be declared before Run
+// is called.
+//
+// The TypeSet is loaded from the types directory of the directory given by the -dir flag, so that the
+// TypeSet My::Own is loaded from <dir>/types/my/own.pp. The generated output is written to the file given
+// by the -out flag or to standard output. The process exits with status 2 when the command line is invalid
+// and with status 1 when an error occurs.
+func Run(generate func(c px.Context, ts px.TypeSet, out io.Writer)) {
+	dir := flag.String(`dir`, `.`, `directory that contains the types directory`)
+	out := flag.String(`out`, ``, `file to write the output to (default is standard output)`)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <TypeSet name>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	err := pcore.Try(func(c px.Context) (err error) {
+		c.DoWithLoader(px.NewFileBasedLoader(c.Loader(), *dir, ``, px.PuppetDataTypePath), func() {
+			t, ok := px.Load(c, px.NewTypedName(px.NsType, name))
+			if !ok {
+				err = fmt.Errorf(`unable to find type '%s' in %s`, name, *dir)
+				return
+			}
+			ts, ok := t.(px.TypeSet)
+			if !ok {
+				err = fmt.Errorf(`type '%s' is not a TypeSet`, name)
+				return
+			}
+			b := bytes.NewBufferString(``)
+			generate(c, ts, b)
+			if *out == `` {
+				_, err = os.Stdout.Write(b.Bytes())
+			} else {
+				err = ioutil.WriteFile(*out, b.Bytes(), 0644)
+			}
+		})
+		return
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
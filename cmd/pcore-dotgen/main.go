@@ -0,0 +1,21 @@
+// Command pcore-dotgen generates a Graphviz DOT graph for a TypeSet that is declared in a Puppet type file.
+//
+// Usage:
+//
+//	pcore-dotgen [-dir <dir>] [-out <file>] <TypeSet name>
+//
+// The TypeSet is loaded from the types directory of the given directory, so that the TypeSet My::Own
+// is loaded from <dir>/types/my/own.pp. TypeSets that it references are loaded the same way. The graph
+// is written to standard output unless an output file is given. It can be rendered using Graphviz, e.g.
+//
+//	pcore-dotgen My::Own | dot -Tsvg -o own.svg
+package main
+
+import (
+	"github.com/lyraproj/pcore/cmd/internal/cli"
+	"github.com/lyraproj/pcore/dotgen"
+)
+
+func main() {
+	cli.Run(dotgen.Generate)
+}
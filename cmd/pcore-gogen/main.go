@@ -0,0 +1,31 @@
+// Command pcore-gogen generates Go source code for a TypeSet that is declared in a Puppet type file.
+//
+// Usage:
+//
+//	pcore-gogen [-dir <dir>] [-package <name>] [-out <file>] <TypeSet name>
+//
+// The TypeSet is loaded from the types directory of the given directory, so that the TypeSet My::Own
+// is loaded from <dir>/types/my/own.pp. The generated code is written to standard output unless an
+// output file is given.
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/pcore/cmd/internal/cli"
+	"github.com/lyraproj/pcore/gogen"
+	"github.com/lyraproj/pcore/px"
+)
+
+func main() {
+	pkg := flag.String(`package`, ``, `name of the generated package (default is the last segment of the TypeSet name in lower case)`)
+	cli.Run(func(c px.Context, ts px.TypeSet, out io.Writer) {
+		if *pkg == `` {
+			segments := strings.Split(ts.Name(), `::`)
+			*pkg = strings.ToLower(segments[len(segments)-1])
+		}
+		gogen.Generate(c, ts, *pkg, out)
+	})
+}
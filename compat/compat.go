@@ -0,0 +1,364 @@
+// Package compat analyzes the compatibility between two versions of a TypeSet.
+//
+// Compare classifies each difference between the types of an old and a new version of a TypeSet and tells
+// whether the difference breaks readers or writers. A reader uses the new version to read data that was
+// written using the old version. A writer uses the new version to write data that is read using the old
+// version. A change that breaks readers is not backwards compatible and requires a new major version. A change
+// that only breaks writers, such as an added attribute, requires a new minor version.
+//
+// Types are compared using px.IsAssignable. Named types that are used in attribute types are compared by
+// name, i.e. a named type of the old version is considered equal to the type with the same name in the new
+// version. Changes to such types are reported on the named type itself.
+package compat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+)
+
+// Bump is a semantic version increment. There is no patch increment since every change that Compare reports
+// breaks readers or writers.
+type Bump int
+
+const (
+	None = Bump(iota)
+	Minor
+	Major
+)
+
+func (b Bump) String() string {
+	switch b {
+	case Major:
+		return `major`
+	case Minor:
+		return `minor`
+	default:
+		return `none`
+	}
+}
+
+// Kind classifies a Change
+type Kind string
+
+const (
+	TypeAdded              = Kind(`type added`)
+	TypeRemoved            = Kind(`type removed`)
+	TypeNarrowed           = Kind(`type narrowed`)
+	TypeWidened            = Kind(`type widened`)
+	TypeChanged            = Kind(`type changed`)
+	AttributeAdded         = Kind(`attribute added`)
+	RequiredAttributeAdded = Kind(`required attribute added`)
+	AttributeRemoved       = Kind(`attribute removed`)
+	EnumValueAdded         = Kind(`enum value added`)
+	EnumValueRemoved       = Kind(`enum value removed`)
+)
+
+// A Change is a difference between two versions of a type or one of its attributes
+type Change struct {
+	Kind Kind
+
+	// Type is the name of the type, relative to the TypeSet
+	Type string
+
+	// Attribute is the name of the attribute or empty when the change concerns the type itself
+	Attribute string
+
+	// Detail describes the change, e.g. the old and the new type or the enum value that was removed
+	Detail string
+
+	// BreaksReaders is true when data written using the old version might not be readable using the new
+	BreaksReaders bool
+
+	// BreaksWriters is true when data written using the new version might not be readable using the old
+	BreaksWriters bool
+}
+
+func (c *Change) String() string {
+	b := strings.Builder{}
+	b.WriteString(c.Type)
+	if c.Attribute != `` {
+		b.WriteByte('.')
+		b.WriteString(c.Attribute)
+	}
+	b.WriteString(`: `)
+	b.WriteString(string(c.Kind))
+	if c.Detail != `` {
+		b.WriteString(`, `)
+		b.WriteString(c.Detail)
+	}
+	switch {
+	case c.BreaksReaders && c.BreaksWriters:
+		b.WriteString(` (breaks readers and writers)`)
+	case c.BreaksReaders:
+		b.WriteString(` (breaks readers)`)
+	case c.BreaksWriters:
+		b.WriteString(` (breaks writers)`)
+	}
+	return b.String()
+}
+
+// A Report is the result of comparing two versions of a TypeSet
+type Report struct {
+	OldVersion semver.Version
+	NewVersion semver.Version
+	Changes    []*Change
+
+	// newTypes are the types of the new version keyed by their full names
+	newTypes map[string]px.Type
+}
+
+// Compare returns a Report with the changes between the old and the new version of a TypeSet. Types that
+// are present in the old version are compared first, in the order they are declared, followed by the types
+// that were added.
+func Compare(old, new px.TypeSet) *Report {
+	r := &Report{OldVersion: old.Version(), NewVersion: new.Version(), newTypes: make(map[string]px.Type)}
+	oldTypes := old.Types()
+	newTypes := new.Types()
+	newTypes.EachValue(func(v px.Value) {
+		t := v.(px.Type)
+		r.newTypes[t.Name()] = t
+	})
+	oldTypes.EachPair(func(k, v px.Value) {
+		if nv, ok := newTypes.Get(k); ok {
+			r.compareNamed(k.String(), v.(px.Type), nv.(px.Type))
+		} else {
+			r.add(&Change{Kind: TypeRemoved, Type: k.String(), BreaksReaders: true})
+		}
+	})
+	newTypes.EachPair(func(k, v px.Value) {
+		if !oldTypes.IncludesKey(k) {
+			r.add(&Change{Kind: TypeAdded, Type: k.String(), BreaksWriters: true})
+		}
+	})
+	return r
+}
+
+// Bump returns the minimum version increment that the changes require. The Bump is None when there are no
+// changes.
+func (r *Report) Bump() Bump {
+	b := None
+	for _, c := range r.Changes {
+		if c.BreaksReaders {
+			return Major
+		}
+		if c.BreaksWriters {
+			b = Minor
+		}
+	}
+	return b
+}
+
+// SuggestedVersion returns the old version incremented by the Bump
+func (r *Report) SuggestedVersion() semver.Version {
+	o := r.OldVersion
+	var v semver.Version
+	switch r.Bump() {
+	case Major:
+		v, _ = semver.NewVersion(o.Major()+1, 0, 0)
+	case Minor:
+		v, _ = semver.NewVersion(o.Major(), o.Minor()+1, 0)
+	default:
+		v = o
+	}
+	return v
+}
+
+// VersionSufficient returns true if the new version is greater than or equal to the SuggestedVersion
+func (r *Report) VersionSufficient() bool {
+	return r.NewVersion.CompareTo(r.SuggestedVersion()) >= 0
+}
+
+func (r *Report) add(c *Change) {
+	r.Changes = append(r.Changes, c)
+}
+
+// compareNamed compares two versions of a type that is declared by the TypeSet
+func (r *Report) compareNamed(name string, old, new px.Type) {
+	switch ot := old.(type) {
+	case px.ObjectType:
+		if nt, ok := new.(px.ObjectType); ok {
+			r.compareObjects(name, ot, nt)
+			return
+		}
+	case *types.TypeAliasType:
+		if nt, ok := new.(*types.TypeAliasType); ok {
+			r.compareTypes(name, ``, ot.ResolvedType(), nt.ResolvedType())
+			return
+		}
+	default:
+		r.compareTypes(name, ``, old, new)
+		return
+	}
+	r.add(&Change{Kind: TypeChanged, Type: name, Detail: describe(old, new), BreaksReaders: true, BreaksWriters: true})
+}
+
+// compareObjects compares the attributes of two versions of an Object type. Inherited attributes are only
+// compared when they are inherited from different types in the two versions. Otherwise, the change is reported
+// on the parent.
+func (r *Report) compareObjects(name string, old, new px.ObjectType) {
+	oas := old.AttributesInfo().Attributes()
+	nas := new.AttributesInfo().Attributes()
+	nm := make(map[string]px.Attribute, len(nas))
+	for _, a := range nas {
+		nm[a.Name()] = a
+	}
+	om := make(map[string]px.Attribute, len(oas))
+	for _, a := range oas {
+		om[a.Name()] = a
+		na, ok := nm[a.Name()]
+		if !ok {
+			r.add(&Change{Kind: AttributeRemoved, Type: name, Attribute: a.Name(), BreaksReaders: true, BreaksWriters: !a.HasValue()})
+			continue
+		}
+		if inherited(old, a) && inherited(new, na) && a.Container().Name() == na.Container().Name() {
+			continue
+		}
+		r.compareTypes(name, a.Name(), attributeType(a), attributeType(na))
+	}
+	for _, a := range nas {
+		if _, ok := om[a.Name()]; ok {
+			continue
+		}
+		if a.HasValue() {
+			r.add(&Change{Kind: AttributeAdded, Type: name, Attribute: a.Name(), BreaksWriters: true})
+		} else {
+			r.add(&Change{Kind: RequiredAttributeAdded, Type: name, Attribute: a.Name(), BreaksReaders: true, BreaksWriters: true})
+		}
+	}
+}
+
+// compareTypes classifies the difference between two types. A narrowed type breaks readers since data
+// written using the old type might not be an instance of the new type. A widened type breaks writers for the
+// opposite reason.
+func (r *Report) compareTypes(name, attr string, old, new px.Type) {
+	if old.String() == new.String() {
+		return
+	}
+	if oe, oo := enumType(old); oe != nil {
+		if ne, no := enumType(new); ne != nil && oo == no {
+			r.compareEnums(name, attr, oe, ne)
+			return
+		}
+	}
+	c := &Change{Type: name, Attribute: attr, Detail: describe(old, new)}
+	renamed := r.byName(old)
+	c.BreaksReaders = !px.IsAssignable(new, renamed)
+	c.BreaksWriters = !px.IsAssignable(renamed, new)
+	switch {
+	case c.BreaksReaders && c.BreaksWriters:
+		c.Kind = TypeChanged
+	case c.BreaksReaders:
+		c.Kind = TypeNarrowed
+	case c.BreaksWriters:
+		c.Kind = TypeWidened
+	default:
+		return
+	}
+	r.add(c)
+}
+
+// byName returns the given type of the old version with the named types that it references replaced by the
+// types with the same names in the new version
+func (r *Report) byName(t px.Type) px.Type {
+	switch tt := t.(type) {
+	case px.ObjectType, *types.TypeAliasType:
+		if nt, ok := r.newTypes[t.Name()]; ok {
+			return nt
+		}
+	case *types.OptionalType:
+		return types.NewOptionalType(r.byName(tt.ContainedType()))
+	case *types.NotUndefType:
+		return types.NewNotUndefType(r.byName(tt.ContainedType()))
+	case *types.TypeType:
+		return types.NewTypeType(r.byName(tt.ContainedType()))
+	case *types.ArrayType:
+		return types.NewArrayType(r.byName(tt.ElementType()), tt.Size())
+	case *types.HashType:
+		return types.NewHashType(r.byName(tt.KeyType()), r.byName(tt.ValueType()), tt.Size())
+	case *types.TupleType:
+		return types.NewTupleType(r.byNames(tt.Types()), tt.Size())
+	case *types.VariantType:
+		return types.NewVariantType(r.byNames(tt.Types())...)
+	case *types.StructType:
+		es := make([]*types.StructElement, len(tt.Elements()))
+		for i, e := range tt.Elements() {
+			es[i] = types.NewStructElement(e.Key(), r.byName(e.Value()))
+		}
+		return types.NewStructType(es)
+	}
+	return t
+}
+
+func (r *Report) byNames(ts []px.Type) []px.Type {
+	rs := make([]px.Type, len(ts))
+	for i, t := range ts {
+		rs[i] = r.byName(t)
+	}
+	return rs
+}
+
+// compareEnums reports each value that was removed from or added to an Enum
+func (r *Report) compareEnums(name, attr string, old, new *types.EnumType) {
+	for _, s := range difference(old, new) {
+		r.add(&Change{Kind: EnumValueRemoved, Type: name, Attribute: attr, Detail: fmt.Sprintf(`'%s'`, s), BreaksReaders: true})
+	}
+	for _, s := range difference(new, old) {
+		r.add(&Change{Kind: EnumValueAdded, Type: name, Attribute: attr, Detail: fmt.Sprintf(`'%s'`, s), BreaksWriters: true})
+	}
+}
+
+// difference returns the strings of enum a that are not instances of enum b, sorted alphabetically
+func difference(a, b *types.EnumType) []string {
+	var d []string
+	for _, s := range a.Strings() {
+		if !b.IsInstance(types.WrapString(s), nil) {
+			d = append(d, s)
+		}
+	}
+	sort.Strings(d)
+	return d
+}
+
+// enumType returns the Enum of the given type and whether that Enum is optional, or nil if the type isn't an
+// Enum or an Optional Enum
+func enumType(t px.Type) (*types.EnumType, bool) {
+	optional := false
+	for {
+		switch tt := t.(type) {
+		case *types.TypeAliasType:
+			t = tt.ResolvedType()
+		case *types.OptionalType:
+			if optional {
+				return nil, false
+			}
+			optional = true
+			t = tt.ContainedType()
+		case *types.EnumType:
+			return tt, optional
+		default:
+			return nil, false
+		}
+	}
+}
+
+// attributeType returns the type of the given attribute. The type is optional when the attribute has a
+// value since the attribute can then be omitted.
+func attributeType(a px.Attribute) px.Type {
+	if a.HasValue() && !px.IsInstance(a.Type(), px.Undef) {
+		return types.NewOptionalType(a.Type())
+	}
+	return a.Type()
+}
+
+func inherited(t px.ObjectType, a px.Attribute) bool {
+	return a.Container().Name() != t.Name()
+}
+
+func describe(old, new px.Type) string {
+	return fmt.Sprintf(`%s to %s`, old, new)
+}
@@ -0,0 +1,122 @@
+package compat_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/pcore/compat"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+)
+
+func ExampleCompare() {
+	pcore.Do(func(c px.Context) {
+		old := c.ParseType(`TypeSet[{
+      name => 'My',
+      pcore_version => '1.0.0',
+      version => '1.2.0',
+      types => {
+        Color => Enum[red, green, blue],
+        Entity => { attributes => { id => String }},
+        Person => Entity{
+          attributes => {
+            name => String,
+            age => Integer,
+            favorite => Color,
+            nick => Optional[String]
+          }
+        },
+        Legacy => { attributes => { x => Integer }}
+      }
+    }]`).(px.ResolvableType).Resolve(c).(px.TypeSet)
+
+		new := c.ParseType(`TypeSet[{
+      name => 'My',
+      pcore_version => '1.0.0',
+      version => '1.3.0',
+      types => {
+        Color => Enum[red, blue, yellow],
+        Entity => { attributes => { id => String[1] }},
+        Person => Entity{
+          attributes => {
+            name => String,
+            age => Integer[0],
+            favorite => Color,
+            email => String,
+            tags => { type => Array[String], value => [] },
+            nick => Variant[String, Integer, Undef]
+          }
+        },
+        Address => { attributes => { street => String }}
+      }
+    }]`).(px.ResolvableType).Resolve(c).(px.TypeSet)
+
+		r := compat.Compare(old, new)
+		for _, ch := range r.Changes {
+			fmt.Println(ch)
+		}
+		fmt.Println(r.Bump(), r.SuggestedVersion(), r.VersionSufficient())
+	})
+	// Output:
+	// Color: enum value removed, 'green' (breaks readers)
+	// Color: enum value added, 'yellow' (breaks writers)
+	// Entity.id: type narrowed, String to String[1] (breaks readers)
+	// Person.age: type narrowed, Integer to Integer[0] (breaks readers)
+	// Person.nick: type widened, Optional[String] to Variant[String, Integer, Undef] (breaks writers)
+	// Person.email: required attribute added (breaks readers and writers)
+	// Person.tags: attribute added (breaks writers)
+	// Legacy: type removed (breaks readers)
+	// Address: type added (breaks writers)
+	// major 2.0.0 false
+}
+
+func ExampleReport_Bump() {
+	pcore.Do(func(c px.Context) {
+		typeSet := func(version, attributes string) px.TypeSet {
+			return c.ParseType(fmt.Sprintf(`TypeSet[{
+        name => 'My',
+        pcore_version => '1.0.0',
+        version => '%s',
+        types => { Point => { attributes => %s }}
+      }]`, version, attributes)).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		}
+		v1 := typeSet(`1.0.0`, `{ x => Integer, y => Integer }`)
+		for _, v2 := range []px.TypeSet{
+			typeSet(`1.0.0`, `{ x => Integer, y => Integer }`),
+			typeSet(`1.1.0`, `{ x => Integer, y => Integer, z => { type => Integer, value => 0 }}`),
+			typeSet(`1.1.0`, `{ x => Integer }`),
+		} {
+			r := compat.Compare(v1, v2)
+			fmt.Println(r.Bump(), r.SuggestedVersion(), r.VersionSufficient())
+		}
+	})
+	// Output:
+	// none 1.0.0 true
+	// minor 1.1.0 true
+	// major 2.0.0 false
+}
+
+func ExampleCompare_namedTypes() {
+	pcore.Do(func(c px.Context) {
+		typeSet := func(version, address, customer string) px.TypeSet {
+			return c.ParseType(fmt.Sprintf(`TypeSet[{
+        name => 'My',
+        pcore_version => '1.0.0',
+        version => '%s',
+        types => {
+          Address => { attributes => %s },
+          Customer => { attributes => %s }
+        }
+      }]`, version, address, customer)).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		}
+		old := typeSet(`1.0.0`, `{ street => String }`, `{ billing => Address, shipping => Optional[Address] }`)
+		new := typeSet(`2.0.0`, `{ street => String, zip => { type => String, value => '' }}`,
+			`{ billing => Optional[Address], shipping => Address }`)
+		for _, ch := range compat.Compare(old, new).Changes {
+			fmt.Println(ch)
+		}
+	})
+	// Output:
+	// Address.zip: attribute added (breaks writers)
+	// Customer.billing: type widened, My::Address to Optional[My::Address] (breaks writers)
+	// Customer.shipping: type narrowed, Optional[My::Address] to My::Address (breaks readers)
+}
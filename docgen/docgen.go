@@ -0,0 +1,446 @@
+// Package docgen generates reference documentation for pcore types.
+//
+// The documentation consists of one page per type and an index page. A TypeSet gets a page that lists its
+// members, and each member gets a page of its own. The page of an Object type shows its inheritance chain,
+// the known subtypes among the documented types, and tables of its attributes and constants with their
+// types, kinds, and values. The signatures of its functions are shown as Callable types. Type aliases are
+// documented with the type that they resolve to.
+//
+// The `description` tag of the TagsAnnotation of a type or a function is rendered as paragraphs below its
+// heading, and the first sentence of the description of a type summarizes it in the index and in the member
+// list of its TypeSet. The description of an attribute or a constant is shown in its table row. Other tags
+// are listed below the description.
+//
+// All references to documented types are rendered as links to their pages. Pages can be produced in
+// Markdown or HTML.
+package docgen
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// Format is the format of the generated pages
+type Format string
+
+const (
+	// Markdown pages use the GitHub flavor of Markdown
+	Markdown = Format(`md`)
+
+	// HTML pages are complete HTML documents without external style sheets
+	HTML = Format(`html`)
+)
+
+// Docs is the documentation of a set of types
+type Docs interface {
+	// Names returns the names of all documented types in the order that their pages appear in the index
+	Names() []string
+
+	// FileName returns the name of the file that contains the page for the type with the given name
+	FileName(name string) string
+
+	// WriteIndex writes the index page to the given writer
+	WriteIndex(out io.Writer)
+
+	// WritePage writes the page for the type with the given name to the given writer
+	WritePage(name string, out io.Writer)
+
+	// WriteFiles writes the index page and all type pages to the given directory and returns the paths
+	// of the written files. The index page is written to the file index.md or index.html.
+	WriteFiles(dir string) []string
+}
+
+type docs struct {
+	c      px.Context
+	format Format
+	names  []string
+	types  map[string]px.Type
+
+	// subtypes maps the name of an Object type to the names of the documented types that extend it
+	subtypes map[string][]string
+}
+
+// New creates the documentation for the given types. The members of a TypeSet are documented together
+// with the TypeSet. Types that aren't type aliases, Object types, or TypeSets are ignored.
+func New(c px.Context, format Format, ts ...px.Type) Docs {
+	switch format {
+	case Markdown, HTML:
+	default:
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `docgen.New`, `index`: 1, `arg`: `unknown format '` + string(format) + `'`}))
+	}
+	d := &docs{c: c, format: format, types: make(map[string]px.Type), subtypes: make(map[string][]string)}
+	for _, t := range ts {
+		d.add(t)
+	}
+	for _, n := range d.names {
+		if ot, ok := d.types[n].(px.ObjectType); ok {
+			if pt, ok := ot.Parent().(px.ObjectType); ok {
+				d.subtypes[pt.Name()] = append(d.subtypes[pt.Name()], n)
+			}
+		}
+	}
+	return d
+}
+
+// Discover returns the type aliases, Object types, and TypeSets that the given loader and its parents can
+// find and that are accepted by the given predicate. The returned types are sorted by name.
+func Discover(c px.Context, l px.Loader, predicate func(tn px.TypedName) bool) []px.Type {
+	tns := l.Discover(c, func(tn px.TypedName) bool { return tn.Namespace() == px.NsType && predicate(tn) })
+	sort.Slice(tns, func(i, j int) bool { return tns[i].Name() < tns[j].Name() })
+	ts := make([]px.Type, 0, len(tns))
+	for _, tn := range tns {
+		if v, ok := px.Load(c, tn); ok {
+			if t, ok := v.(px.Type); ok && documented(t) {
+				ts = append(ts, t)
+			}
+		}
+	}
+	return ts
+}
+
+func documented(t px.Type) bool {
+	switch t.(type) {
+	case px.TypeSet, px.ObjectType, *types.TypeAliasType:
+		return t.Name() != ``
+	}
+	return false
+}
+
+func (d *docs) add(t px.Type) {
+	if !documented(t) {
+		return
+	}
+	if _, ok := d.types[t.Name()]; ok {
+		return
+	}
+	d.names = append(d.names, t.Name())
+	d.types[t.Name()] = t
+	if ts, ok := t.(px.TypeSet); ok {
+		ts.Types().EachValue(func(v px.Value) { d.add(v.(px.Type)) })
+	}
+}
+
+func (d *docs) Names() []string {
+	return d.names
+}
+
+func (d *docs) FileName(name string) string {
+	return strings.ToLower(strings.Replace(strings.TrimPrefix(name, `::`), `::`, `-`, -1)) + `.` + string(d.format)
+}
+
+func (d *docs) WriteIndex(out io.Writer) {
+	r := d.newRenderer(`Types`)
+	r.heading(1, `Types`)
+	items := make([]inline, len(d.names))
+	for i, n := range d.names {
+		items[i] = append(inline{d.link(n), {text: ` – `}}, d.summary(d.types[n])...)
+	}
+	r.list(items)
+	r.write(out)
+}
+
+func (d *docs) WritePage(name string, out io.Writer) {
+	t, ok := d.types[name]
+	if !ok {
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `WritePage`, `index`: 1, `arg`: `type '` + name + `' is not documented`}))
+	}
+	r := d.newRenderer(name)
+	r.heading(1, name)
+	switch t := t.(type) {
+	case px.TypeSet:
+		d.typeSetPage(r, t)
+	case px.ObjectType:
+		d.objectPage(r, t)
+	case *types.TypeAliasType:
+		d.aliasPage(r, t)
+	}
+	r.write(out)
+}
+
+func (d *docs) WriteFiles(dir string) []string {
+	paths := make([]string, 0, len(d.names)+1)
+	write := func(fileName string, writer func(io.Writer)) {
+		path := filepath.Join(dir, fileName)
+		f, err := os.Create(path)
+		if err == nil {
+			writer(f)
+			err = f.Close()
+		}
+		if err != nil {
+			panic(px.Error(px.UnableToWriteFile, issue.H{`path`: path, `detail`: err.Error()}))
+		}
+		paths = append(paths, path)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic(px.Error(px.UnableToWriteFile, issue.H{`path`: dir, `detail`: err.Error()}))
+	}
+	write(`index.`+string(d.format), d.WriteIndex)
+	for _, n := range d.names {
+		name := n
+		write(d.FileName(name), func(out io.Writer) { d.WritePage(name, out) })
+	}
+	return paths
+}
+
+func (d *docs) typeSetPage(r renderer, t px.TypeSet) {
+	d.annotations(r, t)
+	r.paragraph(inline{{text: `Version: `}, {text: t.Version().String(), code: true}})
+
+	r.heading(2, `Types`)
+	var rows [][]inline
+	t.Types().EachPair(func(k, v px.Value) {
+		rows = append(rows, []inline{{d.link(v.(px.Type).Name())}, d.summary(v.(px.Type))})
+	})
+	r.table([]string{`Name`, `Description`}, rows)
+
+	if rh, ok := t.(px.PuppetObject).InitHash().Get4(`references`); ok {
+		r.heading(2, `References`)
+		rows = nil
+		rh.(px.OrderedMap).EachPair(func(k, v px.Value) {
+			ref := v.(px.OrderedMap)
+			rows = append(rows, []inline{
+				{{text: k.String(), code: true}},
+				{{text: ref.Get5(`name`, px.EmptyString).String()}},
+				{{text: ref.Get5(`version_range`, px.EmptyString).String(), code: true}}})
+		})
+		r.table([]string{`Alias`, `TypeSet`, `Version range`}, rows)
+	}
+}
+
+func (d *docs) aliasPage(r renderer, t *types.TypeAliasType) {
+	r.paragraph(append(inline{{text: `Alias of `}}, d.typeRef(t.ResolvedType())...))
+}
+
+func (d *docs) objectPage(r renderer, t px.ObjectType) {
+	d.annotations(r, t)
+
+	var chain []px.ObjectType
+	for p := t.Parent(); p != nil; {
+		pt, ok := p.(px.ObjectType)
+		if !ok {
+			break
+		}
+		chain = append([]px.ObjectType{pt}, chain...)
+		p = pt.Parent()
+	}
+	if len(chain) > 0 || len(d.subtypes[t.Name()]) > 0 {
+		r.heading(2, `Inheritance`)
+		var in inline
+		for _, pt := range chain {
+			in = append(in, d.nameRef(pt.Name()), span{text: ` → `})
+		}
+		r.paragraph(append(in, span{text: t.Name(), strong: true}))
+		if sts := d.subtypes[t.Name()]; len(sts) > 0 {
+			in = inline{{text: `Known subtypes: `}}
+			for i, st := range sts {
+				if i > 0 {
+					in = append(in, span{text: `, `})
+				}
+				in = append(in, d.link(st))
+			}
+			r.paragraph(in)
+		}
+	}
+
+	var attrs, consts [][]inline
+	for _, a := range members(t) {
+		switch a.Kind() {
+		case `constant`:
+			consts = append(consts, []inline{{{text: a.Name(), code: true}}, d.typeRef(a.Type()), valueRef(a.Value()), d.description(a)})
+		default:
+			var dv inline
+			if a.HasValue() {
+				dv = valueRef(a.Value())
+			}
+			attrs = append(attrs, []inline{{{text: a.Name(), code: true}}, d.typeRef(a.Type()), {{text: string(a.Kind())}}, dv, d.description(a)})
+		}
+	}
+	if len(attrs) > 0 {
+		r.heading(2, `Attributes`)
+		r.table([]string{`Name`, `Type`, `Kind`, `Default`, `Description`}, attrs)
+	}
+
+	var inherited inline
+	for _, pt := range chain {
+		for _, a := range members(pt) {
+			if a.Kind() == `constant` {
+				continue
+			}
+			if len(inherited) > 0 {
+				inherited = append(inherited, span{text: `, `})
+			}
+			inherited = append(inherited, span{text: a.Name(), code: true}, span{text: ` from `}, d.nameRef(pt.Name()))
+		}
+	}
+	if len(inherited) > 0 {
+		r.paragraph(append(inline{{text: `Inherited attributes: `}}, inherited...))
+	}
+
+	if len(consts) > 0 {
+		r.heading(2, `Constants`)
+		r.table([]string{`Name`, `Type`, `Value`, `Description`}, consts)
+	}
+
+	if fs := t.Functions(false); len(fs) > 0 {
+		r.heading(2, `Functions`)
+		for _, f := range fs {
+			r.heading(3, f.Name())
+			d.annotations(r, f)
+			r.code(f.Type().String())
+		}
+	}
+}
+
+// members returns the attributes and constants that are declared by the given type in declaration order
+func members(t px.ObjectType) []px.Attribute {
+	var as []px.Attribute
+	ih := t.(px.PuppetObject).InitHash()
+	for _, key := range []string{`attributes`, `constants`} {
+		if ah, ok := ih.Get4(key); ok {
+			ah.(px.OrderedMap).EachKey(func(k px.Value) {
+				if m, ok := t.Member(k.String()); ok {
+					if a, ok := m.(px.Attribute); ok {
+						as = append(as, a)
+					}
+				}
+			})
+		}
+	}
+	return as
+}
+
+// tagsAnnotation returns the TagsAnnotation of the given type or member
+func (d *docs) tagsAnnotation(v interface{}) (px.TagsAnnotation, bool) {
+	if a, ok := v.(px.Annotatable); ok {
+		if ta, ok := a.Annotations(d.c).Get(types.TagsAnnotationType); ok {
+			return ta.(px.TagsAnnotation), true
+		}
+		return nil, false
+	}
+	// A TypeSet is not Annotatable. Its annotations must be resolved from its init hash
+	if po, ok := v.(px.PuppetObject); ok {
+		if ah, ok := po.InitHash().Get4(`annotations`); ok {
+			if th, ok := ah.(px.OrderedMap).Get(types.TagsAnnotationType); ok {
+				return px.New(d.c, types.TagsAnnotationType, th).(px.TagsAnnotation), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// annotations renders the description and the other tags of the TagsAnnotation of the given type or member
+func (d *docs) annotations(r renderer, v interface{}) {
+	ta, ok := d.tagsAnnotation(v)
+	if !ok {
+		return
+	}
+	tags := ta.Tags()
+	if desc, ok := tags.Get4(`description`); ok {
+		for _, p := range strings.Split(desc.String(), "\n\n") {
+			r.paragraph(inline{{text: p}})
+		}
+	}
+	var items []inline
+	tags.EachPair(func(k, v px.Value) {
+		if k.String() != `description` {
+			items = append(items, inline{{text: k.String(), code: true}, {text: `: ` + v.String()}})
+		}
+	})
+	if len(items) > 0 {
+		r.list(items)
+	}
+}
+
+// description returns the description of the given type or member as an inline
+func (d *docs) description(v interface{}) inline {
+	desc := ``
+	if a, ok := v.(px.Annotatable); ok {
+		desc = types.Description(d.c, a)
+	} else if ta, ok := d.tagsAnnotation(v); ok {
+		desc = ta.Tag(`description`)
+	}
+	if desc == `` {
+		return nil
+	}
+	return inline{{text: strings.Replace(desc, "\n", ` `, -1)}}
+}
+
+// summary returns the first sentence of the description of the given type, or the kind of the type when
+// it has no description
+func (d *docs) summary(t px.Type) inline {
+	if in := d.description(t); in != nil {
+		s := in[0].text
+		if i := strings.Index(s, `. `); i > 0 {
+			s = s[:i+1]
+		}
+		return inline{{text: s}}
+	}
+	switch t.(type) {
+	case px.TypeSet:
+		return inline{{text: `TypeSet`}}
+	case px.ObjectType:
+		return inline{{text: `Object type`}}
+	}
+	return inline{{text: `Type alias`}}
+}
+
+// link returns a link to the page of the documented type with the given name
+func (d *docs) link(name string) span {
+	return span{text: name, href: d.FileName(name)}
+}
+
+// nameRef returns a link to the page of the type with the given name if that type is documented, or the
+// name as plain text
+func (d *docs) nameRef(name string) span {
+	if _, ok := d.types[name]; ok {
+		return d.link(name)
+	}
+	return span{text: name}
+}
+
+// typeToken matches quoted strings and regexps, which are skipped, and qualified type names
+var typeToken = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|/(?:[^/\\]|\\.)*/|(?:::)?[A-Z]\w*(?:::[A-Z]\w*)*`)
+
+// typeRef returns the string representation of the given type where the names of documented types are
+// links to their pages
+func (d *docs) typeRef(t px.Type) inline {
+	s := t.String()
+	var in inline
+	start := 0
+	for _, m := range typeToken.FindAllStringIndex(s, -1) {
+		name := s[m[0]:m[1]]
+		if _, ok := d.types[name]; ok {
+			if start < m[0] {
+				in = append(in, span{text: s[start:m[0]]})
+			}
+			in = append(in, d.link(name))
+			start = m[1]
+		}
+	}
+	if start < len(s) {
+		in = append(in, span{text: s[start:]})
+	}
+	return in
+}
+
+func valueRef(v px.Value) inline {
+	if sv, ok := v.(px.StringValue); ok {
+		b := bytes.NewBufferString(``)
+		utils.PuppetQuote(b, sv.String())
+		return inline{{text: b.String(), code: true}}
+	}
+	if f, ok := v.(px.Float); ok {
+		return inline{{text: px.ToString2(f, px.ExactFloats), code: true}}
+	}
+	return inline{{text: v.String(), code: true}}
+}
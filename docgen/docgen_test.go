@@ -0,0 +1,158 @@
+package docgen_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/pcore/docgen"
+	"github.com/lyraproj/pcore/loader"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+)
+
+const shop = `TypeSet[{
+  name => 'My::Shop',
+  pcore_version => '1.0.0',
+  version => '2.1.0',
+  annotations => { TagsAnnotation => { tags => { description => 'Types used by the shop' }}},
+  types => {
+    Sku => Pattern[/\A[A-Z]{3}-\d+\z/],
+    Item => {
+      annotations => { TagsAnnotation => { tags => { description => 'An item that can be ordered. It has a price.', owner => 'sales' }}},
+      attributes => {
+        sku => Sku,
+        price => { type => Float[0.0], value => 0.0,
+          annotations => { TagsAnnotation => { tags => { description => 'Price in | EUR' }}}
+        },
+        label => { type => String, kind => derived }
+      },
+      functions => {
+        discount => Callable[[Float[0.0, 1.0]], Float]
+      }
+    },
+    Book => Item{
+      attributes => {
+        title => String,
+        related => Array[Variant[Book, Sku]]
+      },
+      constants => {
+        category => 'book'
+      }
+    }
+  }
+}]`
+
+func ExampleNew() {
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(shop).(px.ResolvableType).Resolve(c)
+		d := docgen.New(c, docgen.Markdown, ts)
+		d.WriteIndex(os.Stdout)
+		d.WritePage(`My::Shop`, os.Stdout)
+		d.WritePage(`My::Shop::Item`, os.Stdout)
+		d.WritePage(`My::Shop::Book`, os.Stdout)
+	})
+	// Output:
+	// # Types
+	//
+	// - [My::Shop](my-shop.md) – Types used by the shop
+	// - [My::Shop::Sku](my-shop-sku.md) – Type alias
+	// - [My::Shop::Item](my-shop-item.md) – An item that can be ordered.
+	// - [My::Shop::Book](my-shop-book.md) – Object type
+	// # My::Shop
+	//
+	// Types used by the shop
+	//
+	// Version: `2.1.0`
+	//
+	// ## Types
+	//
+	// | Name | Description |
+	// |---|---|
+	// | [My::Shop::Sku](my-shop-sku.md) | Type alias |
+	// | [My::Shop::Item](my-shop-item.md) | An item that can be ordered. |
+	// | [My::Shop::Book](my-shop-book.md) | Object type |
+	// # My::Shop::Item
+	//
+	// An item that can be ordered. It has a price.
+	//
+	// - `owner`: sales
+	//
+	// ## Inheritance
+	//
+	// **My::Shop::Item**
+	//
+	// Known subtypes: [My::Shop::Book](my-shop-book.md)
+	//
+	// ## Attributes
+	//
+	// | Name | Type | Kind | Default | Description |
+	// |---|---|---|---|---|
+	// | `sku` | [My::Shop::Sku](my-shop-sku.md) |  |  |  |
+	// | `price` | Float\[0.00000\] |  | `0.0` | Price in \| EUR |
+	// | `label` | String | derived |  |  |
+	//
+	// ## Functions
+	//
+	// ### discount
+	//
+	// ```puppet
+	// Callable[[Float[0.00000, 1.00000]], Float]
+	// ```
+	// # My::Shop::Book
+	//
+	// ## Inheritance
+	//
+	// [My::Shop::Item](my-shop-item.md) → **My::Shop::Book**
+	//
+	// ## Attributes
+	//
+	// | Name | Type | Kind | Default | Description |
+	// |---|---|---|---|---|
+	// | `title` | String |  |  |  |
+	// | `related` | Array\[Variant\[[My::Shop::Book](my-shop-book.md), [My::Shop::Sku](my-shop-sku.md)\]\] |  |  |  |
+	//
+	// Inherited attributes: `sku` from [My::Shop::Item](my-shop-item.md), `price` from [My::Shop::Item](my-shop-item.md), `label` from [My::Shop::Item](my-shop-item.md)
+	//
+	// ## Constants
+	//
+	// | Name | Type | Value | Description |
+	// |---|---|---|---|
+	// | `category` | String | `'book'` |  |
+}
+
+func ExampleNew_html() {
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(shop).(px.ResolvableType).Resolve(c)
+		docgen.New(c, docgen.HTML, ts).WritePage(`My::Shop::Sku`, os.Stdout)
+	})
+	// Output:
+	// <!DOCTYPE html>
+	// <html>
+	// <head>
+	// <meta charset="utf-8">
+	// <title>My::Shop::Sku</title>
+	// </head>
+	// <body>
+	// <h1>My::Shop::Sku</h1>
+	// <p>Alias of Pattern[/\A[A-Z]{3}-\d+\z/]</p>
+	// </body>
+	// </html>
+}
+
+func ExampleDiscover() {
+	dir, err := ioutil.TempDir(``, `docgen`)
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pcore.Do(func(c px.Context) {
+		loader.WritePuppetTypeFile(c, dir, c.ParseType(shop).(px.ResolvableType).Resolve(c))
+		c.DoWithLoader(px.NewFileBasedLoader(c.Loader(), dir, ``, px.PuppetDataTypePath), func() {
+			ts := docgen.Discover(c, c.Loader(), func(tn px.TypedName) bool { return tn.Parts()[0] == `my` })
+			fmt.Println(docgen.New(c, docgen.Markdown, ts...).Names())
+		})
+	})
+	// Output: [My::Shop My::Shop::Sku My::Shop::Item My::Shop::Book]
+}
@@ -0,0 +1,215 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+// A span is a piece of text that is rendered as plain text, code, strong text, or a link
+type span struct {
+	text   string
+	href   string
+	code   bool
+	strong bool
+}
+
+// An inline is a sequence of spans that make up a paragraph, a list item, or a table cell
+type inline []span
+
+// A renderer renders the blocks of a page in a specific format
+type renderer interface {
+	heading(level int, text string)
+	paragraph(in inline)
+	list(items []inline)
+	table(headers []string, rows [][]inline)
+	code(text string)
+
+	// write writes the complete page to the given writer
+	write(out io.Writer)
+}
+
+func (d *docs) newRenderer(title string) renderer {
+	if d.format == HTML {
+		return &htmlRenderer{title: title}
+	}
+	return &markdownRenderer{}
+}
+
+type markdownRenderer struct {
+	b bytes.Buffer
+}
+
+// markdownEscaper escapes characters that have a special meaning in inline Markdown or in table cells
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `|`, `\|`)
+
+func (r *markdownRenderer) block() {
+	if r.b.Len() > 0 {
+		r.b.WriteByte('\n')
+	}
+}
+
+func (r *markdownRenderer) heading(level int, text string) {
+	r.block()
+	r.b.WriteString(strings.Repeat(`#`, level))
+	r.b.WriteByte(' ')
+	r.b.WriteString(markdownEscaper.Replace(text))
+	r.b.WriteByte('\n')
+}
+
+func (r *markdownRenderer) paragraph(in inline) {
+	r.block()
+	r.inline(in)
+	r.b.WriteByte('\n')
+}
+
+func (r *markdownRenderer) list(items []inline) {
+	r.block()
+	for _, in := range items {
+		r.b.WriteString(`- `)
+		r.inline(in)
+		r.b.WriteByte('\n')
+	}
+}
+
+func (r *markdownRenderer) table(headers []string, rows [][]inline) {
+	r.block()
+	r.b.WriteByte('|')
+	for _, h := range headers {
+		r.b.WriteByte(' ')
+		r.b.WriteString(markdownEscaper.Replace(h))
+		r.b.WriteString(` |`)
+	}
+	r.b.WriteString("\n|")
+	for range headers {
+		r.b.WriteString(`---|`)
+	}
+	r.b.WriteByte('\n')
+	for _, row := range rows {
+		r.b.WriteByte('|')
+		for _, cell := range row {
+			r.b.WriteByte(' ')
+			r.inline(cell)
+			r.b.WriteString(` |`)
+		}
+		r.b.WriteByte('\n')
+	}
+}
+
+func (r *markdownRenderer) code(text string) {
+	r.block()
+	r.b.WriteString("```puppet\n")
+	r.b.WriteString(text)
+	r.b.WriteString("\n```\n")
+}
+
+func (r *markdownRenderer) inline(in inline) {
+	for _, s := range in {
+		text := s.text
+		if s.code {
+			// A code span cannot contain a link and pipes must be escaped even in code spans within tables
+			text = "`" + strings.Replace(text, `|`, `\|`, -1) + "`"
+		} else {
+			text = markdownEscaper.Replace(text)
+		}
+		if s.strong {
+			text = `**` + text + `**`
+		}
+		if s.href != `` {
+			text = `[` + text + `](` + s.href + `)`
+		}
+		r.b.WriteString(text)
+	}
+}
+
+func (r *markdownRenderer) write(out io.Writer) {
+	writeBytes(out, r.b.Bytes())
+}
+
+type htmlRenderer struct {
+	title string
+	b     bytes.Buffer
+}
+
+func (r *htmlRenderer) heading(level int, text string) {
+	fmt.Fprintf(&r.b, "<h%d>%s</h%d>\n", level, html.EscapeString(text), level)
+}
+
+func (r *htmlRenderer) paragraph(in inline) {
+	r.b.WriteString(`<p>`)
+	r.inline(in)
+	r.b.WriteString("</p>\n")
+}
+
+func (r *htmlRenderer) list(items []inline) {
+	r.b.WriteString("<ul>\n")
+	for _, in := range items {
+		r.b.WriteString(`<li>`)
+		r.inline(in)
+		r.b.WriteString("</li>\n")
+	}
+	r.b.WriteString("</ul>\n")
+}
+
+func (r *htmlRenderer) table(headers []string, rows [][]inline) {
+	r.b.WriteString("<table>\n<tr>")
+	for _, h := range headers {
+		r.b.WriteString(`<th>`)
+		r.b.WriteString(html.EscapeString(h))
+		r.b.WriteString(`</th>`)
+	}
+	r.b.WriteString("</tr>\n")
+	for _, row := range rows {
+		r.b.WriteString(`<tr>`)
+		for _, cell := range row {
+			r.b.WriteString(`<td>`)
+			r.inline(cell)
+			r.b.WriteString(`</td>`)
+		}
+		r.b.WriteString("</tr>\n")
+	}
+	r.b.WriteString("</table>\n")
+}
+
+func (r *htmlRenderer) code(text string) {
+	r.b.WriteString(`<pre><code>`)
+	r.b.WriteString(html.EscapeString(text))
+	r.b.WriteString("</code></pre>\n")
+}
+
+func (r *htmlRenderer) inline(in inline) {
+	for _, s := range in {
+		text := html.EscapeString(s.text)
+		if s.code {
+			text = `<code>` + text + `</code>`
+		}
+		if s.strong {
+			text = `<strong>` + text + `</strong>`
+		}
+		if s.href != `` {
+			text = `<a href="` + html.EscapeString(s.href) + `">` + text + `</a>`
+		}
+		r.b.WriteString(text)
+	}
+}
+
+func (r *htmlRenderer) write(out io.Writer) {
+	b := bytes.NewBufferString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>")
+	b.WriteString(html.EscapeString(r.title))
+	b.WriteString("</title>\n</head>\n<body>\n")
+	b.Write(r.b.Bytes())
+	b.WriteString("</body>\n</html>\n")
+	writeBytes(out, b.Bytes())
+}
+
+func writeBytes(out io.Writer, bs []byte) {
+	if _, err := out.Write(bs); err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
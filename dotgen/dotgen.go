@@ -0,0 +1,279 @@
+// Package dotgen renders the relationships between pcore types as a Graphviz DOT graph.
+//
+// Generate produces a directed graph for a TypeSet. The TypeSet and each TypeSet that it references are
+// drawn as clusters that contain one node per member type. The node of an Object type lists its
+// attributes. Object types that are not members of any of the TypeSets are drawn outside of the clusters.
+//
+// The graph has two kinds of edges. An inheritance edge with an empty arrowhead points from an Object type
+// to its parent. A dashed edge points from an Object type to each named type that is used in the type of
+// one of its attributes and is labeled with the names of those attributes. A type alias has dashed edges to
+// the named types that it resolves to.
+package dotgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// Generate writes a DOT graph that shows the types of the given TypeSet and of the TypeSets that it
+// references to the given writer
+func Generate(c px.Context, ts px.TypeSet, out io.Writer) {
+	g := &graph{nodes: make(map[string]bool), edgeIndex: make(map[string]int)}
+	g.addTypeSet(ts)
+	for _, name := range references(ts) {
+		if rt, ok := px.Load(c, px.NewTypedName(px.NsType, name)); ok {
+			if rts, ok := rt.(px.TypeSet); ok {
+				g.addTypeSet(rts)
+			}
+		}
+	}
+	for _, cl := range g.clusters {
+		for _, t := range cl.types {
+			g.addEdges(t)
+		}
+	}
+	g.write(ts.Name(), out)
+}
+
+// references returns the names of the TypeSets that the given TypeSet references
+func references(ts px.TypeSet) []string {
+	var names []string
+	if rh, ok := ts.(px.PuppetObject).InitHash().Get4(`references`); ok {
+		rh.(px.OrderedMap).EachValue(func(v px.Value) {
+			names = append(names, v.(px.OrderedMap).Get5(`name`, px.EmptyString).String())
+		})
+	}
+	return names
+}
+
+type cluster struct {
+	ts    px.TypeSet
+	types []px.Type
+}
+
+type edge struct {
+	from, to    string
+	inheritance bool
+	labels      []string
+}
+
+type graph struct {
+	clusters  []*cluster
+	outside   []px.Type
+	nodes     map[string]bool
+	edges     []*edge
+	edgeIndex map[string]int
+}
+
+func (g *graph) addTypeSet(ts px.TypeSet) {
+	cl := &cluster{ts: ts}
+	ts.Types().EachValue(func(v px.Value) {
+		t := v.(px.Type)
+		if !g.nodes[t.Name()] {
+			g.nodes[t.Name()] = true
+			cl.types = append(cl.types, t)
+		}
+	})
+	g.clusters = append(g.clusters, cl)
+}
+
+// node ensures that there's a node for the given named type
+func (g *graph) node(t px.Type) {
+	if !g.nodes[t.Name()] {
+		g.nodes[t.Name()] = true
+		g.outside = append(g.outside, t)
+	}
+}
+
+func (g *graph) addEdges(t px.Type) {
+	switch t := t.(type) {
+	case px.ObjectType:
+		if pt, ok := t.Parent().(px.ObjectType); ok {
+			g.node(pt)
+			g.edges = append(g.edges, &edge{from: t.Name(), to: pt.Name(), inheritance: true})
+		}
+		for _, a := range attributes(t) {
+			for _, rt := range namedTypes(a.Type()) {
+				g.reference(t.Name(), rt, a.Name())
+			}
+		}
+	case *types.TypeAliasType:
+		for _, rt := range namedTypes(t.ResolvedType()) {
+			g.reference(t.Name(), rt, ``)
+		}
+	}
+}
+
+// reference adds an edge for a reference from the type with the given name to the given type. Several
+// references between the same types are combined into one edge.
+func (g *graph) reference(from string, to px.Type, label string) {
+	g.node(to)
+	key := from + ` -> ` + to.Name()
+	if i, ok := g.edgeIndex[key]; ok {
+		if label != `` {
+			g.edges[i].labels = append(g.edges[i].labels, label)
+		}
+		return
+	}
+	e := &edge{from: from, to: to.Name()}
+	if label != `` {
+		e.labels = []string{label}
+	}
+	g.edgeIndex[key] = len(g.edges)
+	g.edges = append(g.edges, e)
+}
+
+// attributes returns the attributes and constants that are declared by the given type, excluding
+// inherited ones
+func attributes(t px.ObjectType) []px.Attribute {
+	var as []px.Attribute
+	ih := t.(px.PuppetObject).InitHash()
+	for _, key := range []string{`attributes`, `constants`} {
+		if ah, ok := ih.Get4(key); ok {
+			ah.(px.OrderedMap).EachKey(func(k px.Value) {
+				if m, ok := t.Member(k.String()); ok {
+					if a, ok := m.(px.Attribute); ok {
+						as = append(as, a)
+					}
+				}
+			})
+		}
+	}
+	return as
+}
+
+// namedTypes returns the Object types and type aliases that are used in the given type. The types are not
+// searched further.
+func namedTypes(t px.Type) []px.Type {
+	var ts []px.Type
+	seen := make(map[string]bool)
+	var walk func(v px.Value)
+	walk = func(v px.Value) {
+		switch v := v.(type) {
+		case px.ObjectType, *types.TypeAliasType:
+			t := v.(px.Type)
+			if t.Name() != `` && !seen[t.Name()] {
+				seen[t.Name()] = true
+				ts = append(ts, t)
+			}
+		case px.ParameterizedType:
+			for _, p := range v.Parameters() {
+				walk(p)
+			}
+		case px.List:
+			v.Each(walk)
+		case px.OrderedMap:
+			v.EachPair(func(k, ev px.Value) {
+				walk(k)
+				walk(ev)
+			})
+		}
+	}
+	walk(t)
+	return ts
+}
+
+func (g *graph) write(name string, out io.Writer) {
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, "digraph %s {\n", quote(name))
+	b.WriteString("  rankdir=BT;\n  node [shape=record, fontsize=10];\n  edge [fontsize=9];\n")
+	for i, cl := range g.clusters {
+		fmt.Fprintf(b, "\n  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(b, "    label=%s;\n", quote(cl.ts.Name()+` `+cl.ts.Version().String()))
+		for _, t := range cl.types {
+			b.WriteString(`    `)
+			g.writeNode(b, t, cl.ts.Name())
+		}
+		b.WriteString("  }\n")
+	}
+	if len(g.outside) > 0 {
+		b.WriteByte('\n')
+		for _, t := range g.outside {
+			b.WriteString(`  `)
+			g.writeNode(b, t, ``)
+		}
+	}
+	if len(g.edges) > 0 {
+		b.WriteByte('\n')
+		for _, e := range g.edges {
+			fmt.Fprintf(b, "  %s -> %s", quote(e.from), quote(e.to))
+			switch {
+			case e.inheritance:
+				b.WriteString(` [arrowhead=empty]`)
+			case len(e.labels) > 0:
+				fmt.Fprintf(b, ` [style=dashed, label=%s]`, quote(strings.Join(e.labels, `, `)))
+			default:
+				b.WriteString(` [style=dashed]`)
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+	if _, err := out.Write(b.Bytes()); err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
+
+// writeNode writes the node for the given type. Names of types in the given TypeSet are shown relative
+// to that TypeSet.
+func (g *graph) writeNode(b *bytes.Buffer, t px.Type, typeSet string) {
+	label := recordEscape(relativeName(typeSet, t.Name()))
+	switch t := t.(type) {
+	case px.ObjectType:
+		as := attributes(t)
+		if len(as) > 0 {
+			fs := make([]string, len(as))
+			for i, a := range as {
+				f := a.Name() + ` : ` + typeString(typeSet, a.Type())
+				if a.Kind() != `` {
+					f = string(a.Kind()) + ` ` + f
+				}
+				fs[i] = recordEscape(f) + `\l`
+			}
+			label = `{` + label + `|` + strings.Join(fs, ``) + `}`
+		}
+	case *types.TypeAliasType:
+		label = `{` + label + `|` + recordEscape(`= `+typeString(typeSet, t.ResolvedType())) + `\l}`
+	}
+	fmt.Fprintf(b, "%s [label=%s];\n", quote(t.Name()), quote(label))
+}
+
+func relativeName(typeSet, name string) string {
+	if typeSet != `` && strings.HasPrefix(name, typeSet+`::`) {
+		return name[len(typeSet)+2:]
+	}
+	return name
+}
+
+// typeString returns the string form of the given type where references to members of the given TypeSet
+// are relative to that TypeSet
+func typeString(typeSet string, t px.Type) string {
+	fc := px.DefaultFormatContext
+	if typeSet != `` {
+		fc = fc.WithProperties(map[string]string{`typeSet`: typeSet})
+	}
+	b := bytes.NewBufferString(``)
+	t.ToString(b, fc, nil)
+	return b.String()
+}
+
+var recordEscaper = strings.NewReplacer(`\`, `\\`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`)
+
+// recordEscape escapes characters that have a special meaning in the label of a record node
+func recordEscape(s string) string {
+	return recordEscaper.Replace(s)
+}
+
+var quoteEscaper = strings.NewReplacer(`"`, `\"`, "\n", `\n`)
+
+// quote returns the given string as a DOT quoted string. Backslashes are kept since they start the escape
+// sequences of labels, e.g. \l which ends a left justified line.
+func quote(s string) string {
+	return `"` + quoteEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,78 @@
+package dotgen_test
+
+import (
+	"os"
+
+	"github.com/lyraproj/pcore/dotgen"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+)
+
+func ExampleGenerate() {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, c.ParseType(`TypeSet[{
+      name => 'My::Base',
+      pcore_version => '1.0.0',
+      version => '1.0.0',
+      types => {
+        Entity => { attributes => { id => String }},
+        Address => Entity{ attributes => { street => String, city => String }}
+      }
+    }]`))
+
+		ts := c.ParseType(`TypeSet[{
+      name => 'My::Shop',
+      pcore_version => '1.0.0',
+      version => '2.1.0',
+      references => {
+        Base => { name => 'My::Base', version_range => '1.x' }
+      },
+      types => {
+        Sku => Pattern[/\A[A-Z]{3}-\d+\z/],
+        Items => Array[Item],
+        Item => Base::Entity{
+          attributes => {
+            sku => Sku,
+            price => Float
+          }
+        },
+        Customer => Base::Entity{
+          attributes => {
+            billing => Base::Address,
+            shipping => Optional[Base::Address],
+            wishlist => Items
+          }
+        }
+      }
+    }]`).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		dotgen.Generate(c, ts, os.Stdout)
+	})
+	// Output:
+	// digraph "My::Shop" {
+	//   rankdir=BT;
+	//   node [shape=record, fontsize=10];
+	//   edge [fontsize=9];
+	//
+	//   subgraph cluster_0 {
+	//     label="My::Shop 2.1.0";
+	//     "My::Shop::Sku" [label="{Sku|= Pattern[/\\A[A-Z]\{3\}-\\d+\\z/]\l}"];
+	//     "My::Shop::Items" [label="{Items|= Array[Item]\l}"];
+	//     "My::Shop::Item" [label="{Item|sku : Sku\lprice : Float\l}"];
+	//     "My::Shop::Customer" [label="{Customer|billing : My::Base::Address\lshipping : Optional[My::Base::Address]\lwishlist : Items\l}"];
+	//   }
+	//
+	//   subgraph cluster_1 {
+	//     label="My::Base 1.0.0";
+	//     "My::Base::Entity" [label="{Entity|id : String\l}"];
+	//     "My::Base::Address" [label="{Address|street : String\lcity : String\l}"];
+	//   }
+	//
+	//   "My::Shop::Items" -> "My::Shop::Item" [style=dashed];
+	//   "My::Shop::Item" -> "My::Base::Entity" [arrowhead=empty];
+	//   "My::Shop::Item" -> "My::Shop::Sku" [style=dashed, label="sku"];
+	//   "My::Shop::Customer" -> "My::Base::Entity" [arrowhead=empty];
+	//   "My::Shop::Customer" -> "My::Base::Address" [style=dashed, label="billing, shipping"];
+	//   "My::Shop::Customer" -> "My::Shop::Items" [style=dashed, label="wishlist"];
+	//   "My::Base::Address" -> "My::Base::Entity" [arrowhead=empty];
+	// }
+}
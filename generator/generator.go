@@ -0,0 +1,422 @@
+// Package generator produces random values that are instances of pcore types, e.g. for use in property based
+// tests, and shrinks values that make such tests fail.
+//
+// A Generator is seeded with a rand.Source, so a failing test can be reproduced with the same seed. Ranges of
+// Integer, Float, Timestamp, and Timespan types and the sizes of String and collection types are respected,
+// strings that match a Pattern are generated from its regular expressions, and Object types are instantiated
+// using px.New with an init hash of generated attribute values.
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+)
+
+// A Generator generates random values and shrinks values
+type Generator interface {
+	// Value returns a random value that is an instance of the given type. A px.Error with the
+	// UnableToGenerateValue issue code is raised when no such value can be generated.
+	Value(t px.Type) px.Value
+
+	// Shrink returns values that are smaller than the given value and instances of the given type. The most
+	// reduced values come first.
+	Shrink(t px.Type, v px.Value) []px.Value
+
+	// Minimize shrinks the given value for as long as the given predicate, which typically answers if a
+	// test fails for a value, returns true for a smaller value and returns the smallest such value.
+	Minimize(t px.Type, v px.Value, predicate func(px.Value) bool) px.Value
+}
+
+// Maximum number of attempts to generate an instance of a type before giving up
+const maxAttempts = 100
+
+// Limits used for types that have no upper or lower bound
+const (
+	softIntLimit   = 1000
+	softFloatLimit = 1000.0
+	softTimespan   = 365 * 24 * time.Hour
+)
+
+var (
+	softMinTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	softMaxTime = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var optionsType = types.NewStructType([]*types.StructElement{
+	types.NewStructElement(types.NewOptionalType(types.NewStringType(nil, `max_size`)), types.NewIntegerType(0, math.MaxInt64)),
+	types.NewStructElement(types.NewOptionalType(types.NewStringType(nil, `max_depth`)), types.NewIntegerType(0, math.MaxInt64)),
+})
+
+type generator struct {
+	c        px.Context
+	r        *rand.Rand
+	maxSize  int64
+	maxDepth int
+}
+
+// New creates a Generator that uses the given context to create objects and the given source for
+// randomness. The following options are recognized:
+//
+//	max_size  - the maximum size of strings and collections whose type has no upper size limit (default 10)
+//	max_depth - the nesting depth after which collections are generated with their minimum size and
+//	            optional values are undef (default 4)
+//
+// A TypeMismatch error is raised for options that are unknown or have values of the wrong type.
+func New(c px.Context, src rand.Source, options px.OrderedMap) Generator {
+	if options == nil {
+		options = px.EmptyMap
+	}
+	px.AssertInstance(`generator options`, optionsType, options)
+	return &generator{
+		c:        c,
+		r:        rand.New(src),
+		maxSize:  options.Get5(`max_size`, types.WrapInteger(10)).(px.Integer).Int(),
+		maxDepth: int(options.Get5(`max_depth`, types.WrapInteger(4)).(px.Integer).Int()),
+	}
+}
+
+func (g *generator) Value(t px.Type) px.Value {
+	return g.instance(t, 0)
+}
+
+// instance generates values until it finds one that is an instance of the given type. Most generated values
+// are instances but e.g. a random string will not always match a Pattern with anchors or look-around.
+func (g *generator) instance(t px.Type, depth int) px.Value {
+	for i := 0; i < maxAttempts; i++ {
+		if v := g.value(t, depth); px.IsInstance(t, v) {
+			return v
+		}
+	}
+	panic(g.error(t, `no generated value is an instance of the type`))
+}
+
+func (g *generator) value(t px.Type, depth int) px.Value {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		return g.value(t.ResolvedType(), depth)
+	case *types.AnyType, *types.UnitType:
+		return g.value(types.DefaultDataType(), depth)
+	case *types.UndefType:
+		return px.Undef
+	case *types.DefaultType:
+		return types.WrapDefault()
+	case *types.BooleanType:
+		return types.WrapBoolean(g.r.Intn(2) == 1)
+	case *types.NumericType:
+		return g.oneOf(depth, types.DefaultIntegerType(), types.DefaultFloatType())
+	case *types.ScalarDataType, *types.ScalarType:
+		return g.oneOf(depth, types.DefaultIntegerType(), types.DefaultFloatType(), types.DefaultStringType(), types.DefaultBooleanType())
+	case *types.IntegerType:
+		return types.WrapInteger(g.integer(t.Min(), t.Max()))
+	case *types.FloatType:
+		return types.WrapFloat(g.float(t.Min(), t.Max()))
+	case *types.EnumType:
+		if ss := t.Strings(); len(ss) > 0 {
+			return types.WrapString(ss[g.r.Intn(len(ss))])
+		}
+		// An Enum without strings accepts all strings
+		return g.value(types.DefaultStringType(), depth)
+	case *types.PatternType:
+		return g.patternString(t)
+	case px.StringType:
+		if s := t.Value(); s != nil {
+			return types.WrapString(*s)
+		}
+		return types.WrapString(g.string(g.size(t.Size().(*types.IntegerType), depth)))
+	case *types.RegexpType:
+		return types.WrapRegexp(t.PatternString())
+	case *types.BinaryType:
+		bs := make([]byte, g.size(types.PositiveIntegerType(), depth))
+		g.r.Read(bs)
+		return types.WrapBinary(bs)
+	case *types.TimestampType:
+		return g.timestamp(t)
+	case *types.TimespanType:
+		return g.timespan(t)
+	case *types.SemVerType:
+		return g.version(t)
+	case *types.OptionalType:
+		if depth >= g.maxDepth || g.r.Intn(4) == 0 {
+			return px.Undef
+		}
+		return g.value(t.ContainedType(), depth)
+	case *types.NotUndefType:
+		if _, ok := t.ContainedType().(*types.AnyType); ok {
+			return g.value(types.DefaultScalarDataType(), depth)
+		}
+		return g.value(t.ContainedType(), depth)
+	case *types.VariantType:
+		return g.oneOf(depth, t.Types()...)
+	case *types.TypeType:
+		return t.ContainedType()
+	case *types.SensitiveType:
+		return types.WrapSensitive(g.instance(t.ContainedType(), depth))
+	case *types.ArrayType:
+		return g.array(t.ElementType(), t.Size(), depth)
+	case *types.IterableType:
+		return g.array(t.ElementType(), types.PositiveIntegerType(), depth)
+	case *types.CollectionType:
+		return g.array(types.DefaultDataType(), t.Size(), depth)
+	case *types.TupleType:
+		return g.tuple(t, depth)
+	case *types.HashType:
+		return g.hash(t, depth)
+	case *types.StructType:
+		return g.structHash(t, depth)
+	case px.ObjectType:
+		return g.object(t, depth)
+	}
+	panic(g.error(t, `the type is not supported`))
+}
+
+// oneOf generates an instance of one of the given types. The types are tried in random order until one that
+// can be generated is found. Types that are instances of Variant[Scalar, Undef] are preferred when the maximum
+// depth is reached so that the generation of recursive types ends.
+func (g *generator) oneOf(depth int, ts ...px.Type) px.Value {
+	if depth >= g.maxDepth {
+		leafs := make([]px.Type, 0, len(ts))
+		for _, t := range ts {
+			if px.IsAssignable(leafType, t) {
+				leafs = append(leafs, t)
+			}
+		}
+		if len(leafs) > 0 {
+			ts = leafs
+		}
+	}
+	for _, i := range g.r.Perm(len(ts)) {
+		if v, ok := g.tryInstance(ts[i], depth); ok {
+			return v
+		}
+	}
+	panic(g.error(types.NewVariantType(ts...), `none of the types can be generated`))
+}
+
+// tryInstance is like instance but returns false instead of raising an error when no instance can be generated
+func (g *generator) tryInstance(t px.Type, depth int) (v px.Value, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if ri, isIssue := r.(issue.Reported); !isIssue || ri.Code() != px.UnableToGenerateValue {
+				panic(r)
+			}
+		}
+	}()
+	return g.instance(t, depth), true
+}
+
+var leafType = types.NewVariantType(types.DefaultScalarType(), types.DefaultUndefType())
+
+// integer returns a random integer in the given range. One in ten integers is one of the bounded limits of the
+// range or zero. Limits that are unbounded are replaced by softer limits.
+func (g *generator) integer(min, max int64) int64 {
+	switch g.r.Intn(10) {
+	case 0:
+		if min != math.MinInt64 {
+			return min
+		}
+	case 1:
+		if max != math.MaxInt64 {
+			return max
+		}
+	case 2:
+		if min <= 0 && max >= 0 {
+			return 0
+		}
+	}
+	lo, hi := min, max
+	if lo == math.MinInt64 {
+		lo = -softIntLimit
+		if hi < lo && hi-softIntLimit < hi {
+			lo = hi - softIntLimit
+		}
+	}
+	if hi == math.MaxInt64 {
+		hi = softIntLimit
+		if lo > hi && lo+softIntLimit > lo {
+			hi = lo + softIntLimit
+		}
+	}
+	w := uint64(hi - lo)
+	if w == math.MaxUint64 {
+		return int64(g.r.Uint64())
+	}
+	return lo + int64(g.r.Uint64()%(w+1))
+}
+
+// float returns a random float in the given range. One in ten floats is one of the bounded limits of the range
+// or zero.
+func (g *generator) float(min, max float64) float64 {
+	switch g.r.Intn(10) {
+	case 0:
+		if min != -math.MaxFloat64 {
+			return min
+		}
+	case 1:
+		if max != math.MaxFloat64 {
+			return max
+		}
+	case 2:
+		if min <= 0 && max >= 0 {
+			return 0
+		}
+	}
+	lo, hi := min, max
+	if lo == -math.MaxFloat64 {
+		lo = math.Min(-softFloatLimit, hi-softFloatLimit)
+	}
+	if hi == math.MaxFloat64 {
+		hi = math.Max(softFloatLimit, lo+softFloatLimit)
+	}
+	return lo + g.r.Float64()*(hi-lo)
+}
+
+// size returns a random size in the given range. The range is limited by the max_size option and by the
+// minimum of the range when the maximum depth is reached.
+func (g *generator) size(rng *types.IntegerType, depth int) int {
+	min, max := rng.Min(), rng.Max()
+	if depth >= g.maxDepth {
+		max = min
+	} else if max-min > g.maxSize {
+		max = min + g.maxSize
+	}
+	return int(min + g.r.Int63n(max-min+1))
+}
+
+const alphabet = `abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-.`
+
+func (g *generator) string(n int) string {
+	bs := make([]byte, n)
+	for i := range bs {
+		bs[i] = alphabet[g.r.Intn(len(alphabet))]
+	}
+	return string(bs)
+}
+
+func (g *generator) timestamp(t *types.TimestampType) px.Value {
+	min, max := softMinTime, softMaxTime
+	if from, ok := t.Get(`from`); ok && from != px.Undef {
+		min = from.(*types.Timestamp).Time()
+		if !max.After(min) {
+			max = min.AddDate(10, 0, 0)
+		}
+	}
+	if to, ok := t.Get(`to`); ok && to != px.Undef {
+		max = to.(*types.Timestamp).Time()
+		if !min.Before(max) {
+			min = max.AddDate(-10, 0, 0)
+		}
+	}
+	return types.WrapTimestamp(min.Add(time.Duration(g.integer(0, int64(max.Sub(min))))))
+}
+
+func (g *generator) timespan(t *types.TimespanType) px.Value {
+	min, max := -softTimespan, softTimespan
+	if from, ok := t.Get(`from`); ok && from != px.Undef {
+		min = from.(types.Timespan).Duration()
+		if max < min {
+			max = min + softTimespan
+		}
+	}
+	if to, ok := t.Get(`to`); ok && to != px.Undef {
+		max = to.(types.Timespan).Duration()
+		if min > max {
+			min = max - softTimespan
+		}
+	}
+	return types.WrapTimespan(time.Duration(g.integer(int64(min), int64(max))))
+}
+
+// version returns either the start version of the range of the given type or a random version
+func (g *generator) version(t *types.SemVerType) px.Value {
+	if ps := t.Parameters(); len(ps) > 0 && g.r.Intn(2) == 0 {
+		if vr, err := semver.ParseVersionRange(ps[0].String()); err == nil && vr.StartVersion() != nil {
+			return types.WrapSemVer(vr.StartVersion())
+		}
+	}
+	v, err := semver.NewVersion(g.r.Intn(5), g.r.Intn(10), g.r.Intn(20))
+	if err != nil {
+		panic(g.error(t, err.Error()))
+	}
+	return types.WrapSemVer(v)
+}
+
+func (g *generator) array(et px.Type, size *types.IntegerType, depth int) px.Value {
+	es := make([]px.Value, g.size(size, depth))
+	for i := range es {
+		es[i] = g.instance(et, depth+1)
+	}
+	return types.WrapValues(es)
+}
+
+func (g *generator) tuple(t *types.TupleType, depth int) px.Value {
+	ts := t.Types()
+	es := make([]px.Value, g.size(t.Size(), depth))
+	for i := range es {
+		et := px.Type(types.DefaultAnyType())
+		if i < len(ts) {
+			et = ts[i]
+		} else if len(ts) > 0 {
+			et = ts[len(ts)-1]
+		}
+		es[i] = g.instance(et, depth+1)
+	}
+	return types.WrapValues(es)
+}
+
+// hash generates a hash with unique keys. A hash that is smaller than the generated size is returned when
+// the key type has too few instances.
+func (g *generator) hash(t *types.HashType, depth int) px.Value {
+	n := g.size(t.Size(), depth)
+	es := make([]*types.HashEntry, 0, n)
+	seen := make(map[px.HashKey]bool, n)
+	for i := 0; len(es) < n && i < maxAttempts; i++ {
+		k := g.instance(t.KeyType(), depth+1)
+		if hk := px.ToKey(k); !seen[hk] {
+			seen[hk] = true
+			es = append(es, types.WrapHashEntry(k, g.instance(t.ValueType(), depth+1)))
+		}
+	}
+	return types.WrapHash(es)
+}
+
+// structHash generates a hash with all required keys of the given type and a random selection of its optional keys
+func (g *generator) structHash(t *types.StructType, depth int) px.Value {
+	es := make([]*types.HashEntry, 0, len(t.Elements()))
+	for _, e := range t.Elements() {
+		if e.Optional() && (depth >= g.maxDepth || g.r.Intn(2) == 0) {
+			continue
+		}
+		es = append(es, types.WrapHashEntry2(e.Name(), g.instance(e.Value(), depth+1)))
+	}
+	return types.WrapHash(es)
+}
+
+// object creates an object from an init hash with all required attributes of the given type and a random
+// selection of its optional attributes
+func (g *generator) object(t px.ObjectType, depth int) px.Value {
+	if t.Equals(types.DefaultObjectType(), nil) {
+		panic(g.error(t, `the type is not supported`))
+	}
+	ai := t.AttributesInfo()
+	es := make([]*types.HashEntry, 0, len(ai.Attributes()))
+	for i, a := range ai.Attributes() {
+		if i >= ai.RequiredCount() && (depth >= g.maxDepth || g.r.Intn(2) == 0) {
+			continue
+		}
+		es = append(es, types.WrapHashEntry2(a.Name(), g.instance(a.Type(), depth+1)))
+	}
+	if o, ok := g.newObject(t, types.WrapHash(es)); ok {
+		return o
+	}
+	panic(g.error(t, `the constructor does not accept the generated attributes`))
+}
+
+func (g *generator) error(t px.Type, detail string) issue.Reported {
+	return px.Error(px.UnableToGenerateValue, issue.H{`type`: t.String(), `detail`: detail})
+}
@@ -0,0 +1,140 @@
+package generator_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/generator"
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValue_isInstance(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, px.NewObjectType(`My::Node`, `{
+      attributes => {
+        name => Pattern[/\A[a-z][a-z0-9_]*\z/],
+        weight => { type => Float[0.0, 1.0], value => 0.5 },
+        children => { type => Array[My::Node], value => [] }
+      }
+    }`))
+		pts := []px.Type{types.NewSemVerType(semver.MustParseVersionRange(`>=1.2.0 <2.0.0`))}
+		for _, ts := range []string{
+			`Integer[-5, 5]`,
+			`Integer[1000000000000]`,
+			`Float[-0.5, 0.5]`,
+			`String[3, 8]`,
+			`Enum[a, b, c]`,
+			`Enum`,
+			`Pattern[/\A\d{3}-[A-F]{2,4}\z/, /^(foo|bar)+$/]`,
+			`Pattern[/\Ab(?i:ar)?\.baz\z/]`,
+			`Struct[{a => Integer, Optional[b] => String[1], c => Optional[Boolean]}]`,
+			`Tuple[Integer, String, 2, 4]`,
+			`Variant[Integer[0, 3], Enum[x, y]]`,
+			`Hash[Enum[a, b, c], Integer, 1, 3]`,
+			`Array[Timestamp['2020-01-01T00:00:00.000 UTC', '2020-12-31T00:00:00.000 UTC'], 2]`,
+			`Timespan['0-01:00:00', '0-02:00:00']`,
+			`Data`,
+			`RichData`,
+			`NotUndef`,
+			`Sensitive[String]`,
+			`My::Node`,
+		} {
+			pts = append(pts, c.ParseType(ts))
+		}
+		for _, pt := range pts {
+			for seed := int64(0); seed < 50; seed++ {
+				v := generator.New(c, rand.NewSource(seed), nil).Value(pt)
+				require.True(t, px.IsInstance(pt, v), `%s is not an instance of %s`, v, pt)
+			}
+		}
+	})
+}
+
+func TestValue_unsupported(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		require.Panics(t, func() { generator.New(c, rand.NewSource(0), nil).Value(c.ParseType(`Pattern[/\A\z[a]/]`)) })
+	})
+}
+
+func TestNew_invalidOptions(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		for _, o := range []map[string]interface{}{{`max_size`: `10`}, {`max_depth`: -1}, {`max_length`: 10}} {
+			r := testutil.Reported(func() { generator.New(c, rand.NewSource(0), px.Wrap(c, o).(px.OrderedMap)) })
+			require.Equal(t, issue.Code(px.TypeMismatch), r.Code())
+		}
+	})
+}
+
+func TestShrink_isInstance(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		pt := c.ParseType(`Struct[{a => Integer[3, 10], b => Array[String[2], 1], Optional[c] => Enum[x, y, z]}]`)
+		g := generator.New(c, rand.NewSource(1), nil)
+		v := g.Value(pt)
+		cs := g.Shrink(pt, v)
+		require.NotEmpty(t, cs)
+		for _, s := range cs {
+			require.True(t, px.IsInstance(pt, s), `%s is not an instance of %s`, s, pt)
+			require.False(t, s.Equals(v, nil))
+		}
+	})
+}
+
+func TestMinimize_timestamp(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		g := generator.New(c, rand.NewSource(0), nil)
+		always := func(px.Value) bool { return true }
+		pt := c.ParseType(`Timestamp['2020-01-01T00:00:00.000 UTC', '2020-12-31T00:00:00.000 UTC']`)
+		require.Equal(t, `2020-01-01T00:00:00.000000000 UTC`, g.Minimize(pt, g.Value(pt), always).String())
+		pt = c.ParseType(`Timestamp`)
+		require.Equal(t, `1970-01-01T00:00:00.000000000 UTC`, g.Minimize(pt, g.Value(pt), always).String())
+	})
+}
+
+func ExampleNew() {
+	pcore.Do(func(c px.Context) {
+		g := generator.New(c, rand.NewSource(42), px.Wrap(c, map[string]interface{}{`max_size`: 3}).(px.OrderedMap))
+		t := c.ParseType(`Struct[{
+      id => Pattern[/\A[A-Z]{3}-\d{4}\z/],
+      qty => Integer[1, 99],
+      tags => Array[Enum[red, green, blue]],
+      Optional[note] => String[1]
+    }]`)
+		for i := 0; i < 3; i++ {
+			fmt.Println(g.Value(t))
+		}
+	})
+	// Output:
+	// {'id' => 'UPT-8952', 'qty' => 30, 'tags' => ['blue', 'blue']}
+	// {'id' => 'VZD-9208', 'qty' => 49, 'tags' => ['blue', 'blue', 'green']}
+	// {'id' => 'AMR-2161', 'qty' => 2, 'tags' => ['red', 'red']}
+}
+
+func ExampleGenerator_Minimize() {
+	pcore.Do(func(c px.Context) {
+		g := generator.New(c, rand.NewSource(7), nil)
+		t := c.ParseType(`Array[Integer[0, 1000], 1, 20]`)
+
+		// The property under test claims that no array contains a value above 500
+		fails := func(v px.Value) bool {
+			return v.(px.List).Any(func(e px.Value) bool { return e.(px.Integer).Int() > 500 })
+		}
+		for {
+			v := g.Value(t)
+			if fails(v) {
+				fmt.Println(g.Minimize(t, v, fails))
+				break
+			}
+		}
+		fmt.Println(g.Minimize(t, types.WrapValues([]px.Value{types.WrapInteger(3), types.WrapInteger(999)}), fails))
+	})
+	// Output:
+	// [501]
+	// [501]
+}
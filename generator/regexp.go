@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"bytes"
+	"regexp/syntax"
+	"unicode"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// Maximum number of repetitions generated for *, +, and repeats without an upper limit
+const maxRepeat = 5
+
+// patternString generates a string that matches one of the regular expressions of the given Pattern. A
+// Pattern without regular expressions matches all strings.
+func (g *generator) patternString(t *types.PatternType) px.Value {
+	rxs := t.Patterns()
+	if rxs.Len() == 0 {
+		return types.WrapString(g.string(g.size(types.PositiveIntegerType(), 0)))
+	}
+	src := rxs.At(g.r.Intn(rxs.Len())).(*types.RegexpType).PatternString()
+	re, err := syntax.Parse(src, syntax.Perl)
+	if err != nil {
+		panic(g.error(t, err.Error()))
+	}
+	b := bytes.NewBufferString(``)
+	g.regexpString(b, re.Simplify())
+	return types.WrapString(b.String())
+}
+
+// regexpString writes a string that matches the given regular expression. Assertions such as anchors and
+// word boundaries are ignored so the caller must verify that the string matches.
+func (g *generator) regexpString(b *bytes.Buffer, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.r.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteByte(alphabet[g.r.Intn(len(alphabet))])
+	case syntax.OpCapture:
+		g.regexpString(b, re.Sub[0])
+	case syntax.OpStar:
+		g.repeat(b, re.Sub[0], 0, maxRepeat)
+	case syntax.OpPlus:
+		g.repeat(b, re.Sub[0], 1, maxRepeat)
+	case syntax.OpQuest:
+		g.repeat(b, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		max := re.Max
+		if max < 0 {
+			max = re.Min + maxRepeat
+		}
+		g.repeat(b, re.Sub[0], re.Min, max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.regexpString(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.regexpString(b, re.Sub[g.r.Intn(len(re.Sub))])
+	}
+}
+
+func (g *generator) repeat(b *bytes.Buffer, re *syntax.Regexp, min, max int) {
+	n := min + g.r.Intn(max-min+1)
+	for i := 0; i < n; i++ {
+		g.regexpString(b, re)
+	}
+}
+
+// classRune returns a rune from the given character class ranges. Printable ASCII characters are preferred.
+func (g *generator) classRune(ranges []rune) rune {
+	ascii := make([]rune, 0, len(ranges))
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 {
+		ranges = ascii
+	}
+	i := g.r.Intn(len(ranges)/2) * 2
+	return ranges[i] + rune(g.r.Intn(int(ranges[i+1]-ranges[i]+1)))
+}
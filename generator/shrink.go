@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"math"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// Maximum number of times that Minimize replaces a value with a smaller one
+const maxShrinkSteps = 1000
+
+func (g *generator) Shrink(t px.Type, v px.Value) []px.Value {
+	cs := g.candidates(t, v)
+	result := make([]px.Value, 0, len(cs))
+	seen := make(map[px.HashKey]bool, len(cs))
+	for _, c := range cs {
+		if k := px.ToKey(c); !seen[k] && !c.Equals(v, nil) && px.IsInstance(t, c) {
+			seen[k] = true
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (g *generator) Minimize(t px.Type, v px.Value, predicate func(px.Value) bool) px.Value {
+	for i := 0; i < maxShrinkSteps; i++ {
+		next := px.Value(nil)
+		for _, c := range g.Shrink(t, v) {
+			if predicate(c) {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		v = next
+	}
+	return v
+}
+
+// candidates returns values that are smaller than the given value. The values are not necessarily instances of
+// the given type. The type is only used to direct the shrinking, e.g. towards the lower limit of an Integer.
+func (g *generator) candidates(t px.Type, v px.Value) []px.Value {
+	t = memberType(t, v)
+	switch v := v.(type) {
+	case px.Integer:
+		n := v.Int()
+		target := int64(0)
+		if it, ok := t.(*types.IntegerType); ok {
+			target = clampInt(0, it.Min(), it.Max())
+		}
+		cs := []px.Value{types.WrapInteger(target), types.WrapInteger(target + (n-target)/2)}
+		if n > target {
+			cs = append(cs, types.WrapInteger(n-1))
+		} else if n < target {
+			cs = append(cs, types.WrapInteger(n+1))
+		}
+		return cs
+	case px.Float:
+		f := v.Float()
+		target := 0.0
+		if ft, ok := t.(*types.FloatType); ok {
+			target = math.Max(ft.Min(), math.Min(0, ft.Max()))
+		}
+		return []px.Value{types.WrapFloat(target), types.WrapFloat(math.Trunc(f)), types.WrapFloat(target + (f-target)/2)}
+	case px.StringValue:
+		s := v.String()
+		var cs []px.Value
+		if et, ok := t.(*types.EnumType); ok {
+			// Strings that are listed before the given string are considered smaller
+			for _, es := range et.Strings() {
+				if es == s {
+					break
+				}
+				cs = append(cs, types.WrapString(es))
+			}
+		}
+		if len(s) > 0 {
+			cs = append(cs, types.WrapString(``), types.WrapString(s[:len(s)/2]), types.WrapString(s[len(s)/2:]),
+				types.WrapString(s[1:]), types.WrapString(s[:len(s)-1]))
+		}
+		return cs
+	case px.Boolean:
+		if v.Bool() {
+			return []px.Value{types.BooleanFalse}
+		}
+	case types.Timespan:
+		d := v.Duration()
+		target := time.Duration(0)
+		if tt, ok := t.(*types.TimespanType); ok {
+			if from, ok := tt.Get(`from`); ok && from != px.Undef && from.(types.Timespan).Duration() > target {
+				target = from.(types.Timespan).Duration()
+			}
+			if to, ok := tt.Get(`to`); ok && to != px.Undef && to.(types.Timespan).Duration() < target {
+				target = to.(types.Timespan).Duration()
+			}
+		}
+		return []px.Value{types.WrapTimespan(target), types.WrapTimespan(target + (d-target)/2)}
+	case *types.Timestamp:
+		// Timestamps shrink towards the lower bound of the range or, when there is none, towards the Unix epoch
+		ts := v.Time()
+		target := time.Unix(0, 0).UTC()
+		if tt, ok := t.(*types.TimestampType); ok {
+			if from, ok := tt.Get(`from`); ok && from != px.Undef {
+				target = from.(*types.Timestamp).Time()
+			} else if to, ok := tt.Get(`to`); ok && to != px.Undef && to.(*types.Timestamp).Time().Before(target) {
+				target = to.(*types.Timestamp).Time()
+			}
+		}
+		return []px.Value{types.WrapTimestamp(target), types.WrapTimestamp(target.Add(ts.Sub(target) / 2))}
+	case *types.Array:
+		return g.arrayCandidates(t, v)
+	case *types.Hash:
+		return g.hashCandidates(t, v, func(k px.Value) px.Type {
+			switch t := t.(type) {
+			case *types.HashType:
+				return t.ValueType()
+			case *types.StructType:
+				if e, ok := t.HashedMembers()[k.String()]; ok {
+					return e.Value()
+				}
+			}
+			return types.DefaultAnyType()
+		})
+	case px.PuppetObject:
+		if ot, ok := v.PType().(px.ObjectType); ok {
+			return g.objectCandidates(ot, v)
+		}
+	}
+	return nil
+}
+
+// arrayCandidates returns the empty array, the halves of the array, the array without each of its elements, and
+// the array with each of its elements shrunk
+func (g *generator) arrayCandidates(t px.Type, v *types.Array) []px.Value {
+	es := v.AppendTo(make([]px.Value, 0, v.Len()))
+	n := len(es)
+	if n == 0 {
+		return nil
+	}
+	cs := []px.Value{types.WrapValues([]px.Value{})}
+	if n > 1 {
+		cs = append(cs, types.WrapValues(es[:n/2]), types.WrapValues(es[n/2:]))
+	}
+	for i := range es {
+		cs = append(cs, types.WrapValues(append(append(make([]px.Value, 0, n-1), es[:i]...), es[i+1:]...)))
+	}
+	for i, e := range es {
+		for _, c := range g.Shrink(elementType(t, i), e) {
+			ce := append(make([]px.Value, 0, n), es...)
+			ce[i] = c
+			cs = append(cs, types.WrapValues(ce))
+		}
+	}
+	return cs
+}
+
+// hashCandidates returns the empty hash, the hash without each of its entries, and the hash with each of its
+// values shrunk
+func (g *generator) hashCandidates(t px.Type, v *types.Hash, valueType func(k px.Value) px.Type) []px.Value {
+	es := make([]*types.HashEntry, 0, v.Len())
+	v.EachPair(func(k, ev px.Value) { es = append(es, types.WrapHashEntry(k, ev)) })
+	n := len(es)
+	if n == 0 {
+		return nil
+	}
+	cs := []px.Value{types.WrapHash([]*types.HashEntry{})}
+	for i := range es {
+		cs = append(cs, types.WrapHash(append(append(make([]*types.HashEntry, 0, n-1), es[:i]...), es[i+1:]...)))
+	}
+	for i, e := range es {
+		for _, c := range g.Shrink(valueType(e.Key()), e.Value()) {
+			ce := append(make([]*types.HashEntry, 0, n), es...)
+			ce[i] = types.WrapHashEntry(e.Key(), c)
+			cs = append(cs, types.WrapHash(ce))
+		}
+	}
+	return cs
+}
+
+// objectCandidates shrinks the init hash of the given object and creates new objects from the results. Init
+// hashes that the object type doesn't accept are skipped.
+func (g *generator) objectCandidates(t px.ObjectType, v px.PuppetObject) []px.Value {
+	hcs := g.hashCandidates(t, v.InitHash().(*types.Hash), func(k px.Value) px.Type {
+		if m, ok := t.Member(k.String()); ok {
+			if a, ok := m.(px.Attribute); ok {
+				return a.Type()
+			}
+		}
+		return types.DefaultAnyType()
+	})
+	cs := make([]px.Value, 0, len(hcs))
+	for _, h := range hcs {
+		if o, ok := g.newObject(t, h); ok {
+			cs = append(cs, o)
+		}
+	}
+	return cs
+}
+
+func (g *generator) newObject(t px.ObjectType, h px.Value) (o px.Value, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isErr := r.(error); !isErr {
+				panic(r)
+			}
+		}
+	}()
+	return px.New(g.c, t, h), true
+}
+
+// memberType returns the type that directs the shrinking of the given value, i.e. the given type with aliases,
+// Optional, and NotUndef removed, or the first type of a Variant that the value is an instance of
+func memberType(t px.Type, v px.Value) px.Type {
+	switch tt := t.(type) {
+	case *types.TypeAliasType:
+		return memberType(tt.ResolvedType(), v)
+	case *types.OptionalType:
+		return memberType(tt.ContainedType(), v)
+	case *types.NotUndefType:
+		return memberType(tt.ContainedType(), v)
+	case *types.VariantType:
+		for _, vt := range tt.Types() {
+			if px.IsInstance(vt, v) {
+				return memberType(vt, v)
+			}
+		}
+	}
+	return t
+}
+
+// elementType returns the type of the element at the given position of an array of the given type
+func elementType(t px.Type, i int) px.Type {
+	switch t := t.(type) {
+	case *types.ArrayType:
+		return t.ElementType()
+	case *types.TupleType:
+		if ts := t.Types(); len(ts) > 0 {
+			if i < len(ts) {
+				return ts[i]
+			}
+			return ts[len(ts)-1]
+		}
+	}
+	return types.DefaultAnyType()
+}
+
+func clampInt(n, min, max int64) int64 {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
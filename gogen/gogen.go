@@ -0,0 +1,357 @@
+// Package gogen generates Go source code from pcore types.
+//
+// Generate produces one Go struct per Object type in a TypeSet together with an init() function that
+// declares the TypeSet using px.NewGoTypeSet. When the runtime resolves the TypeSet, the types are created
+// from the structs using reflection and the mappings between the types and the structs are registered with
+// the ImplementationRegistry. The TypeSet that is created that way is equal to the TypeSet that the code
+// was generated from.
+//
+// Each attribute becomes a field with a name that is the attribute name in camel case. A `puppet` tag is
+// added to the field when the name, type, value, or kind of the attribute cannot be derived from the field
+// alone. The tags of an attribute's TagsAnnotation are added to the field as additional struct tags.
+//
+// Attribute types are mapped to Go types as follows:
+//
+// Integer maps to int64, or to a sized integer type when its range is exactly the range of that type. Float
+// maps to float64, Boolean to bool, and String, Enum, and Pattern map to string. Timespan and Timestamp map
+// to time.Duration and time.Time. Binary, SemVer, Sensitive, URI, and Regexp map to pointers to their
+// respective implementations.
+//
+// Array and Hash map to slices and maps. Object types in the same TypeSet map to their generated struct.
+// Optional maps to a pointer. The parent of an Object type is embedded as the first field of the struct.
+//
+// All other types map to px.Value.
+//
+// The following is not supported:
+//
+// Members of the TypeSet that are not Object types, and Object types with a parent that is not a member of
+// the TypeSet, cannot be generated and will cause a px.UnableToGenerateGo error.
+//
+// Functions are not generated. The generated code must be complemented with methods that implement them
+// for the TypeSet to be equal.
+//
+// Attributes that are final without being constant or that override a parent attribute, and annotations
+// of the Object types themselves, are not retained.
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// Generate writes the source of a Go file that belongs to the given package and contains the types of the
+// given TypeSet to the given writer.
+func Generate(c px.Context, ts px.TypeSet, packageName string, out io.Writer) {
+	g := &generator{c: c, ts: ts, imports: map[string]bool{
+		`reflect`:                           true,
+		`github.com/lyraproj/pcore/px`:      true,
+		`github.com/lyraproj/semver/semver`: true}}
+
+	ts.Types().EachPair(func(k, v px.Value) {
+		ot, ok := v.(px.ObjectType)
+		if !ok {
+			panic(px.Error(px.UnableToGenerateGo, issue.H{`type`: k.String(), `detail`: `only Object types can be generated`}))
+		}
+		g.names = append(g.names, k.String())
+		g.objects = append(g.objects, ot)
+	})
+
+	body := bytes.NewBufferString(``)
+	for i, ot := range g.objects {
+		g.writeStruct(body, g.names[i], ot)
+	}
+
+	fmt.Fprintf(body, "func init() {\n\tpx.NewGoTypeSet(%s, semver.MustParseVersion(%s), nil", quote(ts.Name()), quote(ts.Version().String()))
+	for _, n := range g.names {
+		fmt.Fprintf(body, ",\n\t\treflect.TypeOf(&%s{})", n)
+	}
+	body.WriteString(")\n}\n")
+
+	src := bytes.NewBufferString(``)
+	fmt.Fprintf(src, "// Code generated by pcore-gogen from the TypeSet %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", ts.Name(), packageName)
+	// Standard library imports are separated from the others
+	std := make([]string, 0, len(g.imports))
+	other := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		if strings.Contains(imp, `.`) {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for i, imps := range [][]string{std, other} {
+		if i > 0 {
+			src.WriteByte('\n')
+		}
+		for _, imp := range imps {
+			src.WriteString(strconv.Quote(imp))
+			src.WriteByte('\n')
+		}
+	}
+	src.WriteString(")\n\n")
+	src.Write(body.Bytes())
+
+	bs, err := format.Source(src.Bytes())
+	if err != nil {
+		panic(px.Error(px.UnableToGenerateGo, issue.H{`type`: ts.Name(), `detail`: err.Error()}))
+	}
+	if _, err = out.Write(bs); err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
+
+type generator struct {
+	c       px.Context
+	ts      px.TypeSet
+	names   []string
+	objects []px.ObjectType
+	imports map[string]bool
+}
+
+// goType describes the Go type that an attribute type maps to
+type goType struct {
+	// src is the Go source for the type
+	src string
+
+	// typ is the type that the reflector will derive from the Go type
+	typ px.Type
+
+	// nilable is true when the Go type is a pointer or an interface
+	nilable bool
+}
+
+// leafType is a Go type that maps to a type that has no contained types
+type leafType struct {
+	src     string
+	pkg     string
+	rt      reflect.Type
+	nilable bool
+}
+
+var intTypes = []leafType{
+	{`int64`, ``, reflect.TypeOf(int64(0)), false},
+	{`int8`, ``, reflect.TypeOf(int8(0)), false},
+	{`int16`, ``, reflect.TypeOf(int16(0)), false},
+	{`int32`, ``, reflect.TypeOf(int32(0)), false},
+	{`uint8`, ``, reflect.TypeOf(uint8(0)), false},
+	{`uint16`, ``, reflect.TypeOf(uint16(0)), false},
+	{`uint32`, ``, reflect.TypeOf(uint32(0)), false},
+	{`uint64`, ``, reflect.TypeOf(uint64(0)), false}}
+
+var floatTypes = []leafType{
+	{`float64`, ``, reflect.TypeOf(float64(0)), false},
+	{`float32`, ``, reflect.TypeOf(float32(0)), false}}
+
+var (
+	boolType      = leafType{`bool`, ``, reflect.TypeOf(false), false}
+	stringType    = leafType{`string`, ``, reflect.TypeOf(``), false}
+	durationType  = leafType{`time.Duration`, `time`, reflect.TypeOf(time.Duration(0)), false}
+	timeType      = leafType{`time.Time`, `time`, reflect.TypeOf(time.Time{}), false}
+	binaryType    = leafType{`*types.Binary`, `github.com/lyraproj/pcore/types`, reflect.TypeOf(&types.Binary{}), true}
+	semVerType    = leafType{`*types.SemVer`, `github.com/lyraproj/pcore/types`, reflect.TypeOf(&types.SemVer{}), true}
+	sensitiveType = leafType{`*types.Sensitive`, `github.com/lyraproj/pcore/types`, reflect.TypeOf(&types.Sensitive{}), true}
+	uriType       = leafType{`*types.UriValue`, `github.com/lyraproj/pcore/types`, reflect.TypeOf(&types.UriValue{}), true}
+	regexpType    = leafType{`*regexp.Regexp`, `regexp`, reflect.TypeOf(&regexp.Regexp{}), true}
+	typeType      = leafType{`px.Type`, ``, reflect.TypeOf((*px.Type)(nil)).Elem(), true}
+	valueType     = leafType{`px.Value`, ``, reflect.TypeOf((*px.Value)(nil)).Elem(), true}
+)
+
+func (g *generator) writeStruct(b *bytes.Buffer, name string, ot px.ObjectType) {
+	fmt.Fprintf(b, "// %s is the Go implementation of %s\ntype %s struct {\n", name, ot.Name(), name)
+	if p := ot.Parent(); p != nil {
+		pn, ok := g.memberName(p)
+		if !ok {
+			panic(px.Error(px.UnableToGenerateGo, issue.H{`type`: ot.Name(), `detail`: fmt.Sprintf(`parent %s is not a member of %s`, p.Name(), g.ts.Name())}))
+		}
+		b.WriteString(pn)
+		b.WriteByte('\n')
+	}
+	// The attributes are written in the order they were declared, followed by the constants
+	ih := ot.(px.PuppetObject).InitHash()
+	for _, key := range []string{`attributes`, `constants`} {
+		if ah, ok := ih.Get4(key); ok {
+			ah.(px.OrderedMap).EachKey(func(k px.Value) {
+				if m, ok := ot.Member(k.String()); ok {
+					if a, ok := m.(px.Attribute); ok {
+						g.writeField(b, a)
+					}
+				}
+			})
+		}
+	}
+	b.WriteString("}\n\n")
+}
+
+func (g *generator) writeField(b *bytes.Buffer, a px.Attribute) {
+	fn := a.GoName()
+	if fn == `` {
+		fn = fieldName(a.Name())
+	}
+
+	at := a.Type()
+	gt := g.goType(at)
+	_, optional := at.(*types.OptionalType)
+
+	tags := make([]string, 0, 4)
+	if issue.FirstToLower(fn) != a.Name() {
+		tags = append(tags, `name=>`+a.Name())
+	}
+	if a.Kind() != `` {
+		tags = append(tags, `kind=>`+string(a.Kind()))
+	}
+	if a.HasValue() && !(optional && a.Value().Equals(px.Undef, nil)) {
+		tags = append(tags, `value=>`+literal(a.Value()))
+	}
+	if !gt.typ.Equals(at, nil) {
+		tags = append(tags, `type=>`+at.String())
+	}
+
+	b.WriteString(fn)
+	b.WriteByte(' ')
+	b.WriteString(gt.src)
+
+	st := make([]string, 0, 2)
+	if len(tags) > 0 {
+		st = append(st, `puppet:`+strconv.Quote(strings.Join(tags, `, `)))
+	}
+	if ta := a.Tags(g.c); ta != nil {
+		ta.Tags().EachPair(func(k, v px.Value) {
+			st = append(st, k.String()+`:`+strconv.Quote(v.String()))
+		})
+	}
+	if len(st) > 0 {
+		tag := strings.Join(st, ` `)
+		if strings.ContainsRune(tag, '`') {
+			tag = strconv.Quote(tag)
+		} else {
+			tag = "`" + tag + "`"
+		}
+		b.WriteByte(' ')
+		b.WriteString(tag)
+	}
+	b.WriteByte('\n')
+}
+
+// goType returns the Go type that the given type maps to
+func (g *generator) goType(t px.Type) goType {
+	switch t := t.(type) {
+	case *types.OptionalType:
+		et := g.goType(t.ContainedType())
+		if et.nilable {
+			return et
+		}
+		return goType{src: `*` + et.src, typ: types.NewOptionalType(et.typ), nilable: true}
+	case *types.ArrayType:
+		et := g.goType(t.ElementType())
+		return goType{src: `[]` + et.src, typ: types.NewArrayType(et.typ, nil)}
+	case *types.HashType:
+		kt := g.goType(t.KeyType())
+		vt := g.goType(t.ValueType())
+		return goType{src: `map[` + kt.src + `]` + vt.src, typ: types.NewHashType(kt.typ, vt.typ, nil)}
+	case px.ObjectType:
+		if n, ok := g.memberName(t); ok {
+			return goType{src: n, typ: t}
+		}
+		return g.leafType(valueType)
+	case *types.IntegerType:
+		return g.matchingType(t, intTypes)
+	case *types.FloatType:
+		return g.matchingType(t, floatTypes)
+	case *types.BooleanType:
+		return g.leafType(boolType)
+	case px.StringType, *types.EnumType, *types.PatternType:
+		return g.leafType(stringType)
+	case *types.TimespanType:
+		return g.leafType(durationType)
+	case *types.TimestampType:
+		return g.leafType(timeType)
+	case *types.BinaryType:
+		return g.leafType(binaryType)
+	case *types.SemVerType:
+		return g.leafType(semVerType)
+	case *types.SensitiveType:
+		return g.leafType(sensitiveType)
+	case *types.UriType:
+		return g.leafType(uriType)
+	case *types.RegexpType:
+		return g.leafType(regexpType)
+	case *types.TypeType:
+		return g.leafType(typeType)
+	}
+	return g.leafType(valueType)
+}
+
+// matchingType returns the first of the given leaf types that maps exactly to the given type, or the first
+// leaf type if no such type exists
+func (g *generator) matchingType(t px.Type, lts []leafType) goType {
+	for _, lt := range lts {
+		gt := g.leafType(lt)
+		if gt.typ.Equals(t, nil) {
+			return gt
+		}
+	}
+	return g.leafType(lts[0])
+}
+
+func (g *generator) leafType(lt leafType) goType {
+	t, err := px.WrapReflectedType(g.c, lt.rt)
+	if err != nil {
+		panic(err)
+	}
+	if lt.pkg != `` {
+		g.imports[lt.pkg] = true
+	}
+	return goType{src: lt.src, typ: t, nilable: lt.nilable}
+}
+
+// memberName returns the name of the given type relative to the TypeSet when the type is an Object type
+// that is a member of the TypeSet
+func (g *generator) memberName(t px.Type) (string, bool) {
+	for i, ot := range g.objects {
+		if ot.Equals(t, nil) {
+			return g.names[i], true
+		}
+	}
+	return ``, false
+}
+
+// fieldName converts an attribute name into an exported Go field name
+func fieldName(name string) string {
+	b := bytes.NewBufferString(``)
+	for _, w := range strings.Split(name, `_`) {
+		if w != `` {
+			b.WriteString(strings.ToUpper(w[:1]))
+			b.WriteString(w[1:])
+		}
+	}
+	return b.String()
+}
+
+// literal returns the given value in a form that can be parsed back into the same value
+func literal(v px.Value) string {
+	if s, ok := v.(px.StringValue); ok {
+		b := bytes.NewBufferString(``)
+		utils.PuppetQuote(b, s.String())
+		return b.String()
+	}
+	return v.String()
+}
+
+func quote(s string) string {
+	return "`" + s + "`"
+}
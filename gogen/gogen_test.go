@@ -0,0 +1,76 @@
+package gogen_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/lyraproj/pcore/gogen"
+	_ "github.com/lyraproj/pcore/gogen/testdata/own"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/stretchr/testify/require"
+)
+
+const ownTypeSet = `TypeSet[{
+  name => 'My::Own',
+  version => '1.0.0',
+  pcore_uri => 'http://puppet.com/2016.1/pcore',
+  pcore_version => '1.0.0',
+  types => {
+    Address => {
+      attributes => {
+        street => String,
+        zip_code => String[5, 5],
+        country => { type => String, value => 'SE' }
+      }
+    },
+    Person => {
+      attributes => {
+        name => String,
+        age => Integer[0, 255],
+        address => Optional[Address],
+        contact => Variant[Address, String],
+        tags => Array[String],
+        mode => Enum[r, w],
+        born => Optional[Timestamp],
+        extra => Hash[String, Integer],
+        secret => Optional[Sensitive],
+        kind => { type => String, kind => constant, value => 'person' }
+      }
+    },
+    Employee => Person {
+      attributes => {
+        salary => Float,
+        manager => Optional[Employee],
+        id => {
+          type => Integer,
+          annotations => { TagsAnnotation => { tags => { json => 'id,omitempty' } } }
+        }
+      }
+    }
+  }
+}]`
+
+func TestGenerate_roundTrip(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		// The testdata/own package was generated from this TypeSet and its init() has declared My::Own
+		// using the generated structs
+		expected := c.ParseType(ownTypeSet).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		b := bytes.NewBufferString(``)
+		gogen.Generate(c, expected, `own`, b)
+		src, err := ioutil.ReadFile(`testdata/own/own.go`)
+		require.NoError(t, err)
+		require.Equal(t, string(src), b.String())
+
+		lt, ok := px.Load(c, px.NewTypedName(px.NsType, `My::Own`))
+		require.True(t, ok)
+		actual := lt.(px.TypeSet)
+		require.True(t, expected.Equals(actual, nil))
+		require.Equal(t, expected.Types().Keys(), actual.Types().Keys())
+		expected.Types().EachPair(func(k, et px.Value) {
+			at := actual.Types().Get5(k.String(), px.Undef)
+			require.True(t, et.Equals(at, nil), `%s != %s`, et, at)
+		})
+	})
+}
@@ -0,0 +1,48 @@
+// Code generated by pcore-gogen from the TypeSet My::Own. DO NOT EDIT.
+
+package own
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+)
+
+// Address is the Go implementation of My::Own::Address
+type Address struct {
+	Street  string
+	ZipCode string `puppet:"name=>zip_code, type=>String[5, 5]"`
+	Country string `puppet:"value=>'SE'"`
+}
+
+// Person is the Go implementation of My::Own::Person
+type Person struct {
+	Name    string
+	Age     uint8
+	Address *Address
+	Contact px.Value `puppet:"type=>Variant[My::Own::Address, String]"`
+	Tags    []string
+	Mode    string `puppet:"type=>Enum['r', 'w']"`
+	Born    *time.Time
+	Extra   map[string]int64
+	Secret  *types.Sensitive `puppet:"type=>Optional[Sensitive]"`
+	Kind    string           `puppet:"kind=>constant, value=>'person'"`
+}
+
+// Employee is the Go implementation of My::Own::Employee
+type Employee struct {
+	Person
+	Salary  float64
+	Manager *Employee
+	Id      int64 `json:"id,omitempty"`
+}
+
+func init() {
+	px.NewGoTypeSet(`My::Own`, semver.MustParseVersion(`1.0.0`), nil,
+		reflect.TypeOf(&Address{}),
+		reflect.TypeOf(&Person{}),
+		reflect.TypeOf(&Employee{}))
+}
@@ -0,0 +1,25 @@
+// Package testutil contains helpers that are shared by the tests of several packages
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// Reported calls the given function and returns the issue.Reported that it panics with. It panics if the
+// function returns normally or panics with something else.
+func Reported(f func()) (reported issue.Reported) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			panic(fmt.Errorf(`expected panic didn't happen`))
+		}
+		var ok bool
+		if reported, ok = r.(issue.Reported); !ok {
+			panic(r)
+		}
+	}()
+	f()
+	return
+}
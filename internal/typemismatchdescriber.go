@@ -990,6 +990,19 @@ func init() {
 			return strings.Join(rs, "\n")
 		}
 	}
+
+	px.DescribeMismatch2 = func(path string, expected, actual px.Type) string {
+		var p []*pathElement
+		if path != `` {
+			p = []*pathElement{{path, subject}}
+		}
+		result := describe(expected, actual, p)
+		rs := make([]string, len(result))
+		for i, r := range result {
+			rs[i] = strings.TrimSpace(formatMismatch(r))
+		}
+		return strings.Join(rs, "\n")
+	}
 }
 
 func describeSignatures(signatures []px.Signature, argsTuple px.Type, block px.Lambda) string {
@@ -0,0 +1,344 @@
+// Package jsonschema converts between pcore types and JSON Schema documents, draft 2020-12.
+//
+// A schema is represented as a px.OrderedMap that contains Data only. It can be written as JSON
+// using a serialization.Serializer with a JSON streamer.
+//
+// Export maps pcore types to JSON Schema as follows:
+//
+// Any, Unit, and other types that have no JSON Schema counterpart, such as Callable, Iterable,
+// Runtime, and unresolved type references, map to the empty (unconstrained) schema.
+//
+// Undef maps to type "null", Boolean to type "boolean", Integer to type "integer", Float and Numeric
+// to type "number", and String, Enum, and Pattern to type "string". The ranges of Integer and Float
+// map to minimum and maximum, and the size of a String to minLength and maxLength.
+//
+// Optional[T] maps to anyOf T and null. NotUndef[T] maps to T, or to a schema that disallows null
+// when T is Any. An optional Struct key and an Object attribute that has a value (including the
+// implicit undef value of an Optional attribute) are not listed as required.
+//
+// Array, Tuple, Hash, and Struct map to schemas of type "array" or "object". The size constraints map
+// to minItems/maxItems and minProperties/maxProperties. The element types of a Tuple map to
+// prefixItems, and when the Tuple can be longer than its number of types, the last type maps to items.
+// A Hash key type that is a String, Enum, or Pattern maps to propertyNames. Struct schemas do not
+// permit additional properties.
+//
+// Variant maps to anyOf.
+//
+// Named types, i.e. Object types and type aliases, are added to $defs and referenced using $ref. The
+// parent of an Object type is referenced from an allOf. The definition of an Object type permits
+// additional properties so that it can be extended. References to it use unevaluatedProperties to
+// prevent them. The definition of an Object type has the name of the type as its title, and a
+// `description` tag in the TagsAnnotation of the type or of one of its attributes becomes the description
+// keyword of the definition or of the property.
+//
+// The following constructs are approximated:
+//
+// Enum[..., true] (case insensitive) is exported as a case sensitive enum.
+//
+// Patterns are exported verbatim although Go regular expressions are not fully compatible with the
+// ECMA-262 dialect that JSON Schema uses.
+//
+// Hash key types other than String, Enum, and Pattern are not exported since JSON object keys are
+// always strings.
+//
+// Timestamp maps to a string with format "date-time", Binary to a string with contentEncoding
+// "base64", Regexp to a string with format "regex", and Timespan, SemVer, SemVerRange, URI, and Type
+// to type "string". The rich data representation that the Serializer uses for these types when
+// streaming to JSON is not described by the schema.
+//
+// Sensitive[T] maps to T with writeOnly true.
+//
+// Constants, and derived attributes of Object types are not exported. Attribute values are exported as
+// default when they are Data.
+package jsonschema
+
+import (
+	"math"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// SchemaURI is the URI of the JSON Schema draft that is produced by Export and understood by Import
+const SchemaURI = `https://json-schema.org/draft/2020-12/schema`
+
+// Export returns a JSON Schema for the given type. Named types are added to the $defs of the returned
+// schema. If the given type is itself named, the schema will be a $ref to its definition.
+//
+// When the type is a TypeSet, all types in the set are added to $defs and the returned schema has no
+// other constraints.
+func Export(c px.Context, t px.Type) px.OrderedMap {
+	e := &exporter{c: c, defs: make(map[string]px.Value), names: make([]string, 0)}
+	var s []*types.HashEntry
+	if ts, ok := t.(px.TypeSet); ok {
+		s = []*types.HashEntry{types.WrapHashEntry2(`title`, types.WrapString(ts.Name()))}
+		ts.Types().EachValue(func(v px.Value) { e.schema(v.(px.Type)) })
+	} else {
+		s = entries(e.schema(t))
+	}
+	s = append([]*types.HashEntry{types.WrapHashEntry2(`$schema`, types.WrapString(SchemaURI))}, s...)
+	if len(e.names) > 0 {
+		ds := make([]*types.HashEntry, len(e.names))
+		for i, n := range e.names {
+			ds[i] = types.WrapHashEntry2(n, e.defs[n])
+		}
+		s = append(s, types.WrapHashEntry2(`$defs`, types.WrapHash(ds)))
+	}
+	return types.WrapHash(s)
+}
+
+type exporter struct {
+	c     px.Context
+	defs  map[string]px.Value
+	names []string
+}
+
+// schema returns the schema for the given type
+func (e *exporter) schema(t px.Type) px.Value {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		return e.ref(t.Name(), func() px.Value { return e.schema(t.ResolvedType()) })
+	case px.ObjectType:
+		// Object schemas are left open so that they can be extended using allOf. They are closed
+		// where they are used instead.
+		var s []*types.HashEntry
+		if t.Name() == `` {
+			s = entries(e.objectSchema(t))
+		} else {
+			s = entries(e.objectRef(t))
+		}
+		return types.WrapHash(append(s, types.WrapHashEntry2(`unevaluatedProperties`, types.BooleanFalse)))
+	case *types.UndefType:
+		return typeSchema(`null`)
+	case *types.OptionalType:
+		return anyOf(e.schema(t.ContainedType()), typeSchema(`null`))
+	case *types.NotUndefType:
+		if _, ok := t.ContainedType().(*types.AnyType); ok {
+			return schemaHash(`not`, typeSchema(`null`))
+		}
+		return e.schema(t.ContainedType())
+	case *types.BooleanType:
+		return typeSchema(`boolean`)
+	case *types.IntegerType:
+		s := []*types.HashEntry{typeEntry(`integer`)}
+		if t.Min() != math.MinInt64 {
+			s = append(s, types.WrapHashEntry2(`minimum`, types.WrapInteger(t.Min())))
+		}
+		if t.Max() != math.MaxInt64 {
+			s = append(s, types.WrapHashEntry2(`maximum`, types.WrapInteger(t.Max())))
+		}
+		return types.WrapHash(s)
+	case *types.FloatType:
+		s := []*types.HashEntry{typeEntry(`number`)}
+		if t.Min() != -math.MaxFloat64 {
+			s = append(s, types.WrapHashEntry2(`minimum`, types.WrapFloat(t.Min())))
+		}
+		if t.Max() != math.MaxFloat64 {
+			s = append(s, types.WrapHashEntry2(`maximum`, types.WrapFloat(t.Max())))
+		}
+		return types.WrapHash(s)
+	case *types.NumericType:
+		return typeSchema(`number`)
+	case px.StringType:
+		if v := t.Value(); v != nil {
+			return schemaHash(`const`, types.WrapString(*v))
+		}
+		s := []*types.HashEntry{typeEntry(`string`)}
+		return types.WrapHash(append(s, sizeEntries(t.Size().(*types.IntegerType), `minLength`, `maxLength`)...))
+	case *types.EnumType:
+		ss := t.Strings()
+		if len(ss) == 0 {
+			return typeSchema(`string`)
+		}
+		return schemaHash(`enum`, types.WrapStrings(ss))
+	case *types.PatternType:
+		ps := t.Patterns()
+		switch ps.Len() {
+		case 0:
+			return typeSchema(`string`)
+		case 1:
+			return patternSchema(ps.At(0).(*types.RegexpType))
+		}
+		return anyOf(ps.Map(func(p px.Value) px.Value { return patternSchema(p.(*types.RegexpType)) }).(*types.Array).AppendTo(nil)...)
+	case *types.RegexpType:
+		return types.WrapHash([]*types.HashEntry{typeEntry(`string`), types.WrapHashEntry2(`format`, types.WrapString(`regex`))})
+	case *types.ScalarDataType, *types.ScalarType:
+		return typeSchema(`string`, `number`, `boolean`)
+	case *types.TimestampType:
+		return types.WrapHash([]*types.HashEntry{typeEntry(`string`), types.WrapHashEntry2(`format`, types.WrapString(`date-time`))})
+	case *types.BinaryType:
+		return types.WrapHash([]*types.HashEntry{typeEntry(`string`), types.WrapHashEntry2(`contentEncoding`, types.WrapString(`base64`))})
+	case *types.TimespanType, *types.SemVerType, *types.SemVerRangeType, *types.UriType, *types.TypeType:
+		return typeSchema(`string`)
+	case *types.SensitiveType:
+		s := entries(e.schema(t.ContainedType()))
+		return types.WrapHash(append(s, types.WrapHashEntry2(`writeOnly`, types.BooleanTrue)))
+	case *types.ArrayType:
+		s := []*types.HashEntry{typeEntry(`array`)}
+		if _, ok := t.ElementType().(*types.AnyType); !ok {
+			s = append(s, types.WrapHashEntry2(`items`, e.schema(t.ElementType())))
+		}
+		return types.WrapHash(append(s, sizeEntries(t.Size(), `minItems`, `maxItems`)...))
+	case *types.TupleType:
+		tts := t.Types()
+		s := []*types.HashEntry{typeEntry(`array`)}
+		if len(tts) > 0 {
+			ps := make([]px.Value, len(tts))
+			for i, tt := range tts {
+				ps[i] = e.schema(tt)
+			}
+			s = append(s, types.WrapHashEntry2(`prefixItems`, types.WrapValues(ps)))
+			if t.Size().Max() > int64(len(tts)) {
+				s = append(s, types.WrapHashEntry2(`items`, ps[len(ps)-1]))
+			} else {
+				s = append(s, types.WrapHashEntry2(`items`, types.BooleanFalse))
+			}
+		}
+		return types.WrapHash(append(s, sizeEntries(t.Size(), `minItems`, `maxItems`)...))
+	case *types.HashType:
+		s := []*types.HashEntry{typeEntry(`object`)}
+		switch kt := t.KeyType().(type) {
+		case px.StringType, *types.EnumType, *types.PatternType:
+			if ks := e.schema(kt).(*types.Hash); !ks.Equals(typeSchema(`string`), nil) {
+				s = append(s, types.WrapHashEntry2(`propertyNames`, ks))
+			}
+		}
+		if _, ok := t.ValueType().(*types.AnyType); !ok {
+			s = append(s, types.WrapHashEntry2(`additionalProperties`, e.schema(t.ValueType())))
+		}
+		return types.WrapHash(append(s, sizeEntries(t.Size(), `minProperties`, `maxProperties`)...))
+	case *types.StructType:
+		es := t.Elements()
+		ps := make([]*types.HashEntry, len(es))
+		rq := make([]string, 0, len(es))
+		for i, se := range es {
+			ps[i] = types.WrapHashEntry2(se.Name(), e.schema(se.Value()))
+			if !se.Optional() {
+				rq = append(rq, se.Name())
+			}
+		}
+		return objectSchema(nil, ps, rq, `additionalProperties`)
+	case *types.VariantType:
+		vs := t.Types()
+		ss := make([]px.Value, len(vs))
+		for i, v := range vs {
+			ss[i] = e.schema(v)
+		}
+		return anyOf(ss...)
+	case *types.DefaultType:
+		return schemaHash(`const`, types.WrapString(`default`))
+	}
+	return px.EmptyMap
+}
+
+// ref adds a definition with the given name unless it exists and returns a $ref to it
+func (e *exporter) ref(name string, f func() px.Value) px.Value {
+	if _, ok := e.defs[name]; !ok {
+		// Reserve the name first to prevent endless recursion in self referencing types
+		e.defs[name] = px.EmptyMap
+		e.names = append(e.names, name)
+		e.defs[name] = f()
+	}
+	return schemaHash(`$ref`, types.WrapString(`#/$defs/`+name))
+}
+
+func (e *exporter) objectSchema(t px.ObjectType) px.Value {
+	s := make([]*types.HashEntry, 0, 4)
+	if t.Name() != `` {
+		s = append(s, types.WrapHashEntry2(`title`, types.WrapString(t.Name())))
+	}
+	if d := types.Description(e.c, t); d != `` {
+		s = append(s, types.WrapHashEntry2(`description`, types.WrapString(d)))
+	}
+	if p := t.Parent(); p != nil {
+		if pt, ok := p.(px.ObjectType); ok {
+			s = append(s, types.WrapHashEntry2(`allOf`, types.SingletonArray(e.objectRef(pt))))
+		}
+	}
+
+	ps := make([]*types.HashEntry, 0)
+	rq := make([]string, 0)
+	for _, a := range t.AttributesInfo().Attributes() {
+		if a.Container() != t {
+			// Inherited attribute
+			continue
+		}
+		switch a.Kind() {
+		case `constant`, `derived`:
+			continue
+		}
+		as := entries(e.schema(a.Type()))
+		if d := types.Description(e.c, a); d != `` {
+			as = append(as, types.WrapHashEntry2(`description`, types.WrapString(d)))
+		}
+		if a.HasValue() {
+			if v := a.Value(); v != px.Undef && px.IsInstance(types.DefaultDataType(), v) {
+				as = append(as, types.WrapHashEntry2(`default`, v))
+			}
+		} else {
+			rq = append(rq, a.Name())
+		}
+		ps = append(ps, types.WrapHashEntry2(a.Name(), types.WrapHash(as)))
+	}
+	return objectSchema(s, ps, rq, ``)
+}
+
+// objectRef returns a $ref to the definition of the given object type
+func (e *exporter) objectRef(t px.ObjectType) px.Value {
+	return e.ref(t.Name(), func() px.Value { return e.objectSchema(t) })
+}
+
+// objectSchema creates a schema of type "object" with the given properties. The closing keyword, if
+// given, is used to prevent additional properties
+func objectSchema(s []*types.HashEntry, ps []*types.HashEntry, rq []string, closing string) px.Value {
+	s = append(s, typeEntry(`object`))
+	if len(ps) > 0 {
+		s = append(s, types.WrapHashEntry2(`properties`, types.WrapHash(ps)))
+	}
+	if len(rq) > 0 {
+		s = append(s, types.WrapHashEntry2(`required`, types.WrapStrings(rq)))
+	}
+	if closing != `` {
+		s = append(s, types.WrapHashEntry2(closing, types.BooleanFalse))
+	}
+	return types.WrapHash(s)
+}
+
+// entries returns the entries of the given schema
+func entries(s px.Value) []*types.HashEntry {
+	return s.(*types.Hash).AppendEntriesTo(nil)
+}
+
+func patternSchema(rx *types.RegexpType) px.Value {
+	return types.WrapHash([]*types.HashEntry{typeEntry(`string`), types.WrapHashEntry2(`pattern`, types.WrapString(rx.PatternString()))})
+}
+
+func sizeEntries(sz *types.IntegerType, minKey, maxKey string) []*types.HashEntry {
+	s := make([]*types.HashEntry, 0, 2)
+	if sz.Min() > 0 {
+		s = append(s, types.WrapHashEntry2(minKey, types.WrapInteger(sz.Min())))
+	}
+	if sz.Max() != math.MaxInt64 {
+		s = append(s, types.WrapHashEntry2(maxKey, types.WrapInteger(sz.Max())))
+	}
+	return s
+}
+
+func anyOf(ss ...px.Value) px.Value {
+	return schemaHash(`anyOf`, types.WrapValues(ss))
+}
+
+func schemaHash(key string, value px.Value) px.Value {
+	return types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(key, value)})
+}
+
+func typeEntry(name string) *types.HashEntry {
+	return types.WrapHashEntry2(`type`, types.WrapString(name))
+}
+
+func typeSchema(names ...string) px.Value {
+	if len(names) == 1 {
+		return types.WrapHash([]*types.HashEntry{typeEntry(names[0])})
+	}
+	return schemaHash(`type`, types.WrapStrings(names))
+}
@@ -0,0 +1,153 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lyraproj/pcore/jsonschema"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+)
+
+func toJson(c px.Context, v px.Value) string {
+	b := bytes.NewBufferString(``)
+	serialization.NewSerializer(c, px.EmptyMap).Convert(v, serialization.NewJsonStreamer2(b, px.SingletonMap(`indent`, types.WrapInteger(2))))
+	return b.String()
+}
+
+func ExampleExport() {
+	pcore.Do(func(c px.Context) {
+		t := c.ParseType(`Struct[{name => String[1], port => Integer[1, 65535], Optional[tags] => Array[Enum[a, b], 1, 3]}]`)
+		fmt.Println(toJson(c, jsonschema.Export(c, t)))
+	})
+	// Output:
+	// {
+	//   "$schema": "https://json-schema.org/draft/2020-12/schema",
+	//   "type": "object",
+	//   "properties": {
+	//     "name": {
+	//       "type": "string",
+	//       "minLength": 1
+	//     },
+	//     "port": {
+	//       "type": "integer",
+	//       "minimum": 1,
+	//       "maximum": 65535
+	//     },
+	//     "tags": {
+	//       "type": "array",
+	//       "items": {
+	//         "enum": [
+	//           "a",
+	//           "b"
+	//         ]
+	//       },
+	//       "minItems": 1,
+	//       "maxItems": 3
+	//     }
+	//   },
+	//   "required": [
+	//     "name",
+	//     "port"
+	//   ],
+	//   "additionalProperties": false
+	// }
+}
+
+func ExampleExport_object() {
+	pcore.Do(func(c px.Context) {
+		ts := px.NewObjectType(`Shape`, `{
+			annotations => { TagsAnnotation => { tags => { description => 'A shape' }}},
+			attributes => {
+				name => { type => String, value => 'unnamed' },
+				area => { type => Float, kind => derived }
+			}
+		}`)
+		ct := px.NewObjectType(`Circle`, `Shape{
+			attributes => {
+				radius => Float[0.0],
+				center => Optional[Tuple[Integer, Integer]]
+			}
+		}`)
+		px.AddTypes(c, ts, ct)
+		fmt.Println(toJson(c, jsonschema.Export(c, ct)))
+	})
+	// Output:
+	// {
+	//   "$schema": "https://json-schema.org/draft/2020-12/schema",
+	//   "$ref": "#/$defs/Circle",
+	//   "unevaluatedProperties": false,
+	//   "$defs": {
+	//     "Circle": {
+	//       "title": "Circle",
+	//       "allOf": [
+	//         {
+	//           "$ref": "#/$defs/Shape"
+	//         }
+	//       ],
+	//       "type": "object",
+	//       "properties": {
+	//         "radius": {
+	//           "type": "number",
+	//           "minimum": 0.0
+	//         },
+	//         "center": {
+	//           "anyOf": [
+	//             {
+	//               "type": "array",
+	//               "prefixItems": [
+	//                 {
+	//                   "type": "integer"
+	//                 },
+	//                 {
+	//                   "type": "integer"
+	//                 }
+	//               ],
+	//               "items": false,
+	//               "minItems": 2,
+	//               "maxItems": 2
+	//             },
+	//             {
+	//               "type": "null"
+	//             }
+	//           ]
+	//         }
+	//       },
+	//       "required": [
+	//         "radius"
+	//       ]
+	//     },
+	//     "Shape": {
+	//       "title": "Shape",
+	//       "description": "A shape",
+	//       "type": "object",
+	//       "properties": {
+	//         "name": {
+	//           "type": "string",
+	//           "default": "unnamed"
+	//         }
+	//       }
+	//     }
+	//   }
+	// }
+}
+
+func ExampleExport_typeSetDefs() {
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(`TypeSet[{
+			name => 'Net',
+			version => '1.0.0',
+			pcore_version => '1.0.0',
+			types => {
+				Port => Integer[1, 65535],
+				Server => Object[attributes => { host => String, ports => Hash[Pattern[/^[a-z]+$/], Port] }]
+			}}]`)
+		px.AddTypes(c, ts)
+		b := bytes.NewBufferString(``)
+		serialization.NewSerializer(c, px.EmptyMap).Convert(jsonschema.Export(c, ts), serialization.NewJsonStreamer(b))
+		fmt.Println(b)
+	})
+	// Output: {"$schema":"https://json-schema.org/draft/2020-12/schema","title":"Net","$defs":{"Net::Port":{"type":"integer","minimum":1,"maximum":65535},"Net::Server":{"title":"Net::Server","type":"object","properties":{"host":{"type":"string"},"ports":{"type":"object","propertyNames":{"type":"string","pattern":"^[a-z]+$"},"additionalProperties":{"$ref":"#/$defs/Net::Port"}}},"required":["host","ports"]}}}
+}
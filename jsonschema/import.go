@@ -0,0 +1,573 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+)
+
+// ImportJson reads a JSON Schema document from the given reader and imports it using Import. The path
+// is only used in error messages.
+func ImportJson(c px.Context, name, path string, in io.Reader) px.Type {
+	dc := types.NewCollector()
+	serialization.JsonToData(path, in, dc)
+	return Import(c, name, dc.Value())
+}
+
+// Import creates a type from the given JSON Schema. The schema must be a Hash or a Boolean.
+//
+// When the schema contains $defs (or the older definitions), a TypeSet with the given name is created
+// that contains one type alias per definition. The TypeSet is added to the loader of the given context
+// using px.AddTypes. The name of each alias is derived from the name of the definition by removing
+// characters that are not letters or digits and capitalizing each word, so that the definition
+// "shipping-address" becomes the type <name>::ShippingAddress. Each $ref to a definition becomes a
+// reference to the corresponding alias.
+//
+// Schemas are mapped as follows:
+//
+// The type keyword is mapped to Undef, Boolean, Integer, Numeric (or a Variant of a ranged Integer and
+// Float), String, Array, Tuple, Hash, or Struct. Several types yield a Variant, or an Optional when one
+// of them is "null". When the type keyword is missing, the type is inferred from the keywords that are
+// present. The Integer of a number is omitted when no integer is within its bounds, and bounds that no
+// value is within result in an InvalidJsonSchema error.
+//
+// An object that declares properties becomes a Struct where properties that aren't required are
+// optional. Other objects become a Hash where propertyNames is used for the key type and
+// additionalProperties for the value type.
+//
+// An array with prefixItems becomes a Tuple, other arrays become an Array.
+//
+// A string with a pattern becomes a Pattern, other strings become a String with a size range.
+//
+// The enum and const keywords become an Enum when all values are strings and otherwise a Variant
+// of types that only match the given values.
+//
+// The anyOf and oneOf keywords become a Variant. The allOf keyword is supported when all its
+// schemas, after following references, describe objects. The properties of those schemas are then
+// merged into one Struct.
+//
+// The following constructs are approximated or ignored:
+//
+// Exclusive bounds on numbers other than integers are treated as inclusive bounds.
+//
+// The length of a string is ignored when it also has a pattern. The format keyword is ignored.
+//
+// Additional properties are never permitted in an object that declares properties.
+//
+// The not, if, then, else, dependentSchemas, and dependentRequired keywords are ignored. So are all
+// keywords that appear next to a $ref.
+//
+// Only references to local definitions ("#/$defs/name" or "#/definitions/name") are supported. A
+// px.InvalidJsonSchema error is raised for other references and for schemas that cannot be imported.
+func Import(c px.Context, name string, schema px.Value) px.Type {
+	im := &importer{name: name, names: make(map[string]string)}
+	if sh, ok := schema.(px.OrderedMap); ok {
+		im.defs, _ = sh.Get4(`$defs`)
+		im.defsKey = `$defs`
+		if im.defs == nil {
+			im.defs, _ = sh.Get4(`definitions`)
+			im.defsKey = `definitions`
+		}
+	}
+
+	if dh, ok := im.defs.(px.OrderedMap); ok && dh.Len() > 0 {
+		taken := make(map[string]bool, dh.Len())
+		dh.EachKey(func(k px.Value) {
+			tn := typeName(k.String())
+			for i := 2; taken[tn]; i++ {
+				tn = typeName(k.String()) + strconv.Itoa(i)
+			}
+			taken[tn] = true
+			im.names[k.String()] = tn
+		})
+
+		b := bytes.NewBufferString(`TypeSet[{name => `)
+		b.WriteString(quote(name))
+		b.WriteString(`, version => '1.0.0', pcore_version => '1.0.0', types => {`)
+		first := true
+		dh.EachPair(func(k, v px.Value) {
+			if first {
+				first = false
+			} else {
+				b.WriteString(`, `)
+			}
+			b.WriteString(im.names[k.String()])
+			b.WriteString(` => `)
+			b.WriteString(im.expr(`#/`+im.defsKey+`/`+k.String(), v))
+		})
+		b.WriteString(`}}]`)
+		px.AddTypes(c, c.ParseType(b.String()))
+		im.prefix = name + `::`
+	}
+	return c.ParseType(im.expr(`#`, schema))
+}
+
+type importer struct {
+	name    string
+	prefix  string
+	defs    px.Value
+	defsKey string
+	names   map[string]string
+}
+
+// expr returns a type expression for the given schema
+func (im *importer) expr(path string, schema px.Value) string {
+	var s px.OrderedMap
+	switch sv := schema.(type) {
+	case px.Boolean:
+		if sv.Bool() {
+			return `Any`
+		}
+		// A Variant without types matches nothing
+		return `Variant`
+	case px.OrderedMap:
+		s = sv
+	default:
+		panic(invalid(path, `a schema must be an object or a boolean`))
+	}
+
+	if ref, ok := s.Get4(`$ref`); ok {
+		return im.ref(path, ref.String())
+	}
+	if ao, ok := s.Get4(`allOf`); ok {
+		return im.expr(path, im.mergeAllOf(path, s, ao))
+	}
+	if vs, ok := s.Get4(`anyOf`); ok {
+		return im.variant(path+`/anyOf`, vs)
+	}
+	if vs, ok := s.Get4(`oneOf`); ok {
+		return im.variant(path+`/oneOf`, vs)
+	}
+	if cv, ok := s.Get4(`const`); ok {
+		return literals(path, types.SingletonArray(cv))
+	}
+	if ev, ok := s.Get4(`enum`); ok {
+		if el, ok := ev.(px.List); ok {
+			return literals(path, el)
+		}
+		panic(invalid(path, `enum must be an array`))
+	}
+
+	var tns []string
+	switch tv := s.Get5(`type`, px.Undef).(type) {
+	case px.StringValue:
+		tns = []string{tv.String()}
+	case px.List:
+		tns = px.StringElements(tv)
+	default:
+		tns = []string{inferType(s)}
+	}
+
+	nullable := false
+	es := make([]string, 0, len(tns))
+	for _, tn := range tns {
+		if tn == `null` {
+			nullable = true
+			continue
+		}
+		es = append(es, im.typeExpr(path, tn, s))
+	}
+	var e string
+	switch len(es) {
+	case 0:
+		return `Undef`
+	case 1:
+		e = es[0]
+	default:
+		e = `Variant[` + strings.Join(es, `, `) + `]`
+	}
+	if nullable {
+		e = `Optional[` + e + `]`
+	}
+	return e
+}
+
+// typeExpr returns a type expression for the given value of the type keyword
+func (im *importer) typeExpr(path, tn string, s px.OrderedMap) string {
+	switch tn {
+	case `any`:
+		return `Any`
+	case `boolean`:
+		return `Boolean`
+	case `integer`:
+		min, max := bounds(path, s)
+		if em, ok := number(path, s, `exclusiveMinimum`); ok {
+			f := math.Floor(em) + 1
+			min = &f
+		}
+		if em, ok := number(path, s, `exclusiveMaximum`); ok {
+			f := math.Ceil(em) - 1
+			max = &f
+		}
+		imin, imax, ok := intRange(min, max)
+		if !ok {
+			panic(invalid(path, `no integer is within the bounds`))
+		}
+		return rangeType(`Integer`, intParam(imin), intParam(imax))
+	case `number`:
+		min, max := bounds(path, s)
+		if em, ok := number(path, s, `exclusiveMinimum`); ok {
+			min = &em
+		}
+		if em, ok := number(path, s, `exclusiveMaximum`); ok {
+			max = &em
+		}
+		if min == nil && max == nil {
+			return `Numeric`
+		}
+		if min != nil && max != nil && *min > *max {
+			panic(invalid(path, `no number is within the bounds`))
+		}
+		ft := rangeType(`Float`, floatParam(min), floatParam(max))
+		imin, imax, ok := intRange(min, max)
+		if !ok {
+			return ft
+		}
+		return fmt.Sprintf(`Variant[%s, %s]`, rangeType(`Integer`, intParam(imin), intParam(imax)), ft)
+	case `string`:
+		if p, ok := s.Get4(`pattern`); ok {
+			return patternType(path, p.String())
+		}
+		return rangeType(`String`, intParam(size(path, s, `minLength`)), intParam(size(path, s, `maxLength`)))
+	case `array`:
+		min := intParam(size(path, s, `minItems`))
+		max := intParam(size(path, s, `maxItems`))
+		if pi, ok := s.Get4(`prefixItems`); ok {
+			pl, ok := pi.(px.List)
+			if !ok {
+				panic(invalid(path, `prefixItems must be an array`))
+			}
+			es := make([]string, 0, pl.Len()+1)
+			pl.EachWithIndex(func(e px.Value, i int) {
+				es = append(es, im.expr(fmt.Sprintf(`%s/prefixItems/%d`, path, i), e))
+			})
+			if min == `default` {
+				min = `0`
+			}
+			switch items := s.Get5(`items`, types.BooleanTrue).(type) {
+			case px.Boolean:
+				if !items.Bool() && max == `default` {
+					max = strconv.Itoa(pl.Len())
+				}
+				if items.Bool() {
+					es = append(es, `Any`)
+				}
+			default:
+				es = append(es, im.expr(path+`/items`, items))
+			}
+			return `Tuple[` + strings.Join(es, `, `) + `, ` + min + `, ` + max + `]`
+		}
+		et := `Any`
+		if items, ok := s.Get4(`items`); ok {
+			et = im.expr(path+`/items`, items)
+		}
+		return rangeType(`Array`, et, min, max)
+	case `object`:
+		if ps, ok := s.Get4(`properties`); ok {
+			return im.structExpr(path, s, ps)
+		}
+		kt := `String`
+		if pn, ok := s.Get4(`propertyNames`); ok {
+			kt = im.expr(path+`/propertyNames`, pn)
+		}
+		vt := `Any`
+		if ap, ok := s.Get4(`additionalProperties`); ok {
+			vt = im.expr(path+`/additionalProperties`, ap)
+		}
+		return rangeType(`Hash`, kt, vt, intParam(size(path, s, `minProperties`)), intParam(size(path, s, `maxProperties`)))
+	}
+	panic(invalid(path, fmt.Sprintf(`unknown type '%s'`, tn)))
+}
+
+func (im *importer) structExpr(path string, s px.OrderedMap, props px.Value) string {
+	ph, ok := props.(px.OrderedMap)
+	if !ok {
+		panic(invalid(path, `properties must be an object`))
+	}
+	required := make(map[string]bool)
+	if rq, ok := s.Get4(`required`); ok {
+		if rl, ok := rq.(px.List); ok {
+			for _, r := range px.StringElements(rl) {
+				required[r] = true
+			}
+		} else {
+			panic(invalid(path, `required must be an array`))
+		}
+	}
+	es := make([]string, 0, ph.Len())
+	ph.EachPair(func(k, v px.Value) {
+		key := quote(k.String())
+		if !required[k.String()] {
+			key = `Optional[` + key + `]`
+		}
+		es = append(es, key+` => `+im.expr(path+`/properties/`+k.String(), v))
+	})
+	return `Struct[{` + strings.Join(es, `, `) + `}]`
+}
+
+// mergeAllOf merges the given schema with the object schemas in its allOf into one schema that
+// declares all properties
+func (im *importer) mergeAllOf(path string, s px.OrderedMap, allOf px.Value) px.OrderedMap {
+	al, ok := allOf.(px.List)
+	if !ok {
+		panic(invalid(path, `allOf must be an array`))
+	}
+	props := make([]*types.HashEntry, 0)
+	required := make([]px.Value, 0)
+	var merge func(p string, sv px.Value)
+	merge = func(p string, sv px.Value) {
+		sh, ok := sv.(px.OrderedMap)
+		if !ok {
+			panic(invalid(p, `allOf is only supported for object schemas`))
+		}
+		if ref, ok := sh.Get4(`$ref`); ok {
+			n := im.defName(p, ref.String())
+			merge(`#/`+im.defsKey+`/`+n, im.defs.(px.OrderedMap).Get5(n, px.Undef))
+			return
+		}
+		if ao, ok := sh.Get4(`allOf`); ok {
+			aol, ok := ao.(px.List)
+			if !ok {
+				panic(invalid(p, `allOf must be an array`))
+			}
+			aol.EachWithIndex(func(e px.Value, i int) { merge(fmt.Sprintf(`%s/allOf/%d`, p, i), e) })
+		}
+		if t, ok := sh.Get4(`type`); ok && t.String() != `object` {
+			panic(invalid(p, `allOf is only supported for object schemas`))
+		}
+		if ps, ok := sh.Get4(`properties`); ok {
+			if ph, ok := ps.(px.OrderedMap); ok {
+				props = append(props, ph.(*types.Hash).AppendEntriesTo(nil)...)
+			}
+		}
+		if rq, ok := sh.Get4(`required`); ok {
+			if rl, ok := rq.(px.List); ok {
+				required = rl.AppendTo(required)
+			}
+		}
+	}
+	merge(path, s.RejectPairs(func(k, _ px.Value) bool { return k.String() == `allOf` }))
+	al.EachWithIndex(func(e px.Value, i int) { merge(fmt.Sprintf(`%s/allOf/%d`, path, i), e) })
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`type`, types.WrapString(`object`)),
+		types.WrapHashEntry2(`properties`, types.WrapHash(props)),
+		types.WrapHashEntry2(`required`, types.WrapValues(required))})
+}
+
+func (im *importer) variant(path string, vs px.Value) string {
+	vl, ok := vs.(px.List)
+	if !ok {
+		panic(invalid(path, `expected an array`))
+	}
+	es := make([]string, vl.Len())
+	vl.EachWithIndex(func(v px.Value, i int) { es[i] = im.expr(fmt.Sprintf(`%s/%d`, path, i), v) })
+	return `Variant[` + strings.Join(es, `, `) + `]`
+}
+
+func (im *importer) ref(path, ref string) string {
+	return im.prefix + im.names[im.defName(path, ref)]
+}
+
+// defName returns the name of the definition that the given reference appoints. The reference must be
+// a URI fragment with a JSON pointer (RFC 6901) to a member of $defs or definitions.
+func (im *importer) defName(path, ref string) string {
+	if strings.HasPrefix(ref, `#/`) {
+		if ptr, err := url.PathUnescape(ref[2:]); err == nil {
+			ts := strings.Split(ptr, `/`)
+			if len(ts) == 2 && (ts[0] == `$defs` || ts[0] == `definitions`) {
+				n := strings.NewReplacer(`~1`, `/`, `~0`, `~`).Replace(ts[1])
+				if _, ok := im.names[n]; ok {
+					return n
+				}
+			}
+		}
+	}
+	panic(invalid(path, fmt.Sprintf(`unable to resolve reference '%s'`, ref)))
+}
+
+// inferType returns the name of the type that the keywords of the given schema apply to
+func inferType(s px.OrderedMap) string {
+	for _, k := range []string{`properties`, `additionalProperties`, `propertyNames`, `required`, `minProperties`, `maxProperties`} {
+		if s.IncludesKey2(k) {
+			return `object`
+		}
+	}
+	for _, k := range []string{`items`, `prefixItems`, `minItems`, `maxItems`} {
+		if s.IncludesKey2(k) {
+			return `array`
+		}
+	}
+	for _, k := range []string{`pattern`, `minLength`, `maxLength`} {
+		if s.IncludesKey2(k) {
+			return `string`
+		}
+	}
+	for _, k := range []string{`minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`} {
+		if s.IncludesKey2(k) {
+			return `number`
+		}
+	}
+	return `any`
+}
+
+// literals returns a type that matches the given values only
+func literals(path string, vs px.List) string {
+	if vs.All(func(v px.Value) bool { _, ok := v.(px.StringValue); return ok }) {
+		qs := make([]string, vs.Len())
+		vs.EachWithIndex(func(v px.Value, i int) { qs[i] = quote(v.String()) })
+		return `Enum[` + strings.Join(qs, `, `) + `]`
+	}
+	es := make([]string, vs.Len())
+	vs.EachWithIndex(func(v px.Value, i int) {
+		switch v := v.(type) {
+		case px.StringValue:
+			es[i] = `Enum[` + quote(v.String()) + `]`
+		case px.Integer:
+			es[i] = fmt.Sprintf(`Integer[%d, %d]`, v.Int(), v.Int())
+		case px.Float:
+			f := px.ToString2(v, px.ExactFloats)
+			es[i] = `Float[` + f + `, ` + f + `]`
+		case px.Boolean:
+			es[i] = fmt.Sprintf(`Boolean[%t]`, v.Bool())
+		case *types.UndefValue:
+			es[i] = `Undef`
+		default:
+			panic(invalid(path, `only scalar values and null are supported in enum and const`))
+		}
+	})
+	if len(es) == 1 {
+		return es[0]
+	}
+	return `Variant[` + strings.Join(es, `, `) + `]`
+}
+
+func bounds(path string, s px.OrderedMap) (min, max *float64) {
+	if f, ok := number(path, s, `minimum`); ok {
+		min = &f
+	}
+	if f, ok := number(path, s, `maximum`); ok {
+		max = &f
+	}
+	return
+}
+
+func number(path string, s px.OrderedMap, key string) (float64, bool) {
+	if v, ok := s.Get4(key); ok {
+		switch v := v.(type) {
+		case px.Integer:
+			return float64(v.Int()), true
+		case px.Float:
+			return v.Float(), true
+		}
+		panic(invalid(path, key+` must be a number`))
+	}
+	return 0, false
+}
+
+func size(path string, s px.OrderedMap, key string) *float64 {
+	if f, ok := number(path, s, key); ok {
+		if f != math.Trunc(f) {
+			panic(invalid(path, key+` must be an integer`))
+		}
+		return &f
+	}
+	return nil
+}
+
+// intRange returns the given bounds rounded to the integers within them. The returned flag is false when no
+// integer in the int64 range is within the bounds.
+func intRange(min, max *float64) (imin, imax *float64, ok bool) {
+	if min != nil {
+		f := math.Ceil(*min)
+		imin = &f
+	}
+	if max != nil {
+		f := math.Floor(*max)
+		imax = &f
+	}
+	// float64(math.MaxInt64) is 2^63 which is the first value above the int64 range
+	ok = (imin == nil || *imin < math.MaxInt64) && (imax == nil || *imax >= math.MinInt64) &&
+		(imin == nil || imax == nil || *imin <= *imax)
+	return
+}
+
+// intParam returns the given bound as an integer parameter. A bound outside of the int64 range is
+// clamped to that range.
+func intParam(f *float64) string {
+	switch {
+	case f == nil:
+		return `default`
+	case *f >= math.MaxInt64:
+		return strconv.FormatInt(math.MaxInt64, 10)
+	case *f <= math.MinInt64:
+		return strconv.FormatInt(math.MinInt64, 10)
+	}
+	return strconv.FormatInt(int64(*f), 10)
+}
+
+func floatParam(f *float64) string {
+	if f == nil {
+		return `default`
+	}
+	return px.ToString2(types.WrapFloat(*f), px.ExactFloats)
+}
+
+// rangeType returns the given type name followed by the given parameters. Trailing default parameters
+// are omitted.
+func rangeType(name string, params ...string) string {
+	n := len(params)
+	for n > 0 && params[n-1] == `default` {
+		n--
+	}
+	if n == 0 {
+		return name
+	}
+	return name + `[` + strings.Join(params[:n], `, `) + `]`
+}
+
+// quote returns the given string as a single quoted string literal
+func quote(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + `'`
+}
+
+// patternType returns a Pattern that matches the given regular expression. The type is created rather than
+// written so that the expression is quoted the same way as when any other Pattern is written.
+func patternType(path, rx string) string {
+	r, err := regexp.Compile(rx)
+	if err != nil {
+		panic(invalid(path, err.Error()))
+	}
+	return types.NewPatternType([]*types.RegexpType{types.NewRegexpTypeR(r)}).String()
+}
+
+var nonWordChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// typeName converts the name of a definition into a valid type name
+func typeName(n string) string {
+	b := bytes.NewBufferString(``)
+	for _, w := range nonWordChars.Split(n, -1) {
+		if w != `` {
+			b.WriteString(strings.ToUpper(w[:1]))
+			b.WriteString(w[1:])
+		}
+	}
+	tn := b.String()
+	if tn == `` || tn[0] >= '0' && tn[0] <= '9' {
+		tn = `T` + tn
+	}
+	return tn
+}
+
+func invalid(path, detail string) issue.Reported {
+	return px.Error(px.InvalidJsonSchema, issue.H{`path`: path, `detail`: detail})
+}
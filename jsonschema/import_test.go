@@ -0,0 +1,152 @@
+package jsonschema_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/jsonschema"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func ExampleImportJson() {
+	pcore.Do(func(c px.Context) {
+		t := jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "minLength": 1},
+				"port": {"type": "integer", "minimum": 1, "exclusiveMaximum": 65536},
+				"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "maxItems": 3},
+				"ratio": {"type": ["number", "null"]}
+			},
+			"required": ["name", "port"]
+		}`))
+		fmt.Println(t)
+	})
+	// Output: Struct[{'name' => String[1], 'port' => Integer[1, 65535], Optional['tags'] => Array[Enum['a', 'b'], 0, 3], 'ratio' => Optional[Numeric]}]
+}
+
+func ExampleImport_roundTrip() {
+	pcore.Do(func(c px.Context) {
+		t := c.ParseType(`Struct[{name => String[1], Optional[mode] => Enum[r, w], size => Tuple[Integer, Integer]}]`)
+		fmt.Println(jsonschema.Import(c, `Example`, jsonschema.Export(c, t)))
+	})
+	// Output: Struct[{'name' => String[1], Optional['mode'] => Enum['r', 'w'], 'size' => Tuple[Integer, Integer, 2, 2]}]
+}
+
+func ExampleImport_defs() {
+	pcore.Do(func(c px.Context) {
+		t := jsonschema.ImportJson(c, `Net`, `net.json`, strings.NewReader(`{
+			"$defs": {
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"end-point": {
+					"type": "object",
+					"properties": {"host": {"type": "string"}, "port": {"$ref": "#/$defs/port"}},
+					"required": ["host"]
+				}
+			},
+			"type": "array",
+			"items": {"$ref": "#/$defs/end-point"}
+		}`))
+		fmt.Println(t)
+		fmt.Println(c.ParseType(`Net::EndPoint`).(*types.TypeAliasType).ResolvedType())
+		fmt.Println(px.IsInstance(t, px.Wrap(c, []interface{}{map[string]interface{}{`host`: `example.com`, `port`: 443}})))
+		fmt.Println(px.IsInstance(t, px.Wrap(c, []interface{}{map[string]interface{}{`host`: `example.com`, `port`: 0}})))
+	})
+	// Output:
+	// Array[Net::EndPoint]
+	// Struct[{'host' => String, Optional['port'] => Net::Port}]
+	// true
+	// false
+}
+
+func ExampleImport_pointer() {
+	pcore.Do(func(c px.Context) {
+		t := jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(`{
+			"$defs": {
+				"a b": {"type": "integer", "minimum": 1, "maximum": 1e30},
+				"c/d~e": {"type": "string"}
+			},
+			"type": "array",
+			"prefixItems": [{"$ref": "#/$defs/a%20b"}, {"$ref": "#/$defs/c~1d~0e"}]
+		}`))
+		fmt.Println(t)
+		fmt.Println(c.ParseType(`Example::AB`).(*types.TypeAliasType).ResolvedType())
+	})
+	// Output:
+	// Tuple[Example::AB, Example::CDE, Any, 0, default]
+	// Integer[1]
+}
+
+func ExampleImport_allOf() {
+	pcore.Do(func(c px.Context) {
+		t := jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(`{
+			"$defs": {
+				"named": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+			},
+			"allOf": [
+				{"$ref": "#/$defs/named"},
+				{"properties": {"value": {"const": 3}}}
+			]
+		}`))
+		fmt.Println(t)
+	})
+	// Output: Struct[{'name' => String, Optional['value'] => Integer[3, 3]}]
+}
+
+func ExampleImport_error() {
+	pcore.Do(func(c px.Context) {
+		r := testutil.Reported(func() {
+			jsonschema.Import(c, `Example`, types.WrapStringToInterfaceMap(c, map[string]interface{}{`$ref`: `other.json#/x`}))
+		})
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	})
+	// Output: PCORE_INVALID_JSON_SCHEMA unable to resolve reference 'other.json#/x'
+}
+
+func ExampleImport_fractionalSize() {
+	pcore.Do(func(c px.Context) {
+		r := testutil.Reported(func() {
+			jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(`{"type": "string", "maxLength": 2.5}`))
+		})
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	})
+	// Output: PCORE_INVALID_JSON_SCHEMA maxLength must be an integer
+}
+
+func ExampleImport_bounds() {
+	pcore.Do(func(c px.Context) {
+		for _, schema := range []string{
+			`{"type": "number", "minimum": 0.5, "maximum": 0.7}`,
+			`{"type": "number", "minimum": 1e300, "maximum": 1e301}`,
+			`{"type": "number", "minimum": 1.5, "maximum": 3}`,
+			`{"type": "string", "pattern": "^a/b\\/c\n$"}`,
+		} {
+			fmt.Println(jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(schema)))
+		}
+	})
+	// Output:
+	// Float[0.50000, 0.70000]
+	// Float[1e+300, 1e+301]
+	// Variant[Integer[2, 3], Float[1.50000, 3.00000]]
+	// Pattern[/^a\/b\/c\n$/]
+}
+
+func ExampleImport_emptyRange() {
+	pcore.Do(func(c px.Context) {
+		for _, schema := range []string{
+			`{"type": "integer", "minimum": 0.5, "maximum": 0.7}`,
+			`{"type": "integer", "minimum": 1e300}`,
+			`{"type": "number", "minimum": 2, "maximum": 1}`,
+		} {
+			r := testutil.Reported(func() { jsonschema.ImportJson(c, `Example`, `example.json`, strings.NewReader(schema)) })
+			fmt.Println(r.Code(), r.Argument(`detail`))
+		}
+	})
+	// Output:
+	// PCORE_INVALID_JSON_SCHEMA no integer is within the bounds
+	// PCORE_INVALID_JSON_SCHEMA no integer is within the bounds
+	// PCORE_INVALID_JSON_SCHEMA no number is within the bounds
+}
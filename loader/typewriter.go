@@ -0,0 +1,195 @@
+package loader
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// WritePuppetType writes the given type as a type assignment, i.e. `type <name> = <type expression>`, in the
+// form that InstantiatePuppetType reads. The type must be a type alias, an Object type, or a TypeSet. The
+// members of a TypeSet are written in full. All other named types are written as references.
+func WritePuppetType(c px.Context, t px.Type, out io.Writer) {
+	b := utils.NewIndenter()
+	b.Printf(`type %s = `, t.Name())
+	switch t := t.(type) {
+	case px.TypeSet:
+		tw := newTypeWriter(t.Name())
+		b.Append(`TypeSet[`)
+		tw.writeInitHash(b, t.(px.PuppetObject).InitHash())
+		b.Append(`]`)
+	case px.ObjectType:
+		if t.Name() == `` {
+			panic(px.Error(px.IllegalArgument, issue.H{`function`: `WritePuppetType`, `index`: 1, `arg`: `Object type has no name`}))
+		}
+		tw := newTypeWriter(``)
+		b.Append(`Object[`)
+		tw.writeInitHash(b, t.(px.PuppetObject).InitHash())
+		b.Append(`]`)
+	case *types.TypeAliasType:
+		newTypeWriter(``).writeType(b, t.ResolvedType())
+	default:
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `WritePuppetType`, `index`: 1,
+			`arg`: `type ` + t.Name() + ` is not a type alias, an Object type, or a TypeSet`}))
+	}
+	b.NewLine()
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
+
+// WritePuppetTypeFile writes the given type using WritePuppetType to the file below the given directory
+// where a file based loader for that directory that uses px.PuppetDataTypePath will find it, e.g. the
+// type My::Own is written to <dir>/types/my/own.pp. Missing directories are created. The path of the
+// written file is returned.
+func WritePuppetTypeFile(c px.Context, dir string, t px.Type) string {
+	ml := px.NewFileBasedLoader(c.Loader(), dir, ``, px.PuppetDataTypePath)
+	path := newPuppetTypePath(ml, false).EffectivePath(px.NewTypedName(px.NsType, t.Name()))
+
+	b := bytes.NewBufferString(``)
+	WritePuppetType(c, t, b)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, b.Bytes(), 0644)
+	}
+	if err != nil {
+		panic(px.Error(px.UnableToWriteFile, issue.H{`path`: path, `detail`: err.Error()}))
+	}
+	return path
+}
+
+type typeWriter struct {
+	// fc is used when writing types. Its typeSet property ensures that references to members of the
+	// TypeSet that is written are relative to that TypeSet and its exactFloats property ensures that
+	// Float parameters and values read back as the same values.
+	fc px.FormatContext
+}
+
+func newTypeWriter(typeSet string) *typeWriter {
+	if typeSet == `` {
+		return &typeWriter{fc: px.ExactFloats}
+	}
+	return &typeWriter{fc: px.ExactFloats.WithProperties(map[string]string{`typeSet`: typeSet})}
+}
+
+// writeInitHash writes the init hash of an Object type or a TypeSet. The name is omitted since it is
+// given by the type assignment, and so is a name authority that is the default runtime authority.
+func (tw *typeWriter) writeInitHash(b *utils.Indenter, ih px.OrderedMap) {
+	es := make([]px.MapEntry, 0, ih.Len())
+	ih.EachPair(func(k, v px.Value) {
+		switch k.String() {
+		case `name`:
+			return
+		case `name_authority`:
+			if v.String() == string(px.RuntimeNameAuthority) {
+				return
+			}
+		}
+		es = append(es, types.WrapHashEntry(k, v))
+	})
+	tw.writeEntries(b, es, func(k px.Value, v px.Value) {
+		if k.String() == `types` {
+			tw.writeMembers(b.Indent(), v.(px.OrderedMap))
+		} else {
+			tw.writeValue(b.Indent(), v)
+		}
+	})
+}
+
+// writeMembers writes the types of a TypeSet. Object types are written as init hashes and aliases as the
+// type that they resolve to.
+func (tw *typeWriter) writeMembers(b *utils.Indenter, members px.OrderedMap) {
+	tw.writeEntries(b, entries(members), func(k px.Value, v px.Value) {
+		switch t := v.(type) {
+		case px.ObjectType:
+			tw.writeInitHash(b.Indent(), t.(px.PuppetObject).InitHash())
+		case *types.TypeAliasType:
+			tw.writeType(b.Indent(), t.ResolvedType())
+		default:
+			tw.writeType(b.Indent(), t.(px.Type))
+		}
+	})
+}
+
+// writeEntries writes a hash with one entry per line. The given function writes the values.
+func (tw *typeWriter) writeEntries(b *utils.Indenter, es []px.MapEntry, valueWriter func(k, v px.Value)) {
+	if len(es) == 0 {
+		b.Append(`{}`)
+		return
+	}
+	b.Append(`{`)
+	ib := b.Indent()
+	for i, e := range es {
+		if i > 0 {
+			b.Append(`,`)
+		}
+		ib.NewLine()
+		tw.writeKey(ib, e.Key())
+		b.Append(` => `)
+		valueWriter(e.Key(), e.Value())
+	}
+	b.NewLine()
+	b.Append(`}`)
+}
+
+var bareWord = regexp.MustCompile(`\A[a-z_][a-z0-9_]*\z|\A(?:::)?[A-Z][\w]*(?:::[A-Z][\w]*)*\z`)
+
+func (tw *typeWriter) writeKey(b *utils.Indenter, k px.Value) {
+	if s, ok := k.(px.StringValue); ok {
+		switch ks := s.String(); ks {
+		case `true`, `false`, `undef`, `default`:
+		default:
+			if bareWord.MatchString(ks) {
+				b.Append(ks)
+				return
+			}
+		}
+	}
+	tw.writeValue(b, k)
+}
+
+func (tw *typeWriter) writeValue(b *utils.Indenter, v px.Value) {
+	switch v := v.(type) {
+	case *types.UriValue:
+		// A UriValue is also a px.Type
+		utils.PuppetQuote(b, v.String())
+	case px.Type:
+		tw.writeType(b, v)
+	case px.StringValue:
+		utils.PuppetQuote(b, v.String())
+	case px.Boolean, px.Integer, px.Float, *types.UndefValue, *types.DefaultValue:
+		v.ToString(b, tw.fc, nil)
+	case px.OrderedMap:
+		tw.writeEntries(b, entries(v), func(_, ev px.Value) { tw.writeValue(b.Indent(), ev) })
+	case px.List:
+		b.Append(`[`)
+		v.EachWithIndex(func(e px.Value, i int) {
+			if i > 0 {
+				b.Append(`, `)
+			}
+			tw.writeValue(b, e)
+		})
+		b.Append(`]`)
+	default:
+		// Values such as SemVer and URI are written as strings that can be converted to such values
+		utils.PuppetQuote(b, v.String())
+	}
+}
+
+func (tw *typeWriter) writeType(b *utils.Indenter, t px.Type) {
+	t.ToString(b, tw.fc, nil)
+}
+
+func entries(h px.OrderedMap) []px.MapEntry {
+	es := make([]px.MapEntry, 0, h.Len())
+	h.EachPair(func(k, v px.Value) { es = append(es, types.WrapHashEntry(k, v)) })
+	return es
+}
@@ -0,0 +1,149 @@
+package loader_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/pcore/loader"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/stretchr/testify/require"
+)
+
+const shopTypeSet = `TypeSet[{
+  pcore_uri => 'http://puppet.com/2016.1/pcore',
+  pcore_version => '1.0.0',
+  name => 'My::Shop',
+  version => '1.2.0',
+  types => {
+    Sku => Pattern[/\A[A-Z]{3}-\d+\z/],
+    Item => {
+      annotations => {
+        TagsAnnotation => {
+          tags => {
+            description => 'An item that can be ordered'
+          }
+        }
+      },
+      attributes => {
+        sku => Sku,
+        price => { type => Float[0.0], value => 0.0 },
+        tags => { type => Array[String], value => ['new', 'sale'] }
+      }
+    },
+    Book => {
+      parent => Item,
+      attributes => {
+        title => String,
+        related => Optional[Array[Variant[Book, Sku]]]
+      },
+      constants => {
+        category => 'book'
+      }
+    }
+  }
+}]`
+
+const expectedShop = `type My::Shop = TypeSet[{
+  pcore_uri => 'http://puppet.com/2016.1/pcore',
+  pcore_version => '1.0.0',
+  version => '1.2.0',
+  types => {
+    Sku => Pattern[/\A[A-Z]{3}-\d+\z/],
+    Item => {
+      annotations => {
+        TagsAnnotation => {
+          tags => {
+            description => 'An item that can be ordered'
+          }
+        }
+      },
+      attributes => {
+        sku => Sku,
+        price => {
+          type => Float[0.0],
+          value => 0.0
+        },
+        tags => {
+          type => Array[String],
+          value => ['new', 'sale']
+        }
+      }
+    },
+    Book => {
+      parent => Item,
+      attributes => {
+        title => String,
+        related => Optional[Array[Variant[Book, Sku]]]
+      },
+      constants => {
+        category => 'book'
+      }
+    }
+  }
+}]
+`
+
+func TestWritePuppetType_typeSet(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(shopTypeSet).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		b := bytes.NewBufferString(``)
+		loader.WritePuppetType(c, ts, b)
+		require.Equal(t, expectedShop, b.String())
+	})
+}
+
+func TestWritePuppetTypeFile_roundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pcore-types`)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(shopTypeSet).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		person := c.ParseType(`Object[{name => 'My::Person', attributes => {name => String, age => Integer[0]}}]`).(px.ResolvableType).Resolve(c)
+		size := types.Parse(`type My::Size = Variant[Integer[0, 10], Enum[small, large]]`).(px.ResolvableType).Resolve(c)
+		ratio := c.ParseType(`Object[{name => 'My::Ratio', attributes => {
+			value => {type => Optional[Float[0.1, 1.123456789012345]], value => 0.30000000000000004},
+			scale => {type => Float, value => 1e-20}}}]`).(px.ResolvableType).Resolve(c)
+
+		require.Equal(t, filepath.Join(dir, `types`, `my`, `shop.pp`), loader.WritePuppetTypeFile(c, dir, ts))
+		require.Equal(t, filepath.Join(dir, `types`, `my`, `person.pp`), loader.WritePuppetTypeFile(c, dir, person))
+		require.Equal(t, filepath.Join(dir, `types`, `my`, `size.pp`), loader.WritePuppetTypeFile(c, dir, size))
+		require.Equal(t, filepath.Join(dir, `types`, `my`, `ratio.pp`), loader.WritePuppetTypeFile(c, dir, ratio))
+
+		c.DoWithLoader(px.NewFileBasedLoader(c.Loader(), dir, ``, px.PuppetDataTypePath), func() {
+			lt, ok := px.Load(c, px.NewTypedName(px.NsType, `My::Shop`))
+			require.True(t, ok)
+			lts := lt.(px.TypeSet)
+			require.True(t, ts.Equals(lts, nil))
+			ts.Types().EachPair(func(k, v px.Value) {
+				lv, ok := lts.Types().Get(k)
+				require.True(t, ok)
+				require.True(t, v.Equals(lv, nil), `type %s differs`, k)
+			})
+
+			lp, ok := px.Load(c, px.NewTypedName(px.NsType, `My::Person`))
+			require.True(t, ok)
+			require.True(t, person.Equals(lp, nil))
+
+			ls, ok := px.Load(c, px.NewTypedName(px.NsType, `My::Size`))
+			require.True(t, ok)
+			require.True(t, size.Equals(ls, nil))
+
+			lr, ok := px.Load(c, px.NewTypedName(px.NsType, `My::Ratio`))
+			require.True(t, ok)
+			require.True(t, ratio.Equals(lr, nil))
+			a, _ := lr.(px.ObjectType).Member(`value`)
+			require.Equal(t, 0.30000000000000004, a.(px.Attribute).Value().(px.Float).Float())
+		})
+
+		rp, err := ioutil.ReadFile(filepath.Join(dir, `types`, `my`, `ratio.pp`))
+		require.NoError(t, err)
+		require.Contains(t, string(rp), `type => Optional[Float[0.1, 1.123456789012345]]`)
+		require.Contains(t, string(rp), `value => 1e-20`)
+	})
+}
@@ -0,0 +1,325 @@
+// Package merge implements the Hiera merge strategies for values that are found in several sources.
+//
+// The values that are given to a Strategy are in priority order, i.e. the first value has the highest priority.
+// Hash keys and array elements appear in the order they are first found when the values are traversed in that
+// order. The strategies are:
+//
+//	first  - the value with the highest priority
+//	unique - the union of all values where arrays are flattened and other values are treated as one element arrays
+//	hash   - a shallow merge of hashes where the value of a key is taken from the hash with the highest priority
+//	deep   - a recursive merge of hashes and arrays
+//
+// The deep strategy accepts the following options:
+//
+//	knockout_prefix    - a hash key or an array element that is a string with this prefix removes the key or element
+//	                     without the prefix from the values of lower priority. The knockouts themselves are removed
+//	                     from the result
+//	merge_hash_arrays  - when true, arrays that contain only hashes are merged element by element
+//	sort_merged_arrays - when true, merged arrays are sorted
+package merge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// A Strategy merges values
+type Strategy interface {
+	// Name returns the name of the strategy
+	Name() string
+
+	// Merge returns the result of merging the given values, highest priority first. The result is undef when
+	// no values are given.
+	Merge(values ...px.Value) px.Value
+}
+
+var (
+	noOptionsType   = types.NewStructType([]*types.StructElement{})
+	deepOptionsType = types.NewStructType([]*types.StructElement{
+		types.NewStructElement(types.WrapString(`knockout_prefix`), types.NewOptionalType(types.DefaultStringType())),
+		types.NewStructElement(types.WrapString(`merge_hash_arrays`), types.NewOptionalType(types.DefaultBooleanType())),
+		types.NewStructElement(types.WrapString(`sort_merged_arrays`), types.NewOptionalType(types.DefaultBooleanType())),
+	})
+)
+
+// NewStrategy returns the strategy with the given name, configured with the given options. An UnknownMergeStrategy
+// error is raised for an unknown name and a TypeMismatch error for options that the strategy doesn't accept.
+func NewStrategy(name string, options px.OrderedMap) Strategy {
+	if options == nil {
+		options = px.EmptyMap
+	}
+	var s Strategy
+	switch name {
+	case `first`:
+		s = first{}
+	case `unique`:
+		s = unique{}
+	case `hash`:
+		s = hash{}
+	case `deep`:
+		assertOptions(name, deepOptionsType, options)
+		return &deep{
+			knockoutPrefix:   options.Get5(`knockout_prefix`, px.EmptyString).String(),
+			mergeHashArrays:  options.Get5(`merge_hash_arrays`, types.BooleanFalse).(px.Boolean).Bool(),
+			sortMergedArrays: options.Get5(`sort_merged_arrays`, types.BooleanFalse).(px.Boolean).Bool()}
+	default:
+		panic(px.Error(px.UnknownMergeStrategy, issue.H{`name`: name}))
+	}
+	assertOptions(name, noOptionsType, options)
+	return s
+}
+
+func assertOptions(name string, optionsType px.Type, options px.OrderedMap) {
+	px.AssertInstance(func() string { return fmt.Sprintf(`options for merge strategy '%s'`, name) }, optionsType, options)
+}
+
+// StrategyFromValue returns the strategy for the given value, which is either the name of a strategy or a hash with
+// the name in the key 'strategy' and the options in the other keys, as in Hiera lookup_options
+func StrategyFromValue(v px.Value) Strategy {
+	if h, ok := v.(px.OrderedMap); ok {
+		name := h.Get5(`strategy`, px.EmptyString).String()
+		return NewStrategy(name, h.RejectPairs(func(k, _ px.Value) bool { return k.String() == `strategy` }))
+	}
+	return NewStrategy(v.String(), nil)
+}
+
+type first struct{}
+
+func (first) Name() string {
+	return `first`
+}
+
+func (first) Merge(values ...px.Value) px.Value {
+	if len(values) == 0 {
+		return px.Undef
+	}
+	return values[0]
+}
+
+type unique struct{}
+
+func (unique) Name() string {
+	return `unique`
+}
+
+func (unique) Merge(values ...px.Value) px.Value {
+	if len(values) == 0 {
+		return px.Undef
+	}
+	es := make([]px.Value, 0)
+	seen := make(map[px.HashKey]bool)
+	for _, v := range values {
+		var vs []px.Value
+		if a, ok := v.(*types.Array); ok {
+			vs = a.Flatten().AppendTo(vs)
+		} else {
+			vs = []px.Value{v}
+		}
+		for _, e := range vs {
+			if k := px.ToKey(e); !seen[k] {
+				seen[k] = true
+				es = append(es, e)
+			}
+		}
+	}
+	return types.WrapValues(es)
+}
+
+type hash struct{}
+
+func (hash) Name() string {
+	return `hash`
+}
+
+func (hash) Merge(values ...px.Value) px.Value {
+	if len(values) == 0 {
+		return px.Undef
+	}
+	es := make([]*types.HashEntry, 0)
+	seen := make(map[px.HashKey]bool)
+	for _, v := range values {
+		px.AssertInstance(`hash merge`, types.DefaultHashType(), v).(px.OrderedMap).EachPair(func(k, ev px.Value) {
+			if hk := px.ToKey(k); !seen[hk] {
+				seen[hk] = true
+				es = append(es, types.WrapHashEntry(k, ev))
+			}
+		})
+	}
+	return types.WrapHash(es)
+}
+
+type deep struct {
+	knockoutPrefix   string
+	mergeHashArrays  bool
+	sortMergedArrays bool
+}
+
+func (d *deep) Name() string {
+	return `deep`
+}
+
+// Merge merges the values starting with the one with the lowest priority so that a knockout removes keys and
+// elements from all values of lower priority. Knockouts that remain once all values are merged are removed from
+// the result.
+func (d *deep) Merge(values ...px.Value) px.Value {
+	if len(values) == 0 {
+		return px.Undef
+	}
+	result := values[len(values)-1]
+	for i := len(values) - 2; i >= 0; i-- {
+		result = d.merge(values[i], result)
+	}
+	return d.strip(result)
+}
+
+func (d *deep) merge(high, low px.Value) px.Value {
+	switch h := high.(type) {
+	case *types.Hash:
+		if l, ok := low.(*types.Hash); ok {
+			return d.mergeHashes(h, l)
+		}
+	case *types.Array:
+		if l, ok := low.(*types.Array); ok {
+			return d.mergeArrays(h, l)
+		}
+	}
+	return high
+}
+
+func (d *deep) mergeHashes(high, low *types.Hash) px.Value {
+	es := make([]*types.HashEntry, 0, high.Len()+low.Len())
+	knockouts := make(map[px.HashKey]bool)
+	high.EachPair(func(k, hv px.Value) {
+		if ko, ok := d.knockout(k); ok {
+			knockouts[px.ToKey(ko)] = true
+			return
+		}
+		if lv, ok := low.Get(k); ok {
+			hv = d.merge(hv, lv)
+		}
+		es = append(es, types.WrapHashEntry(k, hv))
+	})
+	low.EachPair(func(k, lv px.Value) {
+		if !(high.IncludesKey(k) || knockouts[px.ToKey(k)]) {
+			es = append(es, types.WrapHashEntry(k, lv))
+		}
+	})
+	return types.WrapHash(es)
+}
+
+func (d *deep) mergeArrays(high, low *types.Array) px.Value {
+	if d.mergeHashArrays && onlyHashes(high) && onlyHashes(low) {
+		n := high.Len()
+		if low.Len() > n {
+			n = low.Len()
+		}
+		es := make([]px.Value, n)
+		for i := range es {
+			switch {
+			case i >= high.Len():
+				es[i] = low.At(i)
+			case i >= low.Len():
+				es[i] = high.At(i)
+			default:
+				es[i] = d.merge(high.At(i), low.At(i))
+			}
+		}
+		return types.WrapValues(es)
+	}
+
+	es := make([]px.Value, 0, high.Len()+low.Len())
+	seen := make(map[px.HashKey]bool)
+	high.Each(func(e px.Value) {
+		if ko, ok := d.knockout(e); ok {
+			seen[px.ToKey(ko)] = true
+		} else if k := px.ToKey(e); !seen[k] {
+			seen[k] = true
+			es = append(es, e)
+		}
+	})
+	low.Each(func(e px.Value) {
+		if k := px.ToKey(e); !seen[k] {
+			seen[k] = true
+			es = append(es, e)
+		}
+	})
+	a := types.WrapValues(es)
+	if d.sortMergedArrays {
+		return a.Sort(less)
+	}
+	return a
+}
+
+// strip returns the given value with all hash keys and array elements that are knockouts removed, including
+// those of nested hashes and arrays
+func (d *deep) strip(v px.Value) px.Value {
+	if d.knockoutPrefix == `` {
+		return v
+	}
+	switch v := v.(type) {
+	case *types.Hash:
+		es := make([]*types.HashEntry, 0, v.Len())
+		v.EachPair(func(k, ev px.Value) {
+			if _, ok := d.knockout(k); !ok {
+				es = append(es, types.WrapHashEntry(k, d.strip(ev)))
+			}
+		})
+		return types.WrapHash(es)
+	case *types.Array:
+		es := make([]px.Value, 0, v.Len())
+		v.Each(func(e px.Value) {
+			if _, ok := d.knockout(e); !ok {
+				es = append(es, d.strip(e))
+			}
+		})
+		return types.WrapValues(es)
+	}
+	return v
+}
+
+// knockout returns the given value without the knockout prefix and true if the value is a string with that prefix
+func (d *deep) knockout(v px.Value) (px.Value, bool) {
+	if d.knockoutPrefix != `` {
+		if s, ok := v.(px.StringValue); ok && strings.HasPrefix(s.String(), d.knockoutPrefix) {
+			return types.WrapString(s.String()[len(d.knockoutPrefix):]), true
+		}
+	}
+	return nil, false
+}
+
+func onlyHashes(a *types.Array) bool {
+	return a.All(func(e px.Value) bool {
+		_, ok := e.(*types.Hash)
+		return ok
+	})
+}
+
+// less orders numbers by value and strings lexically. Numbers come before strings and other values are ordered
+// by their string form after the strings.
+func less(a, b px.Value) bool {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra < rb
+	}
+	switch ra {
+	case 0:
+		return a.(px.Number).Float() < b.(px.Number).Float()
+	case 1:
+		return a.String() < b.String()
+	}
+	return px.ToString(a) < px.ToString(b)
+}
+
+func rank(v px.Value) int {
+	switch v.(type) {
+	case px.Number:
+		return 0
+	case px.StringValue:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,62 @@
+package merge_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/merge"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func ExampleNewStrategy() {
+	high := types.Parse(`{users => [alice, bob], settings => {color => blue, size => {w => 10}}}`)
+	low := types.Parse(`{users => [carol, alice], settings => {size => {h => 20}, font => mono}, extra => true}`)
+	for _, name := range []string{`first`, `unique`, `hash`, `deep`} {
+		s := merge.NewStrategy(name, nil)
+		if name == `unique` {
+			fmt.Println(s.Name(), s.Merge(types.Parse(`[a, [b, c]]`), types.Parse(`d`), types.Parse(`[c, a, e]`)))
+		} else {
+			fmt.Println(s.Name(), s.Merge(high, low))
+		}
+	}
+	// Output:
+	// first {'users' => ['alice', 'bob'], 'settings' => {'color' => 'blue', 'size' => {'w' => 10}}}
+	// unique ['a', 'b', 'c', 'd', 'e']
+	// hash {'users' => ['alice', 'bob'], 'settings' => {'color' => 'blue', 'size' => {'w' => 10}}, 'extra' => true}
+	// deep {'users' => ['alice', 'bob', 'carol'], 'settings' => {'color' => 'blue', 'size' => {'w' => 10, 'h' => 20}, 'font' => 'mono'}, 'extra' => true}
+}
+
+func ExampleStrategyFromValue() {
+	s := merge.StrategyFromValue(types.Parse(`{strategy => deep, knockout_prefix => '--', sort_merged_arrays => true}`))
+	fmt.Println(s.Merge(
+		types.Parse(`{packages => ['--vim', zsh], '--motd' => undef, ntp => {servers => ['--a.pool']}}`),
+		types.Parse(`{packages => [vim, emacs], motd => hello, ntp => {servers => ['b.pool', 'a.pool']}}`),
+		types.Parse(`{packages => [git], ntp => {servers => ['c.pool']}}`)))
+	// Output: {'packages' => ['emacs', 'git', 'zsh'], 'ntp' => {'servers' => ['b.pool', 'c.pool']}}
+}
+
+func ExampleNewStrategy_knockout() {
+	s := merge.NewStrategy(`deep`, types.Parse(`{knockout_prefix => '--'}`).(px.OrderedMap))
+	fmt.Println(s.Merge(
+		types.Parse(`{users => ['--bob'], groups => {admins => ['--carol', alice]}}`),
+		types.Parse(`{users => [alice, bob], '--motd' => hi, limits => {'--cpu' => 2, mem => ['--1G', '2G']}}`)))
+	fmt.Println(s.Merge(types.Parse(`['--a', b, {'--c' => 1, d => 2}]`)))
+	// Output:
+	// {'users' => ['alice'], 'groups' => {'admins' => ['alice']}, 'limits' => {'mem' => ['2G']}}
+	// ['b', {'d' => 2}]
+}
+
+func ExampleNewStrategy_mergeHashArrays() {
+	s := merge.NewStrategy(`deep`, types.Parse(`{merge_hash_arrays => true}`).(px.OrderedMap))
+	fmt.Println(s.Merge(
+		types.Parse(`[{name => a, port => 80}, {name => b}]`),
+		types.Parse(`[{name => x, tls => true}, {name => y, port => 443}, {name => z}]`)))
+	// Output: [{'name' => 'a', 'port' => 80, 'tls' => true}, {'name' => 'b', 'port' => 443}, {'name' => 'z'}]
+}
+
+func ExampleNewStrategy_error() {
+	r := testutil.Reported(func() { merge.NewStrategy(`reverse_deep`, nil) })
+	fmt.Println(r.Code(), r.Argument(`name`))
+	// Output: PCORE_UNKNOWN_MERGE_STRATEGY reverse_deep
+}
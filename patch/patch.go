@@ -0,0 +1,434 @@
+// Package patch computes structural differences between values and applies them.
+//
+// Diff compares two values and returns a Patch, i.e. a list of operations that each add, remove, or replace a
+// value at a path. The elements of a path are hash keys, array indexes, and Object attribute names. Objects are
+// compared and patched using their InitHash, so an attribute that is removed by a patch gets its default
+// value.
+//
+// A Patch can be converted to and from an RFC 6902 JSON Patch. The JSON Patch is a px.List of hashes, so it
+// can be passed through the serializer with any rich values that the operations contain. A Patch may also
+// contain the test, move, and copy operations of RFC 6902 although Diff never produces them.
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// Op is the name of a patch operation
+type Op string
+
+const (
+	Add     = Op(`add`)
+	Copy    = Op(`copy`)
+	Move    = Op(`move`)
+	Remove  = Op(`remove`)
+	Replace = Op(`replace`)
+	Test    = Op(`test`)
+)
+
+// An Operation adds, removes, or replaces the value at a path, tests that it is equal to a given value, or
+// moves or copies the value at another path to it
+type Operation struct {
+	Op Op
+
+	// Path contains the keys, indexes, and attribute names that lead to the value. An empty path denotes
+	// the value itself.
+	Path []px.Value
+
+	// From is the path of the value to move or copy. It is nil for other operations.
+	From []px.Value
+
+	// Value is the value to add, replace with, or test against. It is nil for other operations.
+	Value px.Value
+
+	// of is the Move or Copy operation that this Add or Remove operation is a part of
+	of *Operation
+}
+
+func (o *Operation) String() string {
+	switch o.Op {
+	case Remove:
+		return fmt.Sprintf(`%s %s`, o.Op, Pointer(o.Path))
+	case Move, Copy:
+		return fmt.Sprintf(`%s %s from %s`, o.Op, Pointer(o.Path), Pointer(o.From))
+	}
+	return fmt.Sprintf(`%s %s %s`, o.Op, Pointer(o.Path), o.Value)
+}
+
+// A Patch is a list of operations that are applied in order
+type Patch []*Operation
+
+// Diff returns the Patch that transforms a into b. Hashes are compared key by key and Objects of the same
+// type attribute by attribute. Arrays are compared index by index, so elements beyond the length of the
+// shorter array are added or removed, starting with the last one. All other values that are not equal are
+// replaced.
+func Diff(a, b px.Value) Patch {
+	d := &differ{}
+	d.diff(nil, a, b)
+	return d.patch
+}
+
+type differ struct {
+	patch Patch
+}
+
+func (d *differ) add(op Op, path []px.Value, v px.Value) {
+	d.patch = append(d.patch, &Operation{Op: op, Path: append(make([]px.Value, 0, len(path)), path...), Value: v})
+}
+
+func (d *differ) diff(path []px.Value, a, b px.Value) {
+	if a.Equals(b, nil) {
+		return
+	}
+	switch av := a.(type) {
+	case *types.Array:
+		if bv, ok := b.(*types.Array); ok {
+			d.diffArrays(path, av, bv)
+			return
+		}
+	case *types.Hash:
+		if bv, ok := b.(*types.Hash); ok {
+			d.diffHashes(path, av, bv)
+			return
+		}
+	case px.Type:
+	case px.PuppetObject:
+		if bv, ok := b.(px.PuppetObject); ok && av.PType().Equals(bv.PType(), nil) {
+			d.diffHashes(path, av.InitHash(), bv.InitHash())
+			return
+		}
+	}
+	d.add(Replace, path, b)
+}
+
+func (d *differ) diffArrays(path []px.Value, a, b *types.Array) {
+	an := a.Len()
+	bn := b.Len()
+	for i := 0; i < an && i < bn; i++ {
+		d.diff(append(path, types.WrapInteger(int64(i))), a.At(i), b.At(i))
+	}
+	for i := an; i < bn; i++ {
+		d.add(Add, append(path, types.WrapInteger(int64(i))), b.At(i))
+	}
+	for i := an - 1; i >= bn; i-- {
+		d.add(Remove, append(path, types.WrapInteger(int64(i))), nil)
+	}
+}
+
+func (d *differ) diffHashes(path []px.Value, a, b px.OrderedMap) {
+	a.EachPair(func(k, av px.Value) {
+		if bv, ok := b.Get(k); ok {
+			d.diff(append(path, k), av, bv)
+		} else {
+			d.add(Remove, append(path, k), nil)
+		}
+	})
+	b.EachPair(func(k, bv px.Value) {
+		if !a.IncludesKey(k) {
+			d.add(Add, append(path, k), bv)
+		}
+	})
+}
+
+// Apply returns the result of applying the given patch to the given value. The value itself is not modified.
+// Objects that are changed by the patch are recreated from their patched InitHash using px.New.
+//
+// A path element that is a string is accepted as an array index when it is an integer, and as a hash key
+// that is an integer when the hash has no such string key. This makes it possible to apply a patch that was
+// created from a JSON Patch.
+//
+// A Test operation fails unless the value at its path is equal to its value. A Move operation removes the
+// value at its from path and adds it at its path, and a Copy operation only adds it.
+func Apply(c px.Context, v px.Value, p Patch) px.Value {
+	for _, o := range p {
+		switch o.Op {
+		case Test:
+			tv, ok := get(v, o.Path)
+			if !ok {
+				panic(o.error(`no such value`))
+			}
+			if !tv.Equals(o.Value, nil) {
+				panic(o.error(fmt.Sprintf(`the value %s is not equal to %s`, tv, o.Value)))
+			}
+		case Move, Copy:
+			fv, ok := get(v, o.From)
+			if !ok {
+				panic(o.error(fmt.Sprintf(`no value at '%s'`, Pointer(o.From))))
+			}
+			if o.Op == Move {
+				if len(o.From) == len(o.Path) && isPrefix(o.From, o.Path) {
+					// Moving a value to where it is leaves it unchanged
+					continue
+				}
+				if isPrefix(o.From, o.Path) {
+					panic(o.error(`a value cannot be moved into itself`))
+				}
+				v = apply(c, v, &Operation{Op: Remove, Path: o.From, of: o}, 0)
+			}
+			v = apply(c, v, &Operation{Op: Add, Path: o.Path, Value: fv, of: o}, 0)
+		default:
+			v = apply(c, v, o, 0)
+		}
+	}
+	return v
+}
+
+// get returns the value at the given path of v and true, or nil and false when there is no such value
+func get(v px.Value, path []px.Value) (px.Value, bool) {
+	for _, k := range path {
+		var ok bool
+		switch cv := v.(type) {
+		case *types.Array:
+			idx, isIdx := arrayIndex(k)
+			if !isIdx || idx < 0 || idx >= int64(cv.Len()) {
+				return nil, false
+			}
+			v = cv.At(int(idx))
+			continue
+		case *types.Hash:
+			v, ok = cv.Get(hashKey(cv, k))
+		case px.Type:
+		case px.PuppetObject:
+			ih := cv.InitHash()
+			v, ok = ih.Get(hashKey(ih, k))
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// isPrefix answers whether the path a is a prefix of, or equal to, the path b
+func isPrefix(a, b []px.Value) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for i, k := range a {
+		if k.String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+func apply(c px.Context, v px.Value, o *Operation, i int) px.Value {
+	if i == len(o.Path) {
+		if o.Op == Remove {
+			panic(o.error(`the root value cannot be removed`))
+		}
+		return o.Value
+	}
+	switch cv := v.(type) {
+	case *types.Array:
+		return applyToArray(c, cv, o, i)
+	case *types.Hash:
+		return applyToHash(c, cv, o, i)
+	case px.Type:
+	case px.PuppetObject:
+		return px.New(c, cv.PType(), applyToHash(c, cv.InitHash(), o, i))
+	}
+	panic(o.error(fmt.Sprintf(`%s value has no elements or attributes`, issue.AnOrA(v.PType().Name()))))
+}
+
+func applyToArray(c px.Context, a *types.Array, o *Operation, i int) px.Value {
+	es := a.AppendTo(make([]px.Value, 0, a.Len()+1))
+	last := i == len(o.Path)-1
+	idx := o.index(o.Path[i], len(es), last && o.Op == Add)
+	switch {
+	case !last:
+		es[idx] = apply(c, es[idx], o, i+1)
+	case o.Op == Add:
+		es = append(es, nil)
+		copy(es[idx+1:], es[idx:])
+		es[idx] = o.Value
+	case o.Op == Remove:
+		es = append(es[:idx], es[idx+1:]...)
+	default:
+		es[idx] = o.Value
+	}
+	return types.WrapValues(es)
+}
+
+func applyToHash(c px.Context, h px.OrderedMap, o *Operation, i int) px.Value {
+	es := make([]*types.HashEntry, 0, h.Len()+1)
+	h.EachPair(func(k, v px.Value) { es = append(es, types.WrapHashEntry(k, v)) })
+	key := hashKey(h, o.Path[i])
+	idx := -1
+	for n, e := range es {
+		if e.Key().Equals(key, nil) {
+			idx = n
+			break
+		}
+	}
+	last := i == len(o.Path)-1
+	if idx < 0 && !(last && o.Op == Add) {
+		panic(o.error(fmt.Sprintf(`no such key %s`, key)))
+	}
+	switch {
+	case !last:
+		es[idx] = types.WrapHashEntry(key, apply(c, es[idx].Value(), o, i+1))
+	case o.Op == Remove:
+		es = append(es[:idx], es[idx+1:]...)
+	case idx < 0:
+		es = append(es, types.WrapHashEntry(key, o.Value))
+	default:
+		es[idx] = types.WrapHashEntry(key, o.Value)
+	}
+	return types.WrapHash(es)
+}
+
+// hashKey returns the key of the given hash that the given path element denotes
+func hashKey(h px.OrderedMap, k px.Value) px.Value {
+	if s, ok := k.(px.StringValue); ok && !h.IncludesKey(s) {
+		if n, err := strconv.ParseInt(s.String(), 10, 64); err == nil {
+			if ik := types.WrapInteger(n); h.IncludesKey(ik) {
+				return ik
+			}
+		}
+	}
+	return k
+}
+
+// index returns the array index that the given path element denotes. The index may be equal to the length
+// of the array when adding, and the element '-' then denotes that length.
+func (o *Operation) index(k px.Value, n int, adding bool) int {
+	if adding && k.String() == `-` {
+		return n
+	}
+	idx, ok := arrayIndex(k)
+	if !ok {
+		idx = -1
+	}
+	max := int64(n)
+	if adding {
+		max++
+	}
+	if idx < 0 || idx >= max {
+		panic(o.error(fmt.Sprintf(`index %s is out of bounds`, k)))
+	}
+	return int(idx)
+}
+
+// arrayIndex returns the array index that the given path element denotes and true, or 0 and false when the
+// element is neither an Integer nor a string that is an integer
+func arrayIndex(k px.Value) (int64, bool) {
+	switch k := k.(type) {
+	case px.Integer:
+		return k.Int(), true
+	case px.StringValue:
+		if i, err := strconv.ParseInt(k.String(), 10, 64); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (o *Operation) error(detail string) issue.Reported {
+	if o.of != nil {
+		return o.of.error(detail)
+	}
+	return px.Error(px.UnableToApplyPatch, issue.H{`op`: o.Op, `path`: Pointer(o.Path), `detail`: detail})
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer(`~`, `~0`, `/`, `~1`)
+	pointerUnescaper = strings.NewReplacer(`~1`, `/`, `~0`, `~`)
+)
+
+// Pointer returns the RFC 6901 JSON Pointer for the given path. Path elements that are not strings are
+// represented by their string form.
+func Pointer(path []px.Value) string {
+	b := bytes.NewBufferString(``)
+	for _, k := range path {
+		b.WriteByte('/')
+		b.WriteString(pointerEscaper.Replace(k.String()))
+	}
+	return b.String()
+}
+
+// ParsePointer returns the path that the given RFC 6901 JSON Pointer denotes. All elements of the path are
+// strings. The second return value is false if the pointer is neither empty nor starts with a slash.
+func ParsePointer(pointer string) ([]px.Value, bool) {
+	if pointer == `` {
+		return []px.Value{}, true
+	}
+	if pointer[0] != '/' {
+		return nil, false
+	}
+	ss := strings.Split(pointer[1:], `/`)
+	path := make([]px.Value, len(ss))
+	for i, s := range ss {
+		path[i] = types.WrapString(pointerUnescaper.Replace(s))
+	}
+	return path, true
+}
+
+// ToJSONPatch returns the RFC 6902 JSON Patch for the given patch
+func ToJSONPatch(p Patch) px.List {
+	ops := make([]px.Value, len(p))
+	for i, o := range p {
+		es := []*types.HashEntry{
+			types.WrapHashEntry2(`op`, types.WrapString(string(o.Op))),
+			types.WrapHashEntry2(`path`, types.WrapString(Pointer(o.Path)))}
+		switch o.Op {
+		case Remove:
+		case Move, Copy:
+			es = append(es, types.WrapHashEntry2(`from`, types.WrapString(Pointer(o.From))))
+		default:
+			es = append(es, types.WrapHashEntry2(`value`, o.Value))
+		}
+		ops[i] = types.WrapHash(es)
+	}
+	return types.WrapValues(ops)
+}
+
+// FromJSONPatch returns the patch for the given RFC 6902 JSON Patch
+func FromJSONPatch(jp px.List) Patch {
+	p := make(Patch, 0, jp.Len())
+	jp.EachWithIndex(func(v px.Value, i int) {
+		fail := func(detail string) {
+			panic(px.Error(px.InvalidJsonPatch, issue.H{`index`: i, `detail`: detail}))
+		}
+		h, ok := v.(px.OrderedMap)
+		if !ok {
+			fail(`the operation is not an object`)
+		}
+		o := &Operation{Op: Op(h.Get5(`op`, px.EmptyString).String())}
+		switch o.Op {
+		case Add, Replace, Test:
+			if o.Value, ok = h.Get4(`value`); !ok {
+				fail(`the operation has no value`)
+			}
+		case Move, Copy:
+			if o.From, ok = pointer(h, `from`); !ok {
+				fail(`the operation has no valid from`)
+			}
+		case Remove:
+		default:
+			fail(fmt.Sprintf(`unsupported op '%s'`, o.Op))
+		}
+		if o.Path, ok = pointer(h, `path`); !ok {
+			fail(`the operation has no valid path`)
+		}
+		p = append(p, o)
+	})
+	return p
+}
+
+// pointer returns the path that the JSON Pointer under the given key of the given hash denotes
+func pointer(h px.OrderedMap, key string) ([]px.Value, bool) {
+	if pv, ok := h.Get4(key); ok {
+		if _, ok = pv.(px.StringValue); ok {
+			return ParsePointer(pv.String())
+		}
+	}
+	return nil, false
+}
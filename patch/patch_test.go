@@ -0,0 +1,113 @@
+package patch_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/patch"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+)
+
+func ExampleDiff() {
+	pcore.Do(func(c px.Context) {
+		old := types.Parse(`{name => web, servers => [a, b, c], env => {debug => true, level => 3}}`)
+		new := types.Parse(`{name => web, servers => [a, x], env => {level => 4, region => eu}, owner => ops}`)
+		p := patch.Diff(old, new)
+		for _, o := range p {
+			fmt.Println(o)
+		}
+		fmt.Println(patch.Apply(c, old, p).Equals(new, nil))
+	})
+	// Output:
+	// replace /servers/1 x
+	// remove /servers/2
+	// remove /env/debug
+	// replace /env/level 4
+	// add /env/region eu
+	// add /owner ops
+	// true
+}
+
+func ExampleDiff_object() {
+	pcore.Do(func(c px.Context) {
+		st := px.NewObjectType(`My::Server`, `{
+      attributes => {
+        host => String,
+        port => { type => Integer, value => 80 },
+        tags => { type => Array[String], value => [] }
+      }
+    }`)
+		px.AddTypes(c, st)
+		old := px.New(c, st, types.Parse(`{host => 'example.com', tags => [blue]}`))
+		new := px.New(c, st, types.Parse(`{host => 'example.com', port => 8080, tags => [blue, green]}`))
+		p := patch.Diff(old, new)
+		for _, o := range p {
+			fmt.Println(o)
+		}
+		fmt.Println(patch.Apply(c, old, p))
+	})
+	// Output:
+	// add /tags/1 green
+	// add /port 8080
+	// My::Server('host' => 'example.com', 'port' => 8080, 'tags' => ['blue', 'green'])
+}
+
+func ExampleToJSONPatch() {
+	pcore.Do(func(c px.Context) {
+		old := types.Parse(`{'a/b' => [1, 2], 'c~d' => {e => 1}}`)
+		new := types.Parse(`{'a/b' => [1, 2, 3], 'c~d' => {}}`)
+
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(c, px.EmptyMap).Convert(patch.ToJSONPatch(patch.Diff(old, new)), serialization.NewJsonStreamer(buf))
+		fmt.Println(buf)
+
+		d := serialization.NewDeserializer(c, px.EmptyMap)
+		serialization.JsonToData(`/tmp/patch.json`, buf, d)
+		p := patch.FromJSONPatch(d.Value().(px.List))
+		fmt.Println(patch.Apply(c, old, p))
+	})
+	// Output:
+	// [{"op":"add","path":"/a~1b/2","value":3},{"op":"remove","path":"/c~0d/e"}]
+	// {'a/b' => [1, 2, 3], 'c~d' => {}}
+}
+
+func ExampleFromJSONPatch() {
+	pcore.Do(func(c px.Context) {
+		v := types.Parse(`{servers => [a, b], env => {level => 3}}`)
+		jp := types.Parse(`[
+      {op => test, path => '/env/level', value => 3},
+      {op => copy, from => '/servers/0', path => '/primary'},
+      {op => move, from => '/env/level', path => '/level'},
+      {op => move, from => '/servers/1', path => '/servers/0'}
+    ]`).(px.List)
+		p := patch.FromJSONPatch(jp)
+		for _, o := range p {
+			fmt.Println(o)
+		}
+		fmt.Println(patch.Apply(c, v, p))
+		fmt.Println(patch.ToJSONPatch(p[1:2]))
+
+		for _, o := range []string{
+			`{op => test, path => '/env/level', value => 4}`,
+			`{op => move, from => '/env', path => '/env/inner'}`,
+			`{op => copy, from => '/missing', path => '/x'}`,
+		} {
+			r := testutil.Reported(func() { patch.Apply(c, v, patch.FromJSONPatch(types.WrapValues([]px.Value{types.Parse(o)}))) })
+			fmt.Println(r.Argument(`op`), r.Argument(`path`), r.Argument(`detail`))
+		}
+	})
+	// Output:
+	// test /env/level 3
+	// copy /primary from /servers/0
+	// move /level from /env/level
+	// move /servers/0 from /servers/1
+	// {'servers' => ['b', 'a'], 'env' => {}, 'primary' => 'a', 'level' => 3}
+	// [{'op' => 'copy', 'path' => '/primary', 'from' => '/servers/0'}]
+	// test /env/level the value 3 is not equal to 4
+	// move /env/inner a value cannot be moved into itself
+	// copy /x no value at '/missing'
+}
@@ -0,0 +1,235 @@
+package proto
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/lyraproj/data-protobuf/datapb"
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func (s *schema) ToMessage(c px.Context, v px.PuppetObject) *dynamicpb.Message {
+	md, ok := s.messages[v.PType().Name()]
+	if !ok {
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `ToMessage`, `index`: 1,
+			`arg`: `the schema has no message for type ` + v.PType().Name()}))
+	}
+	return s.toMessage(c, md, v)
+}
+
+func (s *schema) toMessage(c px.Context, md protoreflect.MessageDescriptor, v px.PuppetObject) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	fds := md.Fields()
+	for _, af := range s.fields[md.FullName()] {
+		av, ok := v.Get(af.name)
+		if !ok || av.Equals(px.Undef, nil) {
+			continue
+		}
+		for i, at := range af.types {
+			if len(af.types) > 1 && !px.IsInstance(at, av) {
+				continue
+			}
+			fd := fds.ByNumber(protoreflect.FieldNumber(af.numbers[i]))
+			switch {
+			case fd.IsList():
+				l := m.Mutable(fd).List()
+				av.(px.List).Each(func(e px.Value) { l.Append(s.toProtoValue(c, fd, e)) })
+			case fd.IsMap():
+				pm := m.Mutable(fd).Map()
+				vd := fd.MapValue()
+				av.(px.OrderedMap).EachPair(func(k, e px.Value) {
+					pm.Set(protoreflect.ValueOfString(k.String()).MapKey(), s.toProtoValue(c, vd, e))
+				})
+			default:
+				m.Set(fd, s.toProtoValue(c, fd, av))
+			}
+			break
+		}
+	}
+	return m
+}
+
+// toProtoValue converts a singular value into a value for the given field of the given message
+func (s *schema) toProtoValue(c px.Context, fd protoreflect.FieldDescriptor, v px.Value) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(v.(px.Boolean).Bool())
+	case protoreflect.Int64Kind:
+		return protoreflect.ValueOfInt64(v.(px.Integer).Int())
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(v.(px.Number).Float())
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(v.String())
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(v.(*types.Binary).Bytes())
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(s.enumNumber(fd.Enum(), v.String()))
+	}
+
+	md := fd.Message()
+	sm := dynamicpb.NewMessage(md)
+	switch md.FullName() {
+	case timestampMessage:
+		t := v.(*types.Timestamp).Time()
+		setTime(sm, t.Unix(), int32(t.Nanosecond()))
+	case durationMessage:
+		d := v.(types.Timespan).Duration()
+		secs := d / time.Second
+		setTime(sm, int64(secs), int32(d-secs*time.Second))
+	case dataMessage:
+		pc := NewProtoConsumer()
+		serialization.NewSerializer(c, px.EmptyMap).Convert(v, pc)
+		bs, err := protov1.Marshal(pc.Value())
+		if err == nil {
+			err = proto.Unmarshal(bs, sm)
+		}
+		if err != nil {
+			panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+		}
+	default:
+		return protoreflect.ValueOfMessage(s.toMessage(c, md, v.(px.PuppetObject)))
+	}
+	return protoreflect.ValueOfMessage(sm)
+}
+
+func (s *schema) enumNumber(ed protoreflect.EnumDescriptor, v string) protoreflect.EnumNumber {
+	ss := s.enums[ed.FullName()]
+	for i, es := range ss {
+		if es == v {
+			return protoreflect.EnumNumber(i + 1)
+		}
+	}
+	// Enums may be case insensitive
+	for i, es := range ss {
+		if strings.EqualFold(es, v) {
+			return protoreflect.EnumNumber(i + 1)
+		}
+	}
+	panic(px.Error(px.IllegalArgument, issue.H{`function`: `ToMessage`, `index`: 1,
+		`arg`: `'` + v + `' is not a value of enum ` + string(ed.FullName())}))
+}
+
+func (s *schema) FromMessage(c px.Context, m protoreflect.Message) px.PuppetObject {
+	md := m.Descriptor()
+	t, ok := s.types[md.FullName()]
+	if !ok {
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `FromMessage`, `index`: 1,
+			`arg`: `the schema has no type for message ` + string(md.FullName())}))
+	}
+	fds := md.Fields()
+	entries := make([]*types.HashEntry, 0, fds.Len())
+	for _, af := range s.fields[md.FullName()] {
+		for _, n := range af.numbers {
+			fd := fds.ByNumber(protoreflect.FieldNumber(n))
+			if (fd.HasPresence() || fd.ContainingOneof() != nil) && !m.Has(fd) {
+				continue
+			}
+			var v px.Value
+			switch {
+			case fd.IsList():
+				l := m.Get(fd).List()
+				vs := make([]px.Value, l.Len())
+				for i := range vs {
+					vs[i] = s.fromProtoValue(c, fd, l.Get(i))
+				}
+				v = types.WrapValues(vs)
+			case fd.IsMap():
+				vd := fd.MapValue()
+				var es []*types.HashEntry
+				m.Get(fd).Map().Range(func(k protoreflect.MapKey, e protoreflect.Value) bool {
+					es = append(es, types.WrapHashEntry2(k.String(), s.fromProtoValue(c, vd, e)))
+					return true
+				})
+				v = types.WrapHash(es)
+			default:
+				v = s.fromProtoValue(c, fd, m.Get(fd))
+			}
+			if v != px.Undef {
+				entries = append(entries, types.WrapHashEntry2(af.name, v))
+			}
+			break
+		}
+	}
+	return px.New(c, t, types.WrapHash(entries)).(px.PuppetObject)
+}
+
+// fromProtoValue converts the singular value of the given field into a pcore value
+func (s *schema) fromProtoValue(c px.Context, fd protoreflect.FieldDescriptor, v protoreflect.Value) px.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return types.WrapBoolean(v.Bool())
+	case protoreflect.Int64Kind:
+		return types.WrapInteger(v.Int())
+	case protoreflect.DoubleKind:
+		return types.WrapFloat(v.Float())
+	case protoreflect.StringKind:
+		return types.WrapString(v.String())
+	case protoreflect.BytesKind:
+		return types.WrapBinary(v.Bytes())
+	case protoreflect.EnumKind:
+		ss := s.enums[fd.Enum().FullName()]
+		if n := int(v.Enum()); n > 0 && n <= len(ss) {
+			return types.WrapString(ss[n-1])
+		}
+		return px.Undef
+	}
+
+	sm := v.Message()
+	switch sm.Descriptor().FullName() {
+	case timestampMessage:
+		secs, nanos := getTime(sm)
+		return types.WrapTimestamp(time.Unix(secs, int64(nanos)).UTC())
+	case durationMessage:
+		return types.WrapTimespan(toDuration(getTime(sm)))
+	case dataMessage:
+		bs, err := proto.Marshal(sm.Interface())
+		d := &datapb.Data{}
+		if err == nil {
+			err = protov1.Unmarshal(bs, d)
+		}
+		if err != nil {
+			panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+		}
+		dc := serialization.NewDeserializer(c, px.EmptyMap)
+		ConsumePBData(d, dc)
+		return dc.Value()
+	}
+	return s.FromMessage(c, sm)
+}
+
+// setTime sets the seconds and nanos fields of a google.protobuf.Timestamp or google.protobuf.Duration
+func setTime(m *dynamicpb.Message, secs int64, nanos int32) {
+	fds := m.Descriptor().Fields()
+	m.Set(fds.ByName(`seconds`), protoreflect.ValueOfInt64(secs))
+	m.Set(fds.ByName(`nanos`), protoreflect.ValueOfInt32(nanos))
+}
+
+// getTime returns the seconds and nanos fields of a google.protobuf.Timestamp or google.protobuf.Duration
+func getTime(m protoreflect.Message) (int64, int32) {
+	fds := m.Descriptor().Fields()
+	return m.Get(fds.ByName(`seconds`)).Int(), int32(m.Get(fds.ByName(`nanos`)).Int())
+}
+
+// toDuration returns the duration of the given seconds and nanos. A duration that is out of range is
+// clamped to the range of time.Duration.
+func toDuration(secs int64, nanos int32) time.Duration {
+	d := time.Duration(secs) * time.Second
+	overflow := d/time.Second != time.Duration(secs)
+	d += time.Duration(nanos)
+	overflow = overflow || secs < 0 && nanos < 0 && d > 0 || secs > 0 && nanos > 0 && d < 0
+	if overflow {
+		if secs < 0 {
+			return time.Duration(math.MinInt64)
+		}
+		return time.Duration(math.MaxInt64)
+	}
+	return d
+}
@@ -0,0 +1,128 @@
+package proto
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/pcore/utils"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func (s *schema) WriteProto(out io.Writer) {
+	fd := s.file
+	b := utils.NewIndenter()
+	b.Printf("// Generated by pcore from %s\n\n", s.source.Name())
+	b.Append(`syntax = "proto3";`)
+	b.Append("\n")
+	if fd.Package() != `` {
+		b.Printf("\npackage %s;\n", fd.Package())
+	}
+	if imps := fd.Imports(); imps.Len() > 0 {
+		b.Append("\n")
+		for i := 0; i < imps.Len(); i++ {
+			b.Printf("import \"%s\";\n", imps.Get(i).Path())
+		}
+	}
+	ms := fd.Messages()
+	for i := 0; i < ms.Len(); i++ {
+		b.Append("\n")
+		s.writeMessage(b, ms.Get(i))
+		b.Append("\n")
+	}
+	_, err := io.WriteString(out, b.String())
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *schema) writeMessage(b *utils.Indenter, md protoreflect.MessageDescriptor) {
+	if t, ok := s.types[md.FullName()]; ok {
+		b.Printf("// %s\n", t.Name())
+	}
+	b.Printf("message %s {", md.Name())
+	ib := b.Indent()
+
+	eds := md.Enums()
+	for i := 0; i < eds.Len(); i++ {
+		ed := eds.Get(i)
+		ib.NewLine()
+		ib.Printf("enum %s {", ed.Name())
+		vb := ib.Indent()
+		vds := ed.Values()
+		for j := 0; j < vds.Len(); j++ {
+			vd := vds.Get(j)
+			vb.NewLine()
+			vb.Printf("%s = %d;", vd.Name(), vd.Number())
+		}
+		ib.NewLine()
+		ib.Append("}")
+	}
+
+	fds := md.Fields()
+	nums := make([]int, fds.Len())
+	for i := range nums {
+		nums[i] = int(fds.Get(i).Number())
+	}
+	sort.Ints(nums)
+	var oneof protoreflect.OneofDescriptor
+	for _, n := range nums {
+		fd := fds.ByNumber(protoreflect.FieldNumber(n))
+		od := fd.ContainingOneof()
+		if od != nil && od.IsSynthetic() {
+			od = nil
+		}
+		if od != oneof {
+			if oneof != nil {
+				ib.NewLine()
+				ib.Append("}")
+			}
+			if od != nil {
+				ib.NewLine()
+				ib.Printf("oneof %s {", od.Name())
+			}
+			oneof = od
+		}
+		fb := ib
+		if oneof != nil {
+			fb = ib.Indent()
+		}
+		fb.NewLine()
+		switch {
+		case fd.IsMap():
+			fb.Printf("map<%s, %s>", s.typeName(md, fd.MapKey()), s.typeName(md, fd.MapValue()))
+		case fd.IsList():
+			fb.Printf("repeated %s", s.typeName(md, fd))
+		case fd.HasOptionalKeyword():
+			fb.Printf("optional %s", s.typeName(md, fd))
+		default:
+			fb.Append(s.typeName(md, fd))
+		}
+		fb.Printf(" %s = %d;", fd.Name(), fd.Number())
+	}
+	if oneof != nil {
+		ib.NewLine()
+		ib.Append("}")
+	}
+	b.NewLine()
+	b.Append("}")
+}
+
+// typeName returns the name of the type of the given field relative to the scope of the given message
+func (s *schema) typeName(md protoreflect.MessageDescriptor, fd protoreflect.FieldDescriptor) string {
+	var n protoreflect.FullName
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		n = fd.Message().FullName()
+	case protoreflect.EnumKind:
+		n = fd.Enum().FullName()
+	default:
+		return fd.Kind().String()
+	}
+	for _, scope := range []protoreflect.FullName{md.FullName(), s.file.Package()} {
+		if scope != `` && strings.HasPrefix(string(n), string(scope)+`.`) {
+			return string(n[len(scope)+1:])
+		}
+	}
+	return string(n)
+}
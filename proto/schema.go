@@ -0,0 +1,468 @@
+package proto
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// Ensure that the well known types are registered
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// A Schema describes Object types as protobuf messages. The messages are declared in a protobuf file
+// descriptor that can be used with standard protobuf tooling, and instances of the Object types can be
+// converted to and from dynamic messages that conform to that descriptor.
+//
+// Each Object type is mapped to a message with one field per attribute. Attributes inherited from a parent
+// type are included, constants and derived attributes are not. Fields are numbered in the order that
+// the attributes are declared, starting with the attributes of the topmost parent.
+//
+// Attribute types are mapped as follows:
+//
+// Boolean, Integer, Float, String, Pattern, and Binary map to bool, int64, double, string, and bytes. An
+// Enum maps to a protobuf enum that is nested in the message. Timestamp and Timespan map to the well known
+// types google.protobuf.Timestamp and google.protobuf.Duration. An Object type maps to its message, which
+// is added to the schema when it isn't already present.
+//
+// Optional[T] maps to an optional field, Array[T] to a repeated field, and Hash[String, T] to a map
+// provided that T maps to a scalar, an enum, or a message. A Variant maps to a oneof with one field per
+// type in the Variant.
+//
+// All other types, such as Any, Tuple, Struct, SemVer, and Arrays of Arrays, map to the generic
+// puppet.datapb.Data message. Values of such fields are serialized with rich data enabled.
+type Schema interface {
+	// FileDescriptor returns the protobuf file descriptor that declares the messages
+	FileDescriptor() protoreflect.FileDescriptor
+
+	// MessageDescriptor returns the descriptor of the message that represents the given Object type
+	MessageDescriptor(t px.ObjectType) (protoreflect.MessageDescriptor, bool)
+
+	// ToMessage converts the given object into a dynamic message. The type of the object must be
+	// described by this schema.
+	ToMessage(c px.Context, v px.PuppetObject) *dynamicpb.Message
+
+	// FromMessage converts the given message into an instance of the Object type that the message
+	// represents
+	FromMessage(c px.Context, m protoreflect.Message) px.PuppetObject
+
+	// WriteProto writes the schema as a .proto source file to the given writer
+	WriteProto(out io.Writer)
+}
+
+const (
+	dataMessage      = `puppet.datapb.Data`
+	durationMessage  = `google.protobuf.Duration`
+	timestampMessage = `google.protobuf.Timestamp`
+)
+
+var wellKnownFiles = map[string]string{
+	dataMessage:      `datapb/data.proto`,
+	durationMessage:  `google/protobuf/duration.proto`,
+	timestampMessage: `google/protobuf/timestamp.proto`,
+}
+
+// attrField maps an attribute to the field, or the fields of a oneof, that represents it
+type attrField struct {
+	name string
+
+	// types contains one type per field. It is the type of the attribute without Optional or, for a oneof,
+	// the types of the Variant
+	types []px.Type
+
+	// numbers contains the field numbers in the same order as types
+	numbers []int32
+}
+
+type schema struct {
+	source   px.Type
+	file     protoreflect.FileDescriptor
+	objects  []px.ObjectType
+	messages map[string]protoreflect.MessageDescriptor
+	types    map[protoreflect.FullName]px.ObjectType
+	fields   map[protoreflect.FullName][]*attrField
+
+	// enums maps the full name of an enum to the strings that its values represent. The string for enum
+	// value n is found at index n - 1.
+	enums map[protoreflect.FullName][]string
+}
+
+// NewSchema creates a Schema for the given type, which must be an Object type or a TypeSet. The path is
+// the name of the .proto file that the schema describes. The protobuf package is derived from the name of
+// the type set or the namespace of the object type so that the TypeSet My::Own results in the package
+// my.own and its type My::Own::Address in the message my.own.Address.
+func NewSchema(c px.Context, path string, t px.Type) Schema {
+	var pkg string
+	b := &schemaBuilder{
+		c:        c,
+		names:    make(map[string]string),
+		enums:    make(map[protoreflect.FullName][]string),
+		fields:   make(map[string][]*attrField),
+		usedDeps: make(map[string]bool)}
+
+	switch t := t.(type) {
+	case px.TypeSet:
+		pkg = t.Name()
+		t.Types().EachValue(func(v px.Value) {
+			if ot, ok := v.(px.ObjectType); ok {
+				b.add(ot)
+			}
+		})
+	case px.ObjectType:
+		if i := strings.LastIndex(t.Name(), `::`); i > 0 {
+			pkg = t.Name()[:i]
+		}
+		b.add(t)
+	default:
+		panic(px.Error(px.UnableToGenerateProto, issue.H{`type`: t.Name(), `detail`: `only Object types and TypeSets can be described`}))
+	}
+	b.pkg = strings.ToLower(strings.Replace(pkg, `::`, `.`, -1))
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:   &path,
+		Syntax: str(`proto3`)}
+	if b.pkg != `` {
+		fdp.Package = &b.pkg
+	}
+	for i := 0; i < len(b.objects); i++ {
+		// The slice of objects grows when referenced types are found
+		fdp.MessageType = append(fdp.MessageType, b.message(b.objects[i]))
+	}
+	for _, dep := range []string{dataMessage, durationMessage, timestampMessage} {
+		if b.usedDeps[dep] {
+			fdp.Dependency = append(fdp.Dependency, wellKnownFiles[dep])
+		}
+	}
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(px.Error(px.UnableToGenerateProto, issue.H{`type`: t.Name(), `detail`: err.Error()}))
+	}
+
+	s := &schema{
+		source:   t,
+		file:     fd,
+		objects:  b.objects,
+		messages: make(map[string]protoreflect.MessageDescriptor, len(b.objects)),
+		types:    make(map[protoreflect.FullName]px.ObjectType, len(b.objects)),
+		fields:   make(map[protoreflect.FullName][]*attrField, len(b.objects)),
+		enums:    b.enums}
+	for _, ot := range b.objects {
+		md := fd.Messages().ByName(protoreflect.Name(b.names[ot.Name()]))
+		s.messages[ot.Name()] = md
+		s.types[md.FullName()] = ot
+		s.fields[md.FullName()] = b.fields[ot.Name()]
+	}
+	return s
+}
+
+func (s *schema) FileDescriptor() protoreflect.FileDescriptor {
+	return s.file
+}
+
+func (s *schema) MessageDescriptor(t px.ObjectType) (protoreflect.MessageDescriptor, bool) {
+	md, ok := s.messages[t.Name()]
+	return md, ok
+}
+
+type schemaBuilder struct {
+	c        px.Context
+	pkg      string
+	objects  []px.ObjectType
+	names    map[string]string
+	enums    map[protoreflect.FullName][]string
+	fields   map[string][]*attrField
+	usedDeps map[string]bool
+
+	// state for the message that is currently being built
+	msg       *descriptorpb.DescriptorProto
+	msgName   string
+	synthetic []*descriptorpb.FieldDescriptorProto
+	number    int32
+}
+
+// add adds the given Object type to the types that will be described by messages unless it has been
+// added already, and returns the name of the message
+func (b *schemaBuilder) add(t px.ObjectType) string {
+	if n, ok := b.names[t.Name()]; ok {
+		return n
+	}
+	segs := strings.Split(t.Name(), `::`)
+	n := segs[len(segs)-1]
+	for _, on := range b.names {
+		if on == n {
+			// Name clash. Use the fully qualified name instead
+			n = strings.Join(segs, ``)
+			break
+		}
+	}
+	b.names[t.Name()] = n
+	b.objects = append(b.objects, t)
+	return n
+}
+
+func (b *schemaBuilder) fullName(name string) string {
+	if b.pkg == `` {
+		return `.` + name
+	}
+	return `.` + b.pkg + `.` + name
+}
+
+func (b *schemaBuilder) message(t px.ObjectType) *descriptorpb.DescriptorProto {
+	b.msgName = b.names[t.Name()]
+	b.msg = &descriptorpb.DescriptorProto{Name: str(b.msgName)}
+	b.synthetic = nil
+	b.number = 0
+
+	afs := make([]*attrField, 0)
+	for _, a := range attributes(t) {
+		at := a.Type()
+		optional := false
+		if ot, ok := at.(*types.OptionalType); ok {
+			optional = true
+			at = ot.ContainedType()
+		}
+
+		af := &attrField{name: a.Name()}
+		if vt, ok := at.(*types.VariantType); ok {
+			oi := int32(len(b.msg.OneofDecl))
+			b.msg.OneofDecl = append(b.msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: str(a.Name())})
+			taken := make(map[string]bool)
+			for _, et := range vt.Types() {
+				if _, ok := et.(*types.UndefType); ok {
+					continue
+				}
+				fn := a.Name() + `_` + snakeCase(typeLabel(et))
+				for i := 2; taken[fn]; i++ {
+					fn = fmt.Sprintf(`%s_%s_%d`, a.Name(), snakeCase(typeLabel(et)), i)
+				}
+				taken[fn] = true
+				fdp := b.singularField(fn, et)
+				fdp.OneofIndex = &oi
+				af.types = append(af.types, et)
+				af.numbers = append(af.numbers, fdp.GetNumber())
+			}
+		} else {
+			fdp := b.field(a.Name(), at)
+			if optional && fdp.GetLabel() != descriptorpb.FieldDescriptorProto_LABEL_REPEATED && fdp.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+				fdp.Proto3Optional = proto3Optional
+				b.synthetic = append(b.synthetic, fdp)
+			}
+			af.types = []px.Type{at}
+			af.numbers = []int32{fdp.GetNumber()}
+		}
+		afs = append(afs, af)
+	}
+
+	// Synthetic oneofs for proto3 optional fields must be declared after all other oneofs
+	for _, fdp := range b.synthetic {
+		oi := int32(len(b.msg.OneofDecl))
+		b.msg.OneofDecl = append(b.msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: str(`_` + fdp.GetName())})
+		fdp.OneofIndex = &oi
+	}
+	b.fields[t.Name()] = afs
+	return b.msg
+}
+
+var proto3Optional = func() *bool { b := true; return &b }()
+
+// field adds a field that represents a value of the given type to the current message
+func (b *schemaBuilder) field(name string, t px.Type) *descriptorpb.FieldDescriptorProto {
+	switch t := t.(type) {
+	case *types.ArrayType:
+		if et, ok := b.scalarType(name, t.ElementType()); ok {
+			fdp := b.newField(name, et)
+			fdp.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			return fdp
+		}
+	case *types.HashType:
+		if isStringType(t.KeyType()) {
+			if vt, ok := b.scalarType(name, t.ValueType()); ok {
+				en := camelCase(name) + `Entry`
+				entry := &descriptorpb.DescriptorProto{
+					Name:    str(en),
+					Options: &descriptorpb.MessageOptions{MapEntry: proto3Optional},
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{Name: str(`key`), Number: num(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), JsonName: str(`key`)},
+						{Name: str(`value`), Number: num(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type: vt.typ.Enum(), TypeName: vt.typeName, JsonName: str(`value`)}}}
+				b.msg.NestedType = append(b.msg.NestedType, entry)
+				fdp := b.newField(name, &scalar{descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, str(b.fullName(b.msgName + `.` + en))})
+				fdp.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+				return fdp
+			}
+		}
+	}
+	return b.singularField(name, t)
+}
+
+// singularField adds a field that is neither repeated nor a map to the current message
+func (b *schemaBuilder) singularField(name string, t px.Type) *descriptorpb.FieldDescriptorProto {
+	if st, ok := b.scalarType(name, t); ok {
+		return b.newField(name, st)
+	}
+	return b.newField(name, b.messageType(dataMessage))
+}
+
+func (b *schemaBuilder) newField(name string, st *scalar) *descriptorpb.FieldDescriptorProto {
+	b.number++
+	fdp := &descriptorpb.FieldDescriptorProto{
+		Name:     str(name),
+		Number:   num(b.number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     st.typ.Enum(),
+		TypeName: st.typeName,
+		JsonName: str(jsonName(name))}
+	b.msg.Field = append(b.msg.Field, fdp)
+	return fdp
+}
+
+type scalar struct {
+	typ      descriptorpb.FieldDescriptorProto_Type
+	typeName *string
+}
+
+// scalarType returns the type of a singular field that represents values of the given type, or false
+// when such values must be represented by the generic Data message
+func (b *schemaBuilder) scalarType(name string, t px.Type) (*scalar, bool) {
+	switch t := t.(type) {
+	case *types.BooleanType:
+		return &scalar{typ: descriptorpb.FieldDescriptorProto_TYPE_BOOL}, true
+	case *types.IntegerType:
+		return &scalar{typ: descriptorpb.FieldDescriptorProto_TYPE_INT64}, true
+	case *types.FloatType:
+		return &scalar{typ: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE}, true
+	case *types.BinaryType:
+		return &scalar{typ: descriptorpb.FieldDescriptorProto_TYPE_BYTES}, true
+	case *types.TimestampType:
+		return b.messageType(timestampMessage), true
+	case *types.TimespanType:
+		return b.messageType(durationMessage), true
+	case *types.EnumType:
+		return b.enumType(name, t), true
+	case px.ObjectType:
+		return &scalar{descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, str(b.fullName(b.add(t)))}, true
+	case px.StringType, *types.PatternType:
+		return &scalar{typ: descriptorpb.FieldDescriptorProto_TYPE_STRING}, true
+	}
+	return nil, false
+}
+
+func (b *schemaBuilder) messageType(name string) *scalar {
+	b.usedDeps[name] = true
+	return &scalar{descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, str(`.` + name)}
+}
+
+// enumType adds an enum that is nested in the current message. Its values are prefixed by the enum name
+// because nested enum values share scope with the fields of the message.
+func (b *schemaBuilder) enumType(name string, t *types.EnumType) *scalar {
+	en := camelCase(name)
+	for i := 2; b.hasEnum(en); i++ {
+		en = fmt.Sprintf(`%s%d`, camelCase(name), i)
+	}
+	pfx := strings.ToUpper(snakeCase(en)) + `_`
+	ed := &descriptorpb.EnumDescriptorProto{
+		Name:  str(en),
+		Value: []*descriptorpb.EnumValueDescriptorProto{{Name: str(pfx + `UNSPECIFIED`), Number: num(0)}}}
+	taken := make(map[string]bool)
+	ss := t.Strings()
+	for i, s := range ss {
+		vn := pfx + strings.ToUpper(strings.Trim(nonIdentChars.ReplaceAllString(s, `_`), `_`))
+		if vn == pfx || taken[vn] {
+			vn = fmt.Sprintf(`%sVALUE_%d`, pfx, i+1)
+		}
+		taken[vn] = true
+		ed.Value = append(ed.Value, &descriptorpb.EnumValueDescriptorProto{Name: str(vn), Number: num(int32(i + 1))})
+	}
+	b.msg.EnumType = append(b.msg.EnumType, ed)
+	fn := b.fullName(b.msgName + `.` + en)
+	b.enums[protoreflect.FullName(fn[1:])] = ss
+	return &scalar{descriptorpb.FieldDescriptorProto_TYPE_ENUM, str(fn)}
+}
+
+func (b *schemaBuilder) hasEnum(name string) bool {
+	for _, ed := range b.msg.EnumType {
+		if ed.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attributes returns the attributes of the given type and its parents that are represented in a message
+func attributes(t px.ObjectType) []px.Attribute {
+	var as []px.Attribute
+	if pt, ok := t.Parent().(px.ObjectType); ok {
+		as = attributes(pt)
+	}
+	if ah, ok := t.(px.PuppetObject).InitHash().Get4(`attributes`); ok {
+		ah.(px.OrderedMap).EachKey(func(k px.Value) {
+			if m, ok := t.Member(k.String()); ok {
+				if a, ok := m.(px.Attribute); ok && a.Kind() != `derived` {
+					as = append(as, a)
+				}
+			}
+		})
+	}
+	return as
+}
+
+func isStringType(t px.Type) bool {
+	switch t.(type) {
+	case px.StringType, *types.EnumType, *types.PatternType:
+		return true
+	}
+	return false
+}
+
+// typeLabel returns a name for the given type that is used when naming the fields of a oneof
+func typeLabel(t px.Type) string {
+	n := t.Name()
+	return n[strings.LastIndex(n, `::`)+1:]
+}
+
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+var camelHump = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func snakeCase(s string) string {
+	return strings.ToLower(camelHump.ReplaceAllString(strings.TrimLeft(s, `:`), `${1}_${2}`))
+}
+
+func camelCase(s string) string {
+	b := strings.Builder{}
+	for _, w := range nonIdentChars.Split(s, -1) {
+		if w != `` {
+			b.WriteString(strings.ToUpper(w[:1]))
+			b.WriteString(w[1:])
+		}
+	}
+	return b.String()
+}
+
+func jsonName(s string) string {
+	cc := camelCase(s)
+	if cc == `` {
+		return s
+	}
+	return strings.ToLower(cc[:1]) + cc[1:]
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func num(n int32) *int32 {
+	return &n
+}
@@ -0,0 +1,110 @@
+package proto_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/proto"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const addressBook = `TypeSet[{
+  name => 'AddressBook',
+  pcore_version => '1.0.0',
+  version => '1.0.0',
+  types => {
+    Person => {
+      attributes => {
+        name => String,
+        email => Optional[String],
+        kind => Enum[private, work],
+        phones => Array[String],
+        born => Optional[Timestamp],
+        id => Variant[Integer, String],
+        extra => Hash[String, Any]
+      }
+    },
+    Book => {
+      attributes => {
+        owner => Person,
+        contacts => Hash[String, Person]
+      }
+    }
+  }
+}]`
+
+func ExampleNewSchema() {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, c.ParseType(addressBook))
+		ts := c.ParseType(`AddressBook`)
+		proto.NewSchema(c, `address_book.proto`, ts).WriteProto(os.Stdout)
+	})
+	// Output:
+	// // Generated by pcore from AddressBook
+	//
+	// syntax = "proto3";
+	//
+	// package addressbook;
+	//
+	// import "datapb/data.proto";
+	// import "google/protobuf/timestamp.proto";
+	//
+	// // AddressBook::Person
+	// message Person {
+	//   enum Kind {
+	//     KIND_UNSPECIFIED = 0;
+	//     KIND_PRIVATE = 1;
+	//     KIND_WORK = 2;
+	//   }
+	//   string name = 1;
+	//   optional string email = 2;
+	//   Kind kind = 3;
+	//   repeated string phones = 4;
+	//   google.protobuf.Timestamp born = 5;
+	//   oneof id {
+	//     int64 id_integer = 6;
+	//     string id_string = 7;
+	//   }
+	//   puppet.datapb.Data extra = 8;
+	// }
+	//
+	// // AddressBook::Book
+	// message Book {
+	//   Person owner = 1;
+	//   map<string, Person> contacts = 2;
+	// }
+}
+
+func ExampleSchema_ToMessage() {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, c.ParseType(addressBook))
+		s := proto.NewSchema(c, `address_book.proto`, c.ParseType(`AddressBook`))
+
+		p := px.New(c, c.ParseType(`AddressBook::Person`), px.Wrap(c, map[string]interface{}{
+			`name`:   `Bob`,
+			`kind`:   `work`,
+			`phones`: []string{`555-1234`},
+			`born`:   types.WrapTimestamp(time.Date(1600, 1, 1, 0, 0, 0, 500, time.UTC)),
+			`id`:     `bob-1`,
+			`extra`:  map[string]interface{}{`ver`: types.WrapSemVer(semver.MustParseVersion(`1.2.3`))},
+		})).(px.PuppetObject)
+
+		bs, err := protobuf.Marshal(s.ToMessage(c, p))
+		if err != nil {
+			panic(err)
+		}
+		md, _ := s.MessageDescriptor(p.PType().(px.ObjectType))
+		m := dynamicpb.NewMessage(md)
+		if err = protobuf.Unmarshal(bs, m); err != nil {
+			panic(err)
+		}
+		fmt.Println(s.FromMessage(c, m))
+	})
+	// Output: AddressBook::Person('name' => 'Bob', 'kind' => 'work', 'phones' => ['555-1234'], 'id' => 'bob-1', 'extra' => {'ver' => SemVer('1.2.3')}, 'born' => 1600-01-01T00:00:00.000000500 UTC)
+}
@@ -58,6 +58,10 @@ var DefaultFormatContext FormatContext
 var Pretty FormatContext
 var PrettyExpanded FormatContext
 
+// ExactFloats is like DefaultFormatContext but writes floats in program format using the shortest
+// representation that reads back as the same value
+var ExactFloats FormatContext
+
 var NewFormat func(format string) Format
 var NewIndentation func(indenting bool, level int) Indentation
 var NewFormatContext func(t Type, format Format, indentation Indentation) FormatContext
@@ -14,7 +14,9 @@ const (
 	ConstantRequiresValue                 = `PCORE_CONSTANT_REQUIRES_VALUE`
 	ConstantWithFinal                     = `PCORE_CONSTANT_WITH_FINAL`
 	CtorNotFound                          = `PCORE_CTOR_NOT_FOUND`
+	DepthLimitExceeded                    = `PCORE_DEPTH_LIMIT_EXCEEDED`
 	DuplicateKey                          = `PCORE_DUPLICATE_KEY`
+	ElementLimitExceeded                  = `PCORE_ELEMENT_LIMIT_EXCEEDED`
 	EmptyTypeParameterList                = `PCORE_EMPTY_TYPE_PARAMETER_LIST`
 	EqualityAttributeNotFound             = `PCORE_EQUALITY_ATTRIBUTE_NOT_FOUND`
 	EqualityNotAttribute                  = `PCORE_EQUALITY_NOT_ATTRIBUTE`
@@ -34,9 +36,15 @@ const (
 	ImplAlreadyRegistered                 = `PCORE_IMPL_ALREADY_REGISTERED`
 	InstanceDoesNotRespond                = `PCORE_INSTANCE_DOES_NOT_RESPOND`
 	ImpossibleOptional                    = `PCORE_IMPOSSIBLE_OPTIONAL`
+	InvalidCbor                           = `PCORE_INVALID_CBOR`
 	InvalidCharactersInName               = `PCORE_INVALID_CHARACTERS_IN_NAME`
 	InvalidHashKey                        = `PCORE_INVALID_MAP_KEY`
 	InvalidJson                           = `PCORE_INVALID_JSON`
+	InvalidJsonPatch                      = `PCORE_INVALID_JSON_PATCH`
+	InvalidJsonSchema                     = `PCORE_INVALID_JSON_SCHEMA`
+	InvalidMsgpack                        = `PCORE_INVALID_MSGPACK`
+	InvalidQuery                          = `PCORE_INVALID_QUERY`
+	InvalidReference                      = `PCORE_INVALID_REFERENCE`
 	InvalidRegexp                         = `PCORE_INVALID_REGEXP`
 	InvalidSourceForGet                   = `PCORE_INVALID_SOURCE_FOR_GET`
 	InvalidSourceForSet                   = `PCORE_INVALID_SOURCE_FOR_SET`
@@ -78,12 +86,15 @@ const (
 	SerializationDefaultConvertedToString = `PCORE_SERIALIZATION_DEFAULT_CONVERTED_TO_STRING`
 	SerializationRequiredAfterOptional    = `PCORE_SERIALIZATION_REQUIRED_AFTER_OPTIONAL`
 	SerializationUnknownConvertedToString = `PCORE_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING`
+	SizeLimitExceeded                     = `PCORE_SIZE_LIMIT_EXCEEDED`
 	TimespanBadFormatSpec                 = `PCORE_TIMESPAN_BAD_FORMAT_SPEC`
 	CannotBeParsed                        = `PCORE_TIMESPAN_CANNOT_BE_PARSED`
 	TimespanFormatSpecNotHigher           = `PCORE_TIMESPAN_FORMAT_SPEC_NOT_HIGHER`
 	TimestampCannotBeParsed               = `PCORE_TIMESTAMP_CANNOT_BE_PARSED`
 	TimestampTzAmbiguity                  = `PCORE_TIMESTAMP_TZ_AMBIGUITY`
 	TypeMismatch                          = `PCORE_TYPE_MISMATCH`
+	TypeMismatches                        = `PCORE_TYPE_MISMATCHES`
+	TypeNotAllowed                        = `PCORE_TYPE_NOT_ALLOWED`
 	TypesetAliasCollides                  = `PCORE_TYPESET_ALIAS_COLLIDES`
 	TypesetMissingNameAuthority           = `PCORE_TYPESET_MISSING_NAME_AUTHORITY`
 	TypesetReferenceBadType               = `PCORE_TYPESET_REFERENCE_BAD_TYPE`
@@ -91,11 +102,18 @@ const (
 	TypesetReferenceMismatch              = `PCORE_TYPESET_REFERENCE_MISMATCH`
 	TypesetReferenceOverlap               = `PCORE_TYPESET_REFERENCE_OVERLAP`
 	TypesetReferenceUnresolved            = `PCORE_TYPESET_REFERENCE_UNRESOLVED`
+	UnableToApplyPatch                    = `PCORE_UNABLE_TO_APPLY_PATCH`
+	UnableToDecryptSensitive              = `PCORE_UNABLE_TO_DECRYPT_SENSITIVE`
 	UnableToDeserializeType               = `PCORE_UNABLE_TO_DESERIALIZE_TYPE`
 	UnableToDeserializeValue              = `PCORE_UNABLE_TO_DESERIALIZE_VALUE`
+	UnableToGenerateGo                    = `PCORE_UNABLE_TO_GENERATE_GO`
+	UnableToGenerateProto                 = `PCORE_UNABLE_TO_GENERATE_PROTO`
+	UnableToGenerateValue                 = `PCORE_UNABLE_TO_GENERATE_VALUE`
 	UnableToReadFile                      = `PCORE_UNABLE_TO_READ_FILE`
+	UnableToWriteFile                     = `PCORE_UNABLE_TO_WRITE_FILE`
 	UnhandledPcoreVersion                 = `PCORE_UNHANDLED_PCORE_VERSION`
 	UnknownFunction                       = `PCORE_UNKNOWN_FUNCTION`
+	UnknownMergeStrategy                  = `PCORE_UNKNOWN_MERGE_STRATEGY`
 	UnknownVariable                       = `PCORE_UNKNOWN_VARIABLE`
 	UnreflectableType                     = `PCORE_UNREFLECTABLE_TYPE`
 	UnreflectableValue                    = `PCORE_UNREFLECTABLE_VALUE`
@@ -129,8 +147,12 @@ func init() {
 	// TRANSLATOR 'final => false' is puppet syntax and should not be translated
 	issue.Hard(ConstantWithFinal, `%{label} of kind 'constant' cannot be combined with final => false`)
 
+	issue.Hard(DepthLimitExceeded, `Unable to deserialize a value that is nested deeper than the maximum depth of %{max}`)
+
 	issue.Hard(DuplicateKey, `The key '%{key}' is declared more than once`)
 
+	issue.Hard(ElementLimitExceeded, `Unable to deserialize a value that consists of more than the maximum of %{max} elements`)
+
 	issue.Hard(EmptyTypeParameterList, `The %{label}-Type cannot be parameterized using an empty parameter list`)
 
 	issue.Hard(EqualityAttributeNotFound, `%{label} equality is referencing non existent attribute '%{attribute}'`)
@@ -171,12 +193,24 @@ func init() {
 
 	issue.Hard(InstanceDoesNotRespond, `An instance of %{type} does not respond to %{message}`)
 
+	issue.Hard(InvalidCbor, `Unable to parse CBOR: %{detail}`)
+
 	issue.Hard(InvalidCharactersInName, `Name '%{name} contains invalid characters. Must start with letter and only contain letters, digits, and underscore'`)
 
 	issue.Hard(InvalidJson, `Unable to parse JSON from '%{path}': %{detail}`)
 
+	issue.Hard(InvalidJsonPatch, `Invalid JSON Patch operation at index %{index}: %{detail}`)
+
+	issue.Hard(InvalidJsonSchema, `Unable to import JSON Schema at '%{path}': %{detail}`)
+
 	issue.Hard2(InvalidHashKey, `%{type} values cannot be used as a keys in a Hash`, issue.HF{`type`: issue.UcAnOrA})
 
+	issue.Hard(InvalidMsgpack, `Unable to parse MessagePack: %{detail}`)
+
+	issue.Hard(InvalidQuery, `Invalid query '%{query}' at position %{pos}: %{detail}`)
+
+	issue.Hard(InvalidReference, `Unable to deserialize a reference to value %{ref}. The number of values read is %{count}`)
+
 	issue.Hard(InvalidRegexp, `Cannot compile regular expression '%{pattern}': %{detail}`)
 
 	issue.Hard2(InvalidSourceForGet, `Cannot create a reflect.Value from %{type}`, issue.HF{`type`: issue.AnOrA})
@@ -257,6 +291,8 @@ func init() {
 
 	issue.Hard(SerializationRequiredAfterOptional, `%{label} serialization is referencing required %{required} after optional %{optional}. Optional attributes must be last`)
 
+	issue.Hard(SizeLimitExceeded, `Unable to deserialize a %{type} of size %{size}. The maximum size is %{max}`)
+
 	issue.Hard(TimespanBadFormatSpec, `Bad format specifier '%{expression}' in '%{format}', at position %{position}`)
 
 	issue.Hard(CannotBeParsed, `Unable to parse Timespan '%{str}' using any of the formats %{formats}`)
@@ -269,6 +305,10 @@ func init() {
 
 	issue.Hard(TypeMismatch, `Type mismatch: %{detail}`)
 
+	issue.Hard2(TypeMismatches, `Found %{count} type mismatch(es):%{issues}`, issue.HF{`issues`: issue.JoinErrors})
+
+	issue.Hard(TypeNotAllowed, `Deserialization of a value of type '%{type}' is not allowed`)
+
 	issue.Hard(TypesetAliasCollides, `TypeSet '%{name}' references a TypeSet using alias '%{ref_alias}'. The alias collides with the name of a declared type`)
 
 	issue.Hard(TypesetMissingNameAuthority, `No 'name_authority' is declared in TypeSet '%{name}' and it cannot be inferred`)
@@ -283,16 +323,30 @@ func init() {
 
 	issue.Hard(TypesetReferenceUnresolved, `TypeSet '%{name}' reference to TypeSet '%{ref_name}' cannot be resolved`)
 
+	issue.Hard(UnableToApplyPatch, `Unable to apply %{op} operation at '%{path}': %{detail}`)
+
+	issue.Hard(UnableToDecryptSensitive, `Unable to decrypt a Sensitive value that was encrypted using key '%{key}': %{detail}`)
+
 	issue.Hard(UnableToDeserializeType, `Unable to deserialize a data type from hash %{hash}`)
 
 	issue.Hard2(UnableToDeserializeValue, `Unable to deserialize an instance of %{type} from %{arg_type}`, issue.HF{`arg_type`: issue.AnOrA})
 
+	issue.Hard(UnableToGenerateGo, `Unable to generate Go code for %{type}: %{detail}`)
+
+	issue.Hard(UnableToGenerateProto, `Unable to generate protobuf schema for %{type}: %{detail}`)
+
+	issue.Hard(UnableToGenerateValue, `Unable to generate a value for %{type}: %{detail}`)
+
 	issue.Hard(UnableToReadFile, `Unable to read file '%{path}': %{detail}`)
 
+	issue.Hard(UnableToWriteFile, `Unable to write file '%{path}': %{detail}`)
+
 	issue.Hard(UnhandledPcoreVersion, `The pcore version for TypeSet '%{name}' is not understood by this runtime. Expected range %{expected_range}, got %{pcore_version}`)
 
 	issue.Hard(UnknownFunction, `Unknown function: '%{name}'`)
 
+	issue.Hard(UnknownMergeStrategy, `Unknown merge strategy '%{name}'`)
+
 	issue.Hard(UnknownVariable, `Unknown variable: '$%{name}'`)
 
 	issue.Hard(UnreflectableType, `Unable to create a pcore.Type from value of type '%{type}'`)
@@ -272,6 +272,25 @@ type (
 
 var CommonType func(a Type, b Type) Type
 
+// Intersect returns the greatest common subtype of a and b, i.e. a type whose instances are the values that
+// are instances of both a and b. The result is Unit when no value is an instance of both types. Note that Unit
+// is not an empty type since every value is an instance of Unit, so callers must test whether the result is
+// the Unit type to find out if the intersection is empty.
+//
+// An intersection that cannot be expressed as a type, such as the one between a Pattern and a String with a
+// size, results in a type that accepts more values than the intersection, in this case the Pattern. The
+// intersection of types that are not known to be disjoint and for which no better type is known results in
+// the type whose instances are all of one kind, such as Integer for Iterable and Integer, or in a when both or
+// neither of them are, such as for two Callable types.
+var Intersect func(a Type, b Type) Type
+
+// Subtract returns a type whose instances are the values that are instances of a but not of b. The result is
+// Unit when all instances of a are instances of b.
+//
+// A difference that cannot be expressed as a type, such as a String without a given Enum, results in a type
+// that accepts more values than the difference, in this case the String.
+var Subtract func(a Type, b Type) Type
+
 var GenericType func(t Type) Type
 
 var IsInstance func(puppetType Type, value Value) bool
@@ -281,6 +300,21 @@ var IsAssignable func(puppetType Type, other Type) bool
 
 var Generalize func(t Type) Type
 
+// InferType returns the tightest reasonable type that all the given sample values are instances of. The
+// types of the values are merged so that hashes with String keys become a Struct where keys that are
+// missing in some of the values are optional, small sets of strings become an Enum, and integers and floats
+// get ranges. Values of different kinds give a Variant and the presence of undef values an Optional.
+//
+// The following options control how aggressively the result is generalized:
+//
+//	enum_limit   - the maximum number of distinct strings that form an Enum (default 8, 0 means never)
+//	struct_limit - the maximum number of keys of a Struct, hashes with more keys give a Hash (default 32)
+//	generalize   - when true, Generalize is applied to all scalar types except Enums (default false)
+//
+// A TypeMismatch error is raised for options that are unknown or have values of the wrong type. The result
+// is Any when no values are given.
+var InferType func(values []Value, options OrderedMap) Type
+
 var Normalize func(t Type) Type
 
 var DefaultFor func(t Type) Type
@@ -326,6 +360,10 @@ var DescribeSignatures func(signatures []Signature, argsTuple Type, block Lambda
 // string is prefixed with the given pfx
 var DescribeMismatch func(pfx string, expected Type, actual Type) string
 
+// DescribeMismatch2 is like DescribeMismatch but describes each mismatch relative to the given path, e.g. the
+// path of a value in a document. The mismatches are not prefixed when the path is empty.
+var DescribeMismatch2 func(path string, expected Type, actual Type) string
+
 // NewGoType will infer the Pcore type from the public attributes and functions of the provided
 // zeroValue which must be a struct or a pointer to a struct.
 var NewGoType func(name string, zeroValue interface{}) ObjectType
@@ -336,6 +374,12 @@ var NewGoType func(name string, zeroValue interface{}) ObjectType
 // instance.
 var NewGoObjectType func(name string, rType reflect.Type, typeDecl string, creators ...DispatchFunction) ObjectType
 
+// NewGoTypeSet is like Reflector.TypeSetFromReflect but it is intended to be called from a Go init() function
+// where no Context is available. The TypeSet is created and added to the loader when the runtime resolves
+// its types. The mappings between the types of the TypeSet and the given reflect.Types are then registered
+// with the ImplementationRegistry.
+var NewGoTypeSet func(typeSetName string, version semver.Version, aliases map[string]string, rTypes ...reflect.Type) TypeSet
+
 // NewNamedType should be used to register an alias for another type.
 var NewNamedType func(name, typeDecl string) Type
 
@@ -0,0 +1,157 @@
+package px_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/semver/semver"
+)
+
+const people = `[
+  { name => 'Alice', age => 31, role => 'admin', tags => ['a', 'b'], score => 1.5 },
+  { name => 'Bob', age => 45, role => 'user', tags => [], score => 3 },
+  { name => 'Carol', age => 28, role => 'user', manager => 'Alice', score => undef }
+]`
+
+func ExampleInferType() {
+	pcore.Do(func(c px.Context) {
+		samples := types.Parse(people).(px.List)
+		fmt.Println(px.InferType(samples.AppendTo(nil), nil))
+	})
+	// Output: Struct[{'name' => Enum['Alice', 'Bob', 'Carol'], 'age' => Integer[28, 45], 'role' => Enum['admin', 'user'], Optional['tags'] => Array[Enum['a', 'b'], 0, 2], NotUndef['score'] => Optional[Variant[Float[1.50000, 1.50000], Integer[3, 3]]], Optional['manager'] => Enum['Alice']}]
+}
+
+func ExampleInferType_generalize() {
+	pcore.Do(func(c px.Context) {
+		samples := types.Parse(people).(px.List)
+		fmt.Println(px.InferType(samples.AppendTo(nil), px.Wrap(c, map[string]interface{}{`enum_limit`: 2, `generalize`: true}).(px.OrderedMap)))
+	})
+	// Output: Struct[{'name' => String, 'age' => Integer, 'role' => Enum['admin', 'user'], Optional['tags'] => Array[Enum['a', 'b']], NotUndef['score'] => Optional[Variant[Float, Integer]], Optional['manager'] => Enum['Alice']}]
+}
+
+func ExampleInferType_hash() {
+	pcore.Do(func(c px.Context) {
+		samples := types.Parse(`[
+      { 1 => 'one' },
+      { 2 => 'two', 3 => 'three' },
+      {}
+    ]`).(px.List)
+		fmt.Println(px.InferType(samples.AppendTo(nil), nil))
+
+		samples = types.Parse(`[{ a => 1, b => 2 }, { c => 3 }]`).(px.List)
+		fmt.Println(px.InferType(samples.AppendTo(nil), px.Wrap(c, map[string]interface{}{`struct_limit`: 2}).(px.OrderedMap)))
+	})
+	// Output:
+	// Hash[Integer[1, 3], Enum['one', 'two', 'three'], 0, 2]
+	// Hash[Enum['a', 'b', 'c'], Integer[1, 3], 1, 2]
+}
+
+func ExampleInferType_invalidOptions() {
+	pcore.Do(func(c px.Context) {
+		r := testutil.Reported(func() { px.InferType(nil, px.Wrap(c, map[string]interface{}{`enum_limit`: `many`}).(px.OrderedMap)) })
+		fmt.Println(r.Code())
+	})
+	// Output: PCORE_TYPE_MISMATCH
+}
+
+func ExampleIntersect() {
+	pcore.Do(func(c px.Context) {
+		for _, pair := range [][2]string{
+			{`Integer[0, 10]`, `Integer[5, 20]`},
+			{`Integer[0, 4]`, `Integer[5, 20]`},
+			{`Enum[a, b, c]`, `Variant[Enum[b, c, d], Pattern[/x/]]`},
+			{`String[1, 10]`, `String[5, 20]`},
+			{`Optional[Integer]`, `Variant[String, Undef]`},
+			{`NotUndef`, `Optional[String]`},
+			{`Array[Integer, 1, 5]`, `Array[Integer[0], 3]`},
+			{`Array[Integer]`, `Array[String]`},
+			{`Tuple[Integer, String, 1, 3]`, `Array[Integer]`},
+			{`Struct[{a => Integer, Optional[b] => String, Optional[c] => Float}]`, `Struct[{a => Integer[0], Optional[b] => Enum[x, y]}]`},
+			{`Struct[{a => Integer, Optional[b] => String}]`, `Hash[Enum[a], Integer]`},
+			{`Hash[String, Data]`, `Collection[2, 4]`},
+			{`Scalar`, `Data`},
+			{`Timestamp['2020-01-01T00:00:00 UTC', '2021-01-01T00:00:00 UTC']`, `Timestamp['2020-07-01T00:00:00 UTC']`},
+			{`Timespan['0-01:00:00', '2-00:00:00']`, `Timespan['1-00:00:00', '3-00:00:00']`},
+			{`Numeric`, `String`},
+			{`Callable[Integer]`, `Callable[String]`},
+			{`Iterable`, `Integer`},
+			{`Integer`, `Iterable`},
+			{`SemVer`, `String`},
+			{`Binary`, `ScalarData`},
+		} {
+			fmt.Println(px.Intersect(c.ParseType(pair[0]), c.ParseType(pair[1])))
+		}
+		fmt.Println(px.Intersect(
+			types.NewSemVerType(semver.MustParseVersionRange(`1.x`)), types.NewSemVerType(semver.MustParseVersionRange(`>=1.5.0`))))
+	})
+	// Output:
+	// Integer[5, 10]
+	// Unit
+	// Enum['b', 'c']
+	// String[5, 10]
+	// Undef
+	// String
+	// Array[Integer[0], 3, 5]
+	// Array[0, 0]
+	// Tuple[Integer]
+	// Struct[{'a' => Integer[0], Optional['b'] => Enum['x', 'y']}]
+	// Struct[{'a' => Integer}]
+	// Hash[String, Data, 2, 4]
+	// ScalarData
+	// Timestamp['2020-07-01 00:00:00 +0000 UTC', '2021-01-01 00:00:00 +0000 UTC']
+	// Timespan['24h0m0s', '48h0m0s']
+	// Unit
+	// Callable[Integer]
+	// Integer
+	// Integer
+	// Unit
+	// Unit
+	// SemVer['1.x']
+}
+
+func ExampleSubtract() {
+	pcore.Do(func(c px.Context) {
+		for _, pair := range [][2]string{
+			{`Integer[0, 10]`, `Integer[3, 5]`},
+			{`Integer[0, 10]`, `Integer[5]`},
+			{`Integer[0, 10]`, `Numeric`},
+			{`Float[0.0, 1.0]`, `Float[0.5]`},
+			{`Enum[a, b, c]`, `Pattern[/b/]`},
+			{`String[0, 10]`, `String[0, 0]`},
+			{`String`, `String[0, 0]`},
+			{`Optional[String]`, `Undef`},
+			{`Optional[Integer]`, `Integer`},
+			{`Variant[Integer, String, Boolean]`, `Variant[String, Boolean]`},
+			{`Array[String]`, `Array[String, 0, 0]`},
+			{`Data`, `Variant[Scalar, Undef]`},
+		} {
+			fmt.Println(px.Subtract(c.ParseType(pair[0]), c.ParseType(pair[1])))
+		}
+	})
+	// Output:
+	// Variant[Integer[0, 2], Integer[6, 10]]
+	// Integer[0, 4]
+	// Unit
+	// Float[0.00000, 0.49999999999999994]
+	// Enum['a', 'c']
+	// String[1, 10]
+	// String[1]
+	// String
+	// Undef
+	// Integer
+	// Array[String, 1, default]
+	// Variant[Array[Data], Hash[String, Data]]
+}
+
+func ExampleDescribeMismatch2() {
+	pcore.Do(func(c px.Context) {
+		t := c.ParseType(`Struct[{name => String, ports => Array[Integer]}]`)
+		fmt.Println(px.DescribeMismatch2(`server`, t, px.DetailedValueType(types.Parse(`{ports => [80, http]}`))))
+	})
+	// Output:
+	// server expects a value for key 'name'
+	// server entry 'ports' index '1' expects an Integer value, got String
+}
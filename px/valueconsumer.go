@@ -39,3 +39,13 @@ type ValueConsumer interface {
 	// Add a reference to a previously added afterElement, hash, or array.
 	AddRef(ref int)
 }
+
+// A TimeValueConsumer is a ValueConsumer that might be able to handle Timestamp and Timespan
+// values natively.
+type TimeValueConsumer interface {
+	ValueConsumer
+
+	// CanDoTime returns true if the value can handle Timestamp and Timespan values efficiently.
+	// This tells the Serializer to pass such values verbatim to Add
+	CanDoTime() bool
+}
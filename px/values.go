@@ -3,6 +3,7 @@ package px
 import (
 	"bytes"
 	"fmt"
+	"hash"
 	"io"
 	"regexp"
 )
@@ -224,6 +225,16 @@ var IsTruthy func(tv Value) bool
 var ToInt func(v Value) (int64, bool)
 var ToFloat func(v Value) (float64, bool)
 
+// Digest computes a digest of the given value using a deterministic binary encoding of the value that is
+// written to a hash created by the given algorithm. The algorithm defaults to sha256.New when nil.
+//
+// Unless unordered is true, the order of the entries in a Hash is significant, so two Hashes that are equal
+// but have their entries in different order produce different digests. Values that are equal produce equal
+// digests when unordered is true. Types are digested using their expanded string representation and
+// objects using their type and InitHash. A recursive value is digested with each recursion replaced by
+// a reference to the enclosing array, hash, or object.
+var Digest func(value Value, algorithm func() hash.Hash, unordered bool) []byte
+
 // StringElements returns a slice containing each element in the given list as a string
 func StringElements(l List) []string {
 	ss := make([]string, l.Len())
@@ -0,0 +1,73 @@
+package px_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+func ExampleDigest() {
+	pcore.Do(func(c px.Context) {
+		a := px.Wrap(c, []interface{}{1, `two`, map[string]interface{}{`x`: 3}})
+		b := px.Wrap(c, []interface{}{1, `two`, map[string]interface{}{`x`: 3}})
+		fmt.Println(bytes.Equal(px.Digest(a, nil, false), px.Digest(b, nil, false)))
+		fmt.Println(len(px.Digest(a, nil, false)), len(px.Digest(a, sha1.New, false)))
+		fmt.Println(bytes.Equal(px.Digest(a, nil, false), px.Digest(types.WrapSensitive(a), nil, false)))
+	})
+	// Output:
+	// true
+	// 32 20
+	// false
+}
+
+func ExampleDigest_unordered() {
+	pcore.Do(func(c px.Context) {
+		a := types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(`a`, types.WrapInteger(1)), types.WrapHashEntry2(`b`, types.WrapInteger(2))})
+		b := types.WrapHash([]*types.HashEntry{types.WrapHashEntry2(`b`, types.WrapInteger(2)), types.WrapHashEntry2(`a`, types.WrapInteger(1))})
+		fmt.Println(bytes.Equal(px.Digest(a, nil, false), px.Digest(b, nil, false)))
+		fmt.Println(bytes.Equal(px.Digest(a, nil, true), px.Digest(b, nil, true)))
+	})
+	// Output:
+	// false
+	// true
+}
+
+func ExampleDigest_object() {
+	pcore.Do(func(c px.Context) {
+		pt := px.NewObjectType(`Point`, `{attributes => {x => Integer, y => Integer}}`)
+		px.AddTypes(c, pt)
+		p1 := px.New(c, pt, types.WrapInteger(1), types.WrapInteger(2))
+		p2 := px.New(c, pt, types.WrapInteger(1), types.WrapInteger(2))
+		p3 := px.New(c, pt, types.WrapInteger(2), types.WrapInteger(1))
+		fmt.Println(bytes.Equal(px.Digest(p1, nil, false), px.Digest(p2, nil, false)))
+		fmt.Println(bytes.Equal(px.Digest(p1, nil, false), px.Digest(p3, nil, false)))
+		fmt.Println(bytes.Equal(px.Digest(pt, nil, false), px.Digest(p1.PType(), nil, false)))
+	})
+	// Output:
+	// true
+	// false
+	// true
+}
+
+func ExampleDigest_namedTypes() {
+	pcore.Do(func(c px.Context) {
+		p1 := c.ParseType(`Object[{name => 'Point', attributes => {x => Integer}}]`)
+		p2 := c.ParseType(`Object[{name => 'Point', attributes => {x => Integer, y => Integer}}]`)
+		fmt.Println(bytes.Equal(px.Digest(p1, nil, false), px.Digest(p2, nil, false)))
+	})
+	// Output: false
+}
+
+func ExampleDigest_recursive() {
+	selfRef := func() px.Value {
+		return types.BuildArray(2, func(a *types.Array, es []px.Value) []px.Value {
+			return append(es, types.WrapString(`self`), a)
+		})
+	}
+	fmt.Println(bytes.Equal(px.Digest(selfRef(), nil, false), px.Digest(selfRef(), nil, false)))
+	// Output: true
+}
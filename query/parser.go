@@ -0,0 +1,292 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+type parser struct {
+	expr string
+	pos  int
+}
+
+func (p *parser) parseQuery() []*segment {
+	p.skipWhite()
+	if p.peek() == '$' {
+		p.pos++
+	}
+	ss := p.parseSegments()
+	p.skipWhite()
+	if p.pos < len(p.expr) {
+		panic(p.error(fmt.Sprintf(`unexpected '%c'`, p.peek())))
+	}
+	return ss
+}
+
+// parseSegments parses segments until a character that cannot start a segment is found
+func (p *parser) parseSegments() []*segment {
+	ss := make([]*segment, 0)
+	for {
+		p.skipWhite()
+		switch p.peek() {
+		case '.':
+			p.pos++
+			if p.peek() == '.' {
+				p.pos++
+				if p.peek() == '[' {
+					ss = append(ss, &segment{descendant: true, selectors: p.parseBracket()})
+				} else {
+					ss = append(ss, &segment{descendant: true, selectors: []selector{p.parseDotSelector()}})
+				}
+			} else {
+				ss = append(ss, &segment{selectors: []selector{p.parseDotSelector()}})
+			}
+		case '[':
+			ss = append(ss, &segment{selectors: p.parseBracket()})
+		default:
+			return ss
+		}
+	}
+}
+
+func (p *parser) parseDotSelector() selector {
+	if p.peek() == '*' {
+		p.pos++
+		return wildcardSelector{}
+	}
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := rune(p.expr[p.pos])
+		if !(c == '_' || c == '-' || c >= 0x80 || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			break
+		}
+		p.pos++
+	}
+	if start == p.pos {
+		panic(p.error(`expected a name or '*'`))
+	}
+	return nameSelector(p.expr[start:p.pos])
+}
+
+// parseBracket parses a comma separated list of selectors within brackets
+func (p *parser) parseBracket() []selector {
+	p.expect('[')
+	var sels []selector
+	for {
+		p.skipWhite()
+		sels = append(sels, p.parseBracketSelector())
+		p.skipWhite()
+		if p.peek() == ']' {
+			p.pos++
+			return sels
+		}
+		p.expect(',')
+	}
+}
+
+func (p *parser) parseBracketSelector() selector {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}
+	case c == '\'' || c == '"':
+		return nameSelector(p.parseString())
+	case c == '?':
+		p.pos++
+		return &filterSelector{p.parseOr()}
+	case c == '-' || c == ':' || c >= '0' && c <= '9':
+		return p.parseIndexOrSlice()
+	}
+	panic(p.error(`expected a selector`))
+}
+
+func (p *parser) parseIndexOrSlice() selector {
+	s := &sliceSelector{step: 1}
+	s.start, s.hasStart = p.parseOptionalInt()
+	p.skipWhite()
+	if p.peek() != ':' {
+		if !s.hasStart {
+			panic(p.error(`expected an integer`))
+		}
+		return indexSelector(s.start)
+	}
+	p.pos++
+	p.skipWhite()
+	s.end, s.hasEnd = p.parseOptionalInt()
+	p.skipWhite()
+	if p.peek() == ':' {
+		p.pos++
+		p.skipWhite()
+		if step, ok := p.parseOptionalInt(); ok {
+			s.step = step
+		}
+	}
+	return s
+}
+
+func (p *parser) parseOptionalInt() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+		panic(p.error(`expected an integer`))
+	}
+	return n, true
+}
+
+// parseString parses a single or double quoted string where a backslash escapes the next character
+func (p *parser) parseString() string {
+	q := p.expr[p.pos]
+	p.pos++
+	b := strings.Builder{}
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		p.pos++
+		switch c {
+		case q:
+			return b.String()
+		case '\\':
+			if p.pos < len(p.expr) {
+				b.WriteByte(p.expr[p.pos])
+				p.pos++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	panic(p.error(`unterminated string`))
+}
+
+func (p *parser) parseOr() expression {
+	e := p.parseAnd()
+	for p.skipWhite(); strings.HasPrefix(p.expr[p.pos:], `||`); p.skipWhite() {
+		p.pos += 2
+		e = &orExpression{e, p.parseAnd()}
+	}
+	return e
+}
+
+func (p *parser) parseAnd() expression {
+	e := p.parseUnary()
+	for p.skipWhite(); strings.HasPrefix(p.expr[p.pos:], `&&`); p.skipWhite() {
+		p.pos += 2
+		e = &andExpression{e, p.parseUnary()}
+	}
+	return e
+}
+
+func (p *parser) parseUnary() expression {
+	p.skipWhite()
+	switch p.peek() {
+	case '!':
+		if !strings.HasPrefix(p.expr[p.pos:], `!=`) {
+			p.pos++
+			return &notExpression{p.parseUnary()}
+		}
+	case '(':
+		p.pos++
+		e := p.parseOr()
+		p.skipWhite()
+		p.expect(')')
+		return e
+	}
+	start := p.pos
+	lhs := p.parseOperand()
+	p.skipWhite()
+	for _, op := range []string{`==`, `!=`, `<=`, `>=`, `<`, `>`} {
+		if strings.HasPrefix(p.expr[p.pos:], op) {
+			p.pos += len(op)
+			p.skipWhite()
+			return &comparison{op, lhs, p.parseOperand()}
+		}
+	}
+	if lhs.segments == nil {
+		p.pos = start
+		panic(p.error(`expected a query or a comparison`))
+	}
+	return &existsExpression{lhs}
+}
+
+func (p *parser) parseOperand() *operand {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		return &operand{relative: c == '@', segments: p.parseSegments()}
+	case c == '\'' || c == '"':
+		return &operand{literal: types.WrapString(p.parseString())}
+	case c == '-' || c >= '0' && c <= '9':
+		return &operand{literal: p.parseNumber()}
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && unicode.IsLetter(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	switch p.expr[start:p.pos] {
+	case `true`:
+		return &operand{literal: types.BooleanTrue}
+	case `false`:
+		return &operand{literal: types.BooleanFalse}
+	case `null`, `undef`:
+		return &operand{literal: px.Undef}
+	}
+	p.pos = start
+	panic(p.error(`expected a query or a literal`))
+}
+
+func (p *parser) parseNumber() px.Value {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.expr) && strings.IndexByte(`0123456789.eE+-`, p.expr[p.pos]) >= 0 {
+		p.pos++
+	}
+	s := p.expr[start:p.pos]
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return types.WrapInteger(n)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return types.WrapFloat(f)
+	}
+	p.pos = start
+	panic(p.error(`expected a number`))
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) expect(c byte) {
+	if p.peek() != c {
+		panic(p.error(fmt.Sprintf(`expected '%c'`, c)))
+	}
+	p.pos++
+}
+
+func (p *parser) skipWhite() {
+	for p.pos < len(p.expr) && (p.expr[p.pos] == ' ' || p.expr[p.pos] == '\t' || p.expr[p.pos] == '\n' || p.expr[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *parser) error(detail string) issue.Reported {
+	return px.Error(px.InvalidQuery, issue.H{`query`: p.expr, `pos`: p.pos, `detail`: detail})
+}
@@ -0,0 +1,402 @@
+// Package query finds values in nested hashes, arrays, and Objects using JSONPath-like expressions.
+//
+// A query starts with an optional $ that denotes the root value and continues with a sequence of segments:
+//
+//	.name or ['name']      the value of a hash key or an Object attribute
+//	[3] or [-1]            an array element, counted from the end when negative
+//	[1:5:2]                a slice of an array with an optional start, end, and step
+//	.* or [*]              all elements, hash values, or attribute values
+//	[0, 'a', 2:4]          the union of several selectors
+//	..name or ..[*]        like the segment without the extra dot but applied to the value and all its descendants
+//	[?(@.price < 10)]      the elements, hash values, or attribute values for which the filter is true
+//
+// A filter compares a query relative to the current value (@) or to the root value ($) with another query or
+// with a literal integer, float, string, boolean, or undef (also written as null) using ==, !=, <, <=, >, or
+// >=. A query without a comparison tests if the query matches anything. Tests are combined using &&, ||, !,
+// and parentheses.
+package query
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// A Query is a compiled query expression
+type Query struct {
+	expr     string
+	segments []*segment
+}
+
+// A Match is a value found by a query together with the path to the value. The elements of the path are
+// hash keys, array indexes, and Object attribute names.
+type Match struct {
+	Path  []px.Value
+	Value px.Value
+}
+
+// String returns the path of the match in the normalized JSONPath form, e.g. $['store']['book'][0]
+func (m *Match) String() string {
+	b := bytes.NewBufferString(`$`)
+	for _, k := range m.Path {
+		b.WriteByte('[')
+		if s, ok := k.(px.StringValue); ok {
+			utils.PuppetQuote(b, s.String())
+		} else {
+			b.WriteString(k.String())
+		}
+		b.WriteByte(']')
+	}
+	return b.String()
+}
+
+func (m *Match) child(key, value px.Value) *Match {
+	path := make([]px.Value, len(m.Path)+1)
+	copy(path, m.Path)
+	path[len(m.Path)] = key
+	return &Match{Path: path, Value: value}
+}
+
+// Compile parses the given query expression. A px.Error with the InvalidQuery issue code is raised when the
+// expression is invalid.
+func Compile(expr string) *Query {
+	p := &parser{expr: expr}
+	return &Query{expr: expr, segments: p.parseQuery()}
+}
+
+// Find is a shorthand for Compile(expr).Find(value)
+func Find(expr string, value px.Value) []*Match {
+	return Compile(expr).Find(value)
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Find returns the matches of the receiver in the given value in document order
+func (q *Query) Find(value px.Value) []*Match {
+	return find(q.segments, value, &Match{Path: []px.Value{}, Value: value})
+}
+
+// Values returns the values of the matches of the receiver in the given value
+func (q *Query) Values(value px.Value) []px.Value {
+	ms := q.Find(value)
+	vs := make([]px.Value, len(ms))
+	for i, m := range ms {
+		vs[i] = m.Value
+	}
+	return vs
+}
+
+func find(segments []*segment, root px.Value, start *Match) []*Match {
+	ms := []*Match{start}
+	for _, s := range segments {
+		var next []*Match
+		emit := func(m *Match) { next = append(next, m) }
+		for _, m := range ms {
+			if s.descendant {
+				descendants(m, func(d *Match) { s.apply(root, d, emit) })
+			} else {
+				s.apply(root, m, emit)
+			}
+		}
+		ms = next
+	}
+	return ms
+}
+
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+func (s *segment) apply(root px.Value, m *Match, emit func(*Match)) {
+	for _, sel := range s.selectors {
+		sel.apply(root, m, emit)
+	}
+}
+
+type selector interface {
+	apply(root px.Value, m *Match, emit func(*Match))
+}
+
+type nameSelector string
+
+func (s nameSelector) apply(root px.Value, m *Match, emit func(*Match)) {
+	switch v := m.Value.(type) {
+	case *types.Hash:
+		k := types.WrapString(string(s))
+		if e, ok := v.Get(k); ok {
+			emit(m.child(k, e))
+		}
+	case px.Type:
+	case px.PuppetObject:
+		if ot, ok := v.PType().(px.ObjectType); ok {
+			if ai := ot.AttributesInfo(); ai != nil {
+				if i, ok := ai.NameToPos()[string(s)]; ok {
+					emit(m.child(types.WrapString(string(s)), ai.Attributes()[i].Get(v)))
+				}
+			}
+		}
+	}
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) apply(root px.Value, m *Match, emit func(*Match)) {
+	children(m, emit)
+}
+
+// indexSelector selects an array element or the value of an integer hash key
+type indexSelector int
+
+func (s indexSelector) apply(root px.Value, m *Match, emit func(*Match)) {
+	switch v := m.Value.(type) {
+	case *types.Array:
+		i := int(s)
+		if i < 0 {
+			i += v.Len()
+		}
+		if i >= 0 && i < v.Len() {
+			emit(m.child(types.WrapInteger(int64(i)), v.At(i)))
+		}
+	case *types.Hash:
+		k := types.WrapInteger(int64(s))
+		if e, ok := v.Get(k); ok {
+			emit(m.child(k, e))
+		}
+	}
+}
+
+type sliceSelector struct {
+	start, end       int
+	hasStart, hasEnd bool
+	step             int
+}
+
+func (s *sliceSelector) apply(root px.Value, m *Match, emit func(*Match)) {
+	a, ok := m.Value.(*types.Array)
+	if !ok || s.step == 0 {
+		return
+	}
+	n := a.Len()
+	bound := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	if s.step > 0 {
+		lo, hi := 0, n
+		if s.hasStart {
+			lo = clamp(bound(s.start), 0, n)
+		}
+		if s.hasEnd {
+			hi = clamp(bound(s.end), 0, n)
+		}
+		for i := lo; i < hi; i += s.step {
+			emit(m.child(types.WrapInteger(int64(i)), a.At(i)))
+		}
+	} else {
+		hi, lo := n-1, -1
+		if s.hasStart {
+			hi = clamp(bound(s.start), -1, n-1)
+		}
+		if s.hasEnd {
+			lo = clamp(bound(s.end), -1, n-1)
+		}
+		for i := hi; i > lo; i += s.step {
+			emit(m.child(types.WrapInteger(int64(i)), a.At(i)))
+		}
+	}
+}
+
+type filterSelector struct {
+	expr expression
+}
+
+func (s *filterSelector) apply(root px.Value, m *Match, emit func(*Match)) {
+	children(m, func(c *Match) {
+		if s.expr.test(root, c.Value) {
+			emit(c)
+		}
+	})
+}
+
+// children emits the elements of an array, the values of a hash, and the attribute values of an Object
+func children(m *Match, emit func(*Match)) {
+	switch v := m.Value.(type) {
+	case *types.Array:
+		v.EachWithIndex(func(e px.Value, i int) { emit(m.child(types.WrapInteger(int64(i)), e)) })
+	case *types.Hash:
+		v.EachPair(func(k, e px.Value) { emit(m.child(k, e)) })
+	case px.Type:
+	case px.PuppetObject:
+		if ot, ok := v.PType().(px.ObjectType); ok {
+			if ai := ot.AttributesInfo(); ai != nil {
+				for _, a := range ai.Attributes() {
+					emit(m.child(types.WrapString(a.Name()), a.Get(v)))
+				}
+			}
+		}
+	}
+}
+
+// descendants emits the given match followed by all its descendants in document order
+func descendants(m *Match, emit func(*Match)) {
+	emit(m)
+	children(m, func(c *Match) { descendants(c, emit) })
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+type expression interface {
+	test(root, current px.Value) bool
+}
+
+type orExpression struct{ lhs, rhs expression }
+
+func (e *orExpression) test(root, current px.Value) bool {
+	return e.lhs.test(root, current) || e.rhs.test(root, current)
+}
+
+type andExpression struct{ lhs, rhs expression }
+
+func (e *andExpression) test(root, current px.Value) bool {
+	return e.lhs.test(root, current) && e.rhs.test(root, current)
+}
+
+type notExpression struct{ expr expression }
+
+func (e *notExpression) test(root, current px.Value) bool {
+	return !e.expr.test(root, current)
+}
+
+// operand is a literal or a query in a filter
+type operand struct {
+	literal  px.Value
+	relative bool
+	segments []*segment
+}
+
+// value returns the literal or the value of the first match of the query
+func (o *operand) value(root, current px.Value) (px.Value, bool) {
+	if o.segments == nil {
+		return o.literal, true
+	}
+	start := root
+	if o.relative {
+		start = current
+	}
+	ms := find(o.segments, root, &Match{Value: start})
+	if len(ms) == 0 {
+		return nil, false
+	}
+	return ms[0].Value, true
+}
+
+// existsExpression is true when the query of the operand matches anything
+type existsExpression struct{ query *operand }
+
+func (e *existsExpression) test(root, current px.Value) bool {
+	_, ok := e.query.value(root, current)
+	return ok
+}
+
+type comparison struct {
+	op       string
+	lhs, rhs *operand
+}
+
+func (e *comparison) test(root, current px.Value) bool {
+	a, aok := e.lhs.value(root, current)
+	b, bok := e.rhs.value(root, current)
+	switch e.op {
+	case `==`:
+		return equal(a, aok, b, bok)
+	case `!=`:
+		return !equal(a, aok, b, bok)
+	}
+	if !(aok && bok) {
+		return false
+	}
+	c, ok := compare(a, b)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case `<`:
+		return c < 0
+	case `<=`:
+		return c <= 0
+	case `>`:
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// equal returns true if both values are missing or if both are present and equal. Integers and floats are
+// compared by value.
+func equal(a px.Value, aok bool, b px.Value, bok bool) bool {
+	if !(aok && bok) {
+		return aok == bok
+	}
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return a.Equals(b, nil)
+}
+
+// compare compares two numbers or two strings. The second return value is false for other values.
+func compare(a, b px.Value) (int, bool) {
+	switch a := a.(type) {
+	case px.Integer:
+		switch b := b.(type) {
+		case px.Integer:
+			return compareInts(a.Int(), b.Int()), true
+		case px.Float:
+			return compareFloats(a.Float(), b.Float()), true
+		}
+	case px.Float:
+		if b, ok := b.(px.Number); ok {
+			return compareFloats(a.Float(), b.Float()), true
+		}
+	case px.StringValue:
+		if b, ok := b.(px.StringValue); ok {
+			return strings.Compare(a.String(), b.String()), true
+		}
+	}
+	return 0, false
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
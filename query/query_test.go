@@ -0,0 +1,95 @@
+package query_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/query"
+	"github.com/lyraproj/pcore/types"
+)
+
+const store = `{
+  store => {
+    book => [
+      { category => reference, author => 'Nigel Rees', title => 'Sayings of the Century', price => 8.95 },
+      { category => fiction, author => 'Evelyn Waugh', title => 'Sword of Honour', price => 12.99 },
+      { category => fiction, author => 'Herman Melville', title => 'Moby Dick', isbn => '0-553-21311-3', price => 8.99 },
+      { category => fiction, author => 'J. R. R. Tolkien', title => 'The Lord of the Rings', isbn => '0-395-19395-8', price => 22.99 }
+    ],
+    bicycle => { color => red, price => 19 }
+  }
+}`
+
+func ExampleFind() {
+	doc := types.Parse(store)
+	for _, q := range []string{
+		`$.store.book[*].author`,
+		`$..price`,
+		`$.store.book[-1:]`,
+		`$.store.book[0, 2].title`,
+		`$..book[?(@.isbn)].title`,
+		`$..book[?(@.price < 10 && @.category == 'fiction')].title`,
+		`$.store.*.color`,
+		`$.store['bicycle']`,
+	} {
+		fmt.Println(q)
+		for _, m := range query.Find(q, doc) {
+			fmt.Println(` `, m, `=>`, m.Value)
+		}
+	}
+	// Output:
+	// $.store.book[*].author
+	//   $['store']['book'][0]['author'] => Nigel Rees
+	//   $['store']['book'][1]['author'] => Evelyn Waugh
+	//   $['store']['book'][2]['author'] => Herman Melville
+	//   $['store']['book'][3]['author'] => J. R. R. Tolkien
+	// $..price
+	//   $['store']['book'][0]['price'] => 8.95
+	//   $['store']['book'][1]['price'] => 12.99
+	//   $['store']['book'][2]['price'] => 8.99
+	//   $['store']['book'][3]['price'] => 22.99
+	//   $['store']['bicycle']['price'] => 19
+	// $.store.book[-1:]
+	//   $['store']['book'][3] => {'category' => 'fiction', 'author' => 'J. R. R. Tolkien', 'title' => 'The Lord of the Rings', 'isbn' => '0-395-19395-8', 'price' => 22.9900}
+	// $.store.book[0, 2].title
+	//   $['store']['book'][0]['title'] => Sayings of the Century
+	//   $['store']['book'][2]['title'] => Moby Dick
+	// $..book[?(@.isbn)].title
+	//   $['store']['book'][2]['title'] => Moby Dick
+	//   $['store']['book'][3]['title'] => The Lord of the Rings
+	// $..book[?(@.price < 10 && @.category == 'fiction')].title
+	//   $['store']['book'][2]['title'] => Moby Dick
+	// $.store.*.color
+	//   $['store']['bicycle']['color'] => red
+	// $.store['bicycle']
+	//   $['store']['bicycle'] => {'color' => 'red', 'price' => 19}
+}
+
+func ExampleQuery_Values() {
+	pcore.Do(func(c px.Context) {
+		st := px.NewObjectType(`My::Server`, `{
+      attributes => {
+        host => String,
+        port => { type => Integer, value => 80 }
+      }
+    }`)
+		px.AddTypes(c, st)
+		servers := types.WrapValues([]px.Value{
+			px.New(c, st, types.Parse(`{host => 'a.example.com'}`)),
+			px.New(c, st, types.Parse(`{host => 'b.example.com', port => 8080}`)),
+		})
+		fmt.Println(query.Compile(`[?(@.port != 80)].host`).Values(servers))
+		fmt.Println(query.Compile(`[::-1].port`).Values(servers))
+	})
+	// Output:
+	// [b.example.com]
+	// [8080 80]
+}
+
+func ExampleCompile_error() {
+	r := testutil.Reported(func() { query.Compile(`$.store.book[?(@.price <)]`) })
+	fmt.Println(r.Code(), r.Argument(`pos`), r.Argument(`detail`))
+	// Output: PCORE_INVALID_QUERY 24 expected a query or a literal
+}
@@ -0,0 +1,153 @@
+package serialization
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// CBOR major types
+const (
+	cborUnsigned = 0 << 5
+	cborNegative = 1 << 5
+	cborBytes    = 2 << 5
+	cborText     = 3 << 5
+	cborArray    = 4 << 5
+	cborMap      = 5 << 5
+	cborTag      = 6 << 5
+	cborSimple   = 7 << 5
+)
+
+// Special CBOR bytes
+const (
+	cborFalse      = cborSimple | 20
+	cborTrue       = cborSimple | 21
+	cborNull       = cborSimple | 22
+	cborUndefined  = cborSimple | 23
+	cborFloat16    = cborSimple | 25
+	cborFloat32    = cborSimple | 26
+	cborFloat64    = cborSimple | 27
+	cborBreak      = cborSimple | 31
+	cborIndefinite = 31
+)
+
+// CBOR tags with special meaning
+const (
+	// CborTagDateTime is the tag for an RFC 3339 date/time string
+	CborTagDateTime = 0
+
+	// CborTagEpochDateTime is the tag for a numeric date/time relative to the epoch
+	CborTagEpochDateTime = 1
+
+	// CborTagSharedRef is the tag used for references to previously streamed values. The tagged
+	// value is the reference index as an unsigned integer
+	CborTagSharedRef = 29
+)
+
+// NewCborStreamer creates a new streamer that will produce CBOR (RFC 7049) when
+// receiving values.
+//
+// Arrays and hashes are always written using indefinite length. References are
+// written as the unsigned reference index tagged with CborTagSharedRef.
+func NewCborStreamer(out io.Writer) px.ValueConsumer {
+	return &cborStreamer{out: out, buf: make([]byte, 9)}
+}
+
+type cborStreamer struct {
+	out io.Writer
+	buf []byte
+}
+
+func (c *cborStreamer) AddArray(len int, doer px.Doer) {
+	c.writeByte(cborArray | cborIndefinite)
+	doer()
+	c.writeByte(cborBreak)
+}
+
+func (c *cborStreamer) AddHash(len int, doer px.Doer) {
+	c.writeByte(cborMap | cborIndefinite)
+	doer()
+	c.writeByte(cborBreak)
+}
+
+func (c *cborStreamer) Add(element px.Value) {
+	switch e := element.(type) {
+	case px.StringValue:
+		s := e.String()
+		c.writeHead(cborText, uint64(len(s)))
+		assertOk(io.WriteString(c.out, s))
+	case px.Integer:
+		i := e.Int()
+		if i < 0 {
+			c.writeHead(cborNegative, uint64(-1-i))
+		} else {
+			c.writeHead(cborUnsigned, uint64(i))
+		}
+	case px.Float:
+		c.buf[0] = cborFloat64
+		binary.BigEndian.PutUint64(c.buf[1:], math.Float64bits(e.Float()))
+		assertOk(c.out.Write(c.buf))
+	case px.Boolean:
+		if e.Bool() {
+			c.writeByte(cborTrue)
+		} else {
+			c.writeByte(cborFalse)
+		}
+	case *types.Binary:
+		bs := e.Bytes()
+		c.writeHead(cborBytes, uint64(len(bs)))
+		assertOk(c.out.Write(bs))
+	default:
+		c.writeByte(cborNull)
+	}
+}
+
+func (c *cborStreamer) AddRef(ref int) {
+	c.writeHead(cborTag, CborTagSharedRef)
+	c.writeHead(cborUnsigned, uint64(ref))
+}
+
+func (c *cborStreamer) CanDoBinary() bool {
+	return true
+}
+
+func (c *cborStreamer) CanDoComplexKeys() bool {
+	return true
+}
+
+func (c *cborStreamer) StringDedupThreshold() int {
+	return 20
+}
+
+func (c *cborStreamer) writeByte(b byte) {
+	c.buf[0] = b
+	assertOk(c.out.Write(c.buf[:1]))
+}
+
+func (c *cborStreamer) writeHead(major byte, n uint64) {
+	b := c.buf
+	switch {
+	case n < 24:
+		b[0] = major | byte(n)
+		b = b[:1]
+	case n <= math.MaxUint8:
+		b[0] = major | 24
+		b[1] = byte(n)
+		b = b[:2]
+	case n <= math.MaxUint16:
+		b[0] = major | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		b = b[:3]
+	case n <= math.MaxUint32:
+		b[0] = major | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		b = b[:5]
+	default:
+		b[0] = major | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+	}
+	assertOk(c.out.Write(b))
+}
@@ -0,0 +1,333 @@
+package serialization
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// CborToData reads one CBOR (RFC 7049) data item from the given reader and streams
+// the values to the given ValueConsumer.
+//
+// Byte strings are streamed as Binary values, the tags CborTagDateTime and CborTagEpochDateTime
+// produce Timestamp values, and CborTagSharedRef produces a call to AddRef. Other tags are ignored
+// and only their content is streamed.
+func CborToData(in io.Reader, consumer px.ValueConsumer) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(issue.Reported); ok {
+				panic(r)
+			}
+			panic(px.Error(px.InvalidCbor, issue.H{`detail`: r}))
+		}
+	}()
+	br, ok := in.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(in)
+	}
+	cr := &cborReader{in: br, consumer: consumer, buf: make([]byte, 8)}
+	cr.limiter, _ = consumer.(sizeLimiter)
+	if cr.readItem() {
+		panic(fmt.Errorf("unexpected break"))
+	}
+}
+
+type cborReader struct {
+	in       *bufio.Reader
+	consumer px.ValueConsumer
+	limiter  sizeLimiter
+	buf      []byte
+}
+
+// A sizeLimiter is a ValueConsumer that limits the size of a String or Binary. The readers of
+// formats that declare the length of a string up front use it to check that length before the
+// bytes are read.
+type sizeLimiter interface {
+	assertSize(typeName string, size int)
+}
+
+// readItem reads and streams one data item. It returns true if a break was found instead
+// of an item
+func (cr *cborReader) readItem() bool {
+	ib := cr.readByte()
+	major := ib & 0xe0
+	info := ib & 0x1f
+	c := cr.consumer
+
+	if major == cborSimple {
+		switch ib {
+		case cborFalse:
+			c.Add(types.BooleanFalse)
+		case cborTrue:
+			c.Add(types.BooleanTrue)
+		case cborNull, cborUndefined:
+			c.Add(px.Undef)
+		case cborFloat16:
+			c.Add(types.WrapFloat(halfToFloat(uint16(cr.readUint(25)))))
+		case cborFloat32:
+			c.Add(types.WrapFloat(float64(math.Float32frombits(uint32(cr.readUint(26))))))
+		case cborFloat64:
+			c.Add(types.WrapFloat(math.Float64frombits(cr.readUint(27))))
+		case cborBreak:
+			return true
+		default:
+			panic(fmt.Errorf("unsupported simple value %d", info))
+		}
+		return false
+	}
+
+	if info == cborIndefinite {
+		switch major {
+		case cborBytes, cborText:
+			// Indefinite length strings are streamed as a sequence of definite length chunks
+			var bs []byte
+			for {
+				cb := cr.readByte()
+				if cb == cborBreak {
+					break
+				}
+				if cb&0xe0 != major {
+					panic(fmt.Errorf("illegal chunk type in indefinite length string"))
+				}
+				n := cr.readUint(cb & 0x1f)
+				cr.assertSize(major, uint64(len(bs))+n)
+				bs = append(bs, cr.readBytes(n)...)
+			}
+			cr.addString(major, bs)
+		case cborArray:
+			c.AddArray(8, func() {
+				for !cr.readItem() {
+				}
+			})
+		case cborMap:
+			c.AddHash(8, func() {
+				for !cr.readItem() {
+					cr.readValue()
+				}
+			})
+		default:
+			panic(fmt.Errorf("illegal indefinite length for major type %d", major>>5))
+		}
+		return false
+	}
+
+	n := cr.readUint(info)
+	switch major {
+	case cborUnsigned:
+		if n > math.MaxInt64 {
+			panic(fmt.Errorf("integer %d is out of range", n))
+		}
+		c.Add(types.WrapInteger(int64(n)))
+	case cborNegative:
+		if n > math.MaxInt64 {
+			panic(fmt.Errorf("integer -%d is out of range", n))
+		}
+		c.Add(types.WrapInteger(-1 - int64(n)))
+	case cborBytes, cborText:
+		cr.assertSize(major, n)
+		cr.addString(major, cr.readBytes(n))
+	case cborArray:
+		c.AddArray(capacityHint(n), func() {
+			for i := uint64(0); i < n; i++ {
+				cr.readValue()
+			}
+		})
+	case cborMap:
+		c.AddHash(capacityHint(n), func() {
+			for i := uint64(0); i < n; i++ {
+				cr.readValue()
+				cr.readValue()
+			}
+		})
+	default: // cborTag
+		cr.readTagged(n)
+	}
+	return false
+}
+
+// readValue reads and streams one data item. A break is not permitted.
+func (cr *cborReader) readValue() {
+	if cr.readItem() {
+		panic(fmt.Errorf("unexpected break"))
+	}
+}
+
+func (cr *cborReader) readTagged(tag uint64) {
+	switch tag {
+	case CborTagSharedRef:
+		ib := cr.readByte()
+		if ib&0xe0 != cborUnsigned {
+			panic(fmt.Errorf("shared reference must be an unsigned integer"))
+		}
+		cr.consumer.AddRef(int(cr.readUint(ib & 0x1f)))
+	case CborTagDateTime, CborTagEpochDateTime:
+		cl := types.NewCollector()
+		c := cr.consumer
+		cr.consumer = cl
+		cr.readValue()
+		cr.consumer = c
+		var t time.Time
+		switch v := cl.Value().(type) {
+		case px.StringValue:
+			if tag != CborTagDateTime {
+				panic(fmt.Errorf("epoch date/time must be numeric"))
+			}
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, v.String()); err != nil {
+				panic(err)
+			}
+		case px.Integer:
+			t = time.Unix(v.Int(), 0)
+		case px.Float:
+			s, f := math.Modf(v.Float())
+			t = time.Unix(int64(s), int64(f*1e9))
+		default:
+			panic(fmt.Errorf("illegal content for date/time tag %d", tag))
+		}
+		cr.consumer.Add(types.WrapTimestamp(t.UTC()))
+	default:
+		cr.readValue()
+	}
+}
+
+func (cr *cborReader) addString(major byte, bs []byte) {
+	if major == cborText {
+		cr.consumer.Add(types.WrapString(string(bs)))
+	} else {
+		cr.consumer.Add(types.WrapBinary(bs))
+	}
+}
+
+// assertSize asserts that the consumer accepts a byte or text string of the given size
+func (cr *cborReader) assertSize(major byte, n uint64) {
+	if cr.limiter == nil {
+		return
+	}
+	if n > math.MaxInt32 {
+		n = math.MaxInt32
+	}
+	if major == cborText {
+		cr.limiter.assertSize(`String`, int(n))
+	} else {
+		cr.limiter.assertSize(`Binary`, int(n))
+	}
+}
+
+func (cr *cborReader) readByte() byte {
+	b, err := cr.in.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(err)
+	}
+	return b
+}
+
+func (cr *cborReader) readBytes(n uint64) []byte {
+	if n > math.MaxInt32 {
+		panic(fmt.Errorf("string length %d is out of range", n))
+	}
+	return readFull(cr.in, int64(n))
+}
+
+// maxCapacityHint is the largest capacity hint that is derived from a length declared in the input
+const maxCapacityHint = 1024
+
+// readChunkSize is the largest number of bytes that is allocated up front when reading a string
+// with a length declared in the input
+const readChunkSize = 64 * 1024
+
+// capacityHint returns the given declared number of elements capped to maxCapacityHint. The declared
+// number is not trusted since it's read from the input before the elements are.
+func capacityHint(n uint64) int {
+	if n > maxCapacityHint {
+		return maxCapacityHint
+	}
+	return int(n)
+}
+
+// readFull reads exactly n bytes from the given reader. Large strings are read in chunks into a growing
+// buffer so that the memory used is bounded by the size of the input rather than by the declared length.
+func readFull(in io.Reader, n int64) []byte {
+	if n <= readChunkSize {
+		bs := make([]byte, int(n))
+		if _, err := io.ReadFull(in, bs); err != nil {
+			panic(err)
+		}
+		return bs
+	}
+	b := bytes.NewBuffer(make([]byte, 0, readChunkSize))
+	if _, err := io.CopyN(b, in, n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(err)
+	}
+	return b.Bytes()
+}
+
+// readUint reads the unsigned integer argument that is determined by the additional info
+// of an initial byte.
+func (cr *cborReader) readUint(info byte) uint64 {
+	var n int
+	switch {
+	case info < 24:
+		return uint64(info)
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		panic(fmt.Errorf("illegal additional information %d", info))
+	}
+	b := cr.buf[:n]
+	if _, err := io.ReadFull(cr.in, b); err != nil {
+		panic(err)
+	}
+	switch n {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b))
+	default:
+		return binary.BigEndian.Uint64(b)
+	}
+}
+
+// halfToFloat converts an IEEE 754 half precision float to a float64
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -10,24 +10,154 @@ import (
 
 type dsContext struct {
 	types.BasicCollector
+	dsLimits
 	allowUnresolved bool
 	context         px.Context
 	newTypes        []px.Type
 	value           px.Value
 	converted       map[px.Value]px.Value
+	cipher          SensitiveCipher
+	depth           int
+	elements        int
+}
+
+// dsLimits are the limits imposed on the input consumed by a Deserializer. A zero limit means unlimited.
+type dsLimits struct {
+	maxDepth     int
+	maxElements  int
+	maxSize      int
+	allowedTypes map[string]bool
 }
 
 // NewDeserializer creates a new Collector that consumes input and creates a RichData Value
+//
+// The option sensitive_cipher, a Runtime value that wraps a SensitiveCipher, must be given in order to
+// deserialize Sensitive values that were encrypted by a Serializer. An attempt to deserialize such a value
+// without a cipher, or with a cipher that cannot decrypt it, results in a px.UnableToDecryptSensitive error.
+//
+// A reference to a value that has not been read results in a px.InvalidReference error.
+//
+// The following options limit what input the Deserializer accepts, which is useful when the input
+// cannot be trusted. All limits default to zero, which means unlimited.
+//
+// max_depth: Integer. The maximum nesting depth of arrays and hashes. Exceeding it results in a
+// px.DepthLimitExceeded error.
+//
+// max_elements: Integer. The maximum total number of values, including hash keys and references.
+// Exceeding it results in a px.ElementLimitExceeded error.
+//
+// max_size: Integer. The maximum number of bytes in a String or Binary. Exceeding it results in a
+// px.SizeLimitExceeded error. CborToData and MsgpackToData check the size that is declared in the
+// input before the bytes are read.
+//
+// allowed_types: Array[String]. The names of the data types that may be instantiated from a hash
+// that contains a PcoreTypeKey. The PcoreTypeHash, PcoreTypeSensitive, and PcoreTypeDefault types
+// and the Binary type, which is how a Binary is represented in JSON, are always allowed. Any other
+// type results in a px.TypeNotAllowed error.
 func NewDeserializer(ctx px.Context, options px.OrderedMap) px.Collector {
 	ds := &dsContext{
 		context:         ctx,
 		newTypes:        make([]px.Type, 0, 11),
 		converted:       make(map[px.Value]px.Value, 11),
-		allowUnresolved: options.Get5(`allow_unresolved`, types.BooleanFalse).(px.Boolean).Bool()}
+		allowUnresolved: options.Get5(`allow_unresolved`, types.BooleanFalse).(px.Boolean).Bool(),
+		cipher:          sensitiveCipher(`NewDeserializer`, options),
+		dsLimits: dsLimits{
+			maxDepth:    int(options.Get5(`max_depth`, types.WrapInteger(0)).(px.Integer).Int()),
+			maxElements: int(options.Get5(`max_elements`, types.WrapInteger(0)).(px.Integer).Int()),
+			maxSize:     int(options.Get5(`max_size`, types.WrapInteger(0)).(px.Integer).Int())}}
+	if at, ok := options.Get4(`allowed_types`); ok {
+		ds.allowedTypes = make(map[string]bool)
+		at.(px.List).Each(func(n px.Value) { ds.allowedTypes[n.String()] = true })
+	}
 	ds.Init()
 	return ds
 }
 
+func (ds *dsContext) AddArray(cap int, doer px.Doer) {
+	ds.enter()
+	ds.BasicCollector.AddArray(ds.capHint(cap), doer)
+	ds.depth--
+}
+
+func (ds *dsContext) AddHash(cap int, doer px.Doer) {
+	ds.enter()
+	ds.BasicCollector.AddHash(ds.capHint(cap), doer)
+	ds.depth--
+}
+
+func (ds *dsContext) Add(element px.Value) {
+	ds.countElement()
+	switch e := element.(type) {
+	case px.StringValue:
+		ds.assertSize(`String`, len(e.String()))
+	case *types.Binary:
+		ds.assertSize(`Binary`, len(e.Bytes()))
+	}
+	ds.BasicCollector.Add(element)
+}
+
+func (ds *dsContext) AddRef(ref int) {
+	ds.countElement()
+	if ref < 0 || ref >= ds.ValueCount() {
+		panic(px.Error(px.InvalidReference, issue.H{`ref`: ref, `count`: ds.ValueCount()}))
+	}
+	ds.BasicCollector.AddRef(ref)
+}
+
+// enter counts a new array or hash and increments the current depth
+func (ds *dsContext) enter() {
+	ds.countElement()
+	ds.depth++
+	if ds.maxDepth > 0 && ds.depth > ds.maxDepth {
+		panic(px.Error(px.DepthLimitExceeded, issue.H{`max`: ds.maxDepth}))
+	}
+}
+
+func (ds *dsContext) countElement() {
+	ds.elements++
+	if ds.maxElements > 0 && ds.elements > ds.maxElements {
+		panic(px.Error(px.ElementLimitExceeded, issue.H{`max`: ds.maxElements}))
+	}
+}
+
+// capHint ensures that the capacity hint given by the input doesn't cause allocations beyond
+// what the element limit permits
+func (ds *dsContext) capHint(cap int) int {
+	if ds.maxElements > 0 {
+		if left := ds.maxElements - ds.elements; cap > left {
+			return left
+		}
+	}
+	return cap
+}
+
+// assertSize panics with a px.SizeLimitExceeded error if the given size of a String or Binary
+// exceeds the size limit
+func (ds *dsContext) assertSize(typeName string, size int) {
+	if ds.maxSize > 0 && size > ds.maxSize {
+		panic(px.Error(px.SizeLimitExceeded, issue.H{`type`: typeName, `size`: size, `max`: ds.maxSize}))
+	}
+}
+
+// isInputError answers whether the given recovered value is an error that the Deserializer raises for
+// input that it doesn't accept. Readers pass such errors on as they are instead of wrapping them.
+func isInputError(r interface{}) bool {
+	if ri, ok := r.(issue.Reported); ok {
+		switch ri.Code() {
+		case px.DepthLimitExceeded, px.ElementLimitExceeded, px.SizeLimitExceeded, px.TypeNotAllowed, px.InvalidReference:
+			return true
+		}
+	}
+	return false
+}
+
+// assertAllowed panics with a px.TypeNotAllowed error unless the given type is allowed
+func (ds *dsContext) assertAllowed(typ px.Type) {
+	if ds.allowedTypes != nil && typ.Name() != `Binary` && !ds.allowedTypes[typ.Name()] {
+		panic(px.Error(px.TypeNotAllowed, issue.H{`type`: typ.Name()}))
+	}
+}
+
 func (ds *dsContext) Value() px.Value {
 	if ds.value == nil {
 		ds.value = ds.convert(ds.BasicCollector.Value())
@@ -118,7 +248,12 @@ func (ds *dsContext) convertHash(hv px.OrderedMap) px.Value {
 }
 
 func (ds *dsContext) convertSensitive(hash px.OrderedMap) px.Value {
-	cv := types.WrapSensitive(ds.convert(hash.Get5(PcoreValueKey, px.Undef)))
+	var cv px.Value
+	if keyId, ok := hash.Get4(PcoreKeyIdKey); ok {
+		cv = types.WrapSensitive(ds.decryptSensitive(keyId.String(), ds.convert(hash.Get5(PcoreValueKey, px.Undef))))
+	} else {
+		cv = types.WrapSensitive(ds.convert(hash.Get5(PcoreValueKey, px.Undef)))
+	}
 	ds.converted[hash] = cv
 	return cv
 }
@@ -140,6 +275,7 @@ func (ds *dsContext) convertOther(hash px.OrderedMap, typeValue px.Value) px.Val
 			}
 			return hash
 		}
+		ds.assertAllowed(typ.(px.Type))
 		return ds.pcoreTypeHashToValue(typ.(px.Type), hash, value)
 	}
 	typ := ds.context.ParseTypeValue(typeValue)
@@ -149,6 +285,7 @@ func (ds *dsContext) convertOther(hash px.OrderedMap, typeValue px.Value) px.Val
 		}
 		return hash
 	}
+	ds.assertAllowed(typ)
 	return ds.pcoreTypeHashToValue(typ.(px.Type), hash, value)
 }
 
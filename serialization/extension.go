@@ -13,6 +13,10 @@ const (
 	// value is always an integer
 	PcoreRefKey = `__pref`
 
+	// PcoreKeyIdKey is the key used for the identifier of the key that was used when encrypting the
+	// contents of a Sensitive value. The encrypted contents are stored under the PcoreValueKey
+	PcoreKeyIdKey = `__pkey`
+
 	// PcoreTypeBinary is used for binaries serialized using base64
 	PcoreTypeBinary = `Binary`
 
@@ -24,4 +28,7 @@ const (
 
 	// PcoreTypeDefault is the type key used for Default
 	PcoreTypeDefault = `Default`
+
+	// PcoreSensitiveRedacted is the value written in place of the contents of a redacted Sensitive value
+	PcoreSensitiveRedacted = `[redacted]`
 )
@@ -0,0 +1,55 @@
+package serialization
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+// ReadJsonDocuments reads a stream of JSON documents from the given reader and calls the given consumer
+// once for each document with the deserialized value and the zero based index of the document. The
+// documents may be separated by whitespace, which makes this function suitable for reading newline
+// delimited JSON.
+//
+// Each document is deserialized using a new Deserializer created with the given options so references
+// are scoped to the document in which they appear. A px.InvalidJson error that is caused by malformed
+// input will include the index of the document and the byte offset in the stream where the error was
+// found.
+func ReadJsonDocuments(c px.Context, path string, in io.Reader, options px.OrderedMap, consumer px.IndexedConsumer) {
+	d := json.NewDecoder(in)
+	d.UseNumber()
+	for index := 0; ; index++ {
+		ds, ok := readJsonDocument(c, path, d, options, index)
+		if !ok {
+			return
+		}
+		consumer(ds.Value(), index)
+	}
+}
+
+func readJsonDocument(c px.Context, path string, d *json.Decoder, options px.OrderedMap, index int) (ds px.Collector, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if isInputError(r) {
+				panic(r)
+			}
+			panic(px.Error(px.InvalidJson, issue.H{
+				`path`: path, `detail`: fmt.Sprintf(`document %d, offset %d: %v`, index, d.InputOffset(), r)}))
+		}
+	}()
+	t, err := d.Token()
+	if err == io.EOF {
+		return nil, false
+	}
+	if err != nil {
+		panic(err)
+	}
+	ds = NewDeserializer(c, options)
+	if !jsonValue(ds, d, t) {
+		panic(fmt.Errorf("unexpected delimiter %v", t))
+	}
+	return ds, true
+}
@@ -1,9 +1,13 @@
 package serialization
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strings"
 
 	"github.com/lyraproj/pcore/pcore"
 
@@ -13,14 +17,45 @@ import (
 )
 
 // NewJsonStreamer creates a new streamer that will produce JSON when
-// receiving values
+// receiving values.
+//
+// A Float is always written with a fraction or an exponent so that it is read back as a Float, e.g.
+// 1.0 is written as 1.0 and not as 1. Earlier versions wrote integral Float values without a
+// fraction, which a reader then read as an Integer.
 func NewJsonStreamer(out io.Writer) px.ValueConsumer {
-	return &jsonStreamer{out, firstInArray}
+	return &jsonStreamer{out: out, state: firstInArray}
+}
+
+// NewJsonStreamer2 creates a new streamer that will produce JSON when receiving values.
+//
+// Valid options are:
+//
+// indent: Integer, default 0. When greater than zero, the output is pretty printed using the given
+// number of spaces for each indentation level.
+//
+// canonical: Boolean, default false. When true, the output is byte stable for equal input. Hash keys
+// are sorted (by Unicode code point), numbers are written in their shortest form, and references are
+// replaced with the values that they refer to. A canonical streamer buffers each top level value and
+// writes it once it is complete.
+//
+// Floats are written as described for NewJsonStreamer.
+func NewJsonStreamer2(out io.Writer, options px.OrderedMap) px.ValueConsumer {
+	indent := int(options.Get5(`indent`, types.WrapInteger(0)).(px.Integer).Int())
+	j := &jsonStreamer{out: out, state: firstInArray}
+	if indent > 0 {
+		j.indent = strings.Repeat(` `, indent)
+	}
+	if options.Get5(`canonical`, types.BooleanFalse).(px.Boolean).Bool() {
+		return &canonicalJsonStreamer{jsonStreamer: j, collector: types.NewCollector()}
+	}
+	return j
 }
 
 type jsonStreamer struct {
-	out   io.Writer
-	state int
+	out    io.Writer
+	state  int
+	indent string
+	level  int
 }
 
 // DataToJson streams the given value to a Json ValueConsumer using a
@@ -37,7 +72,12 @@ func (j *jsonStreamer) AddArray(len int, doer px.Doer) {
 	j.delimit(func() {
 		j.state = firstInArray
 		assertOk(j.out.Write([]byte{'['}))
+		j.level++
 		doer()
+		j.level--
+		if j.state != firstInArray {
+			j.indentLine()
+		}
 		assertOk(j.out.Write([]byte{']'}))
 	})
 }
@@ -46,7 +86,12 @@ func (j *jsonStreamer) AddHash(len int, doer px.Doer) {
 	j.delimit(func() {
 		assertOk(j.out.Write([]byte{'{'}))
 		j.state = firstInObject
+		j.level++
 		doer()
+		j.level--
+		if j.state != firstInObject {
+			j.indentLine()
+		}
 		assertOk(j.out.Write([]byte{'}'}))
 	})
 }
@@ -78,25 +123,49 @@ func (j *jsonStreamer) StringDedupThreshold() int {
 func (j *jsonStreamer) delimit(doer px.Doer) {
 	switch j.state {
 	case firstInArray:
+		j.newLine()
 		doer()
 		j.state = afterElement
 	case firstInObject:
+		j.newLine()
 		doer()
 		j.state = afterKey
 	case afterKey:
-		assertOk(j.out.Write([]byte{':'}))
+		if j.indent == `` {
+			assertOk(j.out.Write([]byte{':'}))
+		} else {
+			assertOk(j.out.Write([]byte{':', ' '}))
+		}
 		doer()
 		j.state = afterValue
 	case afterValue:
 		assertOk(j.out.Write([]byte{','}))
+		j.newLine()
 		doer()
 		j.state = afterKey
 	default: // Element
 		assertOk(j.out.Write([]byte{','}))
+		j.newLine()
 		doer()
 	}
 }
 
+// newLine writes a newline followed by indentation when the streamer is pretty printing and
+// the value to write is an element in an array or hash
+func (j *jsonStreamer) newLine() {
+	if j.level > 0 {
+		j.indentLine()
+	}
+}
+
+// indentLine writes a newline followed by indentation for the current level when the streamer
+// is pretty printing
+func (j *jsonStreamer) indentLine() {
+	if j.indent != `` {
+		assertOk(io.WriteString(j.out, "\n"+strings.Repeat(j.indent, j.level)))
+	}
+}
+
 func (j *jsonStreamer) write(e px.Value) {
 	var v []byte
 	var err error
@@ -105,6 +174,10 @@ func (j *jsonStreamer) write(e px.Value) {
 		v, err = json.Marshal(e.String())
 	case px.Float:
 		v, err = json.Marshal(e.Float())
+		if err == nil && !bytes.ContainsAny(v, `.eE`) {
+			// Ensure that the value is read back as a Float
+			v = append(v, '.', '0')
+		}
 	case px.Integer:
 		v, err = json.Marshal(e.Int())
 	case px.Boolean:
@@ -121,3 +194,69 @@ func assertOk(_ int, err error) {
 		panic(px.Error(px.Failure, issue.H{`message`: err}))
 	}
 }
+
+// canonicalJsonStreamer collects each top level value and writes it to the embedded jsonStreamer
+// with sorted hash keys once the value is complete. References are resolved by the collector.
+type canonicalJsonStreamer struct {
+	*jsonStreamer
+	collector px.Collector
+	depth     int
+}
+
+func (j *canonicalJsonStreamer) AddArray(len int, doer px.Doer) {
+	j.depth++
+	j.collector.AddArray(len, doer)
+	j.depth--
+	j.flushIfDone()
+}
+
+func (j *canonicalJsonStreamer) AddHash(len int, doer px.Doer) {
+	j.depth++
+	j.collector.AddHash(len, doer)
+	j.depth--
+	j.flushIfDone()
+}
+
+func (j *canonicalJsonStreamer) Add(element px.Value) {
+	j.collector.Add(element)
+	j.flushIfDone()
+}
+
+func (j *canonicalJsonStreamer) AddRef(ref int) {
+	j.collector.AddRef(ref)
+	j.flushIfDone()
+}
+
+// StringDedupThreshold returns math.MaxInt32 since all references are replaced by the values that
+// they refer to
+func (j *canonicalJsonStreamer) StringDedupThreshold() int {
+	return math.MaxInt32
+}
+
+func (j *canonicalJsonStreamer) flushIfDone() {
+	if j.depth == 0 {
+		j.writeValue(j.collector.Value())
+		j.collector = types.NewCollector()
+	}
+}
+
+func (j *canonicalJsonStreamer) writeValue(v px.Value) {
+	switch v := v.(type) {
+	case *types.Array:
+		j.jsonStreamer.AddArray(v.Len(), func() {
+			v.Each(j.writeValue)
+		})
+	case *types.Hash:
+		es := make([]px.MapEntry, 0, v.Len())
+		v.EachPair(func(k, v px.Value) { es = append(es, types.WrapHashEntry(k, v)) })
+		sort.SliceStable(es, func(i, k int) bool { return es[i].Key().String() < es[k].Key().String() })
+		j.jsonStreamer.AddHash(len(es), func() {
+			for _, e := range es {
+				j.writeValue(e.Key())
+				j.writeValue(e.Value())
+			}
+		})
+	default:
+		j.jsonStreamer.Add(v)
+	}
+}
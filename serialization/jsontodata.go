@@ -21,6 +21,9 @@ const afterKey = 4
 func JsonToData(path string, in io.Reader, consumer px.ValueConsumer) {
 	defer func() {
 		if r := recover(); r != nil {
+			if isInputError(r) {
+				panic(r)
+			}
 			panic(px.Error(px.InvalidJson, issue.H{`path`: path, `detail`: r}))
 		}
 	}()
@@ -38,58 +41,69 @@ func jsonValues(c px.ValueConsumer, d *json.Decoder) {
 		if err != nil {
 			panic(err)
 		}
-		if dl, ok := t.(json.Delim); ok {
-			ds := dl.String()
-			if ds == `}` || ds == `]` {
-				return
-			}
-			if ds == `{` {
-				t = nil
-				if d.More() {
-					t, err = d.Token()
-					if err != nil {
-						panic(err)
-					}
-					if ds, ok = t.(string); ok && ds == PcoreRefKey && d.More() {
-						t, err = d.Token()
-						if err != nil {
-							panic(err)
-						}
-						var n int64
-						n, err = t.(json.Number).Int64()
-						if err != nil {
-							panic(err)
-						}
-						// Consume end delimiter
-						t, err = d.Token()
-						if err != nil {
-							panic(err)
-						}
-						if dl, ok = t.(json.Delim); ok && dl.String() == `}` {
-							c.AddRef(int(n))
-						} else {
-							panic(fmt.Errorf("invalid token %T %v", t, t))
-						}
-						continue
-					}
-					c.AddHash(8, func() {
-						addValue(c, t)
-						jsonValues(c, d)
-					})
-				} else {
-					c.AddHash(8, func() {
-						jsonValues(c, d)
-					})
-				}
-			} else {
-				c.AddArray(8, func() {
-					jsonValues(c, d)
-				})
-			}
+		if !jsonValue(c, d, t) {
+			return
+		}
+	}
+}
+
+// jsonValue streams the value that starts with the given token to the consumer. It returns false
+// if the token is an end delimiter
+func jsonValue(c px.ValueConsumer, d *json.Decoder, t json.Token) bool {
+	dl, ok := t.(json.Delim)
+	if !ok {
+		addValue(c, t)
+		return true
+	}
+	ds := dl.String()
+	if ds == `}` || ds == `]` {
+		return false
+	}
+	if ds != `{` {
+		c.AddArray(8, func() {
+			jsonValues(c, d)
+		})
+		return true
+	}
+
+	if !d.More() {
+		c.AddHash(8, func() {
+			jsonValues(c, d)
+		})
+		return true
+	}
+
+	t, err := d.Token()
+	if err != nil {
+		panic(err)
+	}
+	if ds, ok = t.(string); ok && ds == PcoreRefKey && d.More() {
+		t, err = d.Token()
+		if err != nil {
+			panic(err)
+		}
+		var n int64
+		n, err = t.(json.Number).Int64()
+		if err != nil {
+			panic(err)
+		}
+		// Consume end delimiter
+		t, err = d.Token()
+		if err != nil {
+			panic(err)
+		}
+		if dl, ok = t.(json.Delim); ok && dl.String() == `}` {
+			c.AddRef(int(n))
 		} else {
-			addValue(c, t)
+			panic(fmt.Errorf("invalid token %T %v", t, t))
 		}
+		return true
 	}
+	c.AddHash(8, func() {
+		addValue(c, t)
+		jsonValues(c, d)
+	})
+	return true
 }
 
 func addValue(c px.ValueConsumer, t json.Token) {
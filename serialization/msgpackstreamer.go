@@ -0,0 +1,309 @@
+package serialization
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// MessagePack extension types used by pcore
+const (
+	// MsgpackExtTimestamp is the extension type reserved by the MessagePack specification for timestamps
+	MsgpackExtTimestamp = -1
+
+	// MsgpackExtReference is the extension type used for references to previously streamed
+	// values. The data is the reference index as a big endian uint32
+	MsgpackExtReference = 1
+
+	// MsgpackExtTimespan is the extension type used for Timespan values. The data is the
+	// number of nanoseconds as a big endian int64
+	MsgpackExtTimespan = 2
+)
+
+// MessagePack format bytes
+const (
+	mpNil      = 0xc0
+	mpFalse    = 0xc2
+	mpTrue     = 0xc3
+	mpBin8     = 0xc4
+	mpBin16    = 0xc5
+	mpBin32    = 0xc6
+	mpExt8     = 0xc7
+	mpExt16    = 0xc8
+	mpExt32    = 0xc9
+	mpFloat32  = 0xca
+	mpFloat64  = 0xcb
+	mpUint8    = 0xcc
+	mpUint16   = 0xcd
+	mpUint32   = 0xce
+	mpUint64   = 0xcf
+	mpInt8     = 0xd0
+	mpInt16    = 0xd1
+	mpInt32    = 0xd2
+	mpInt64    = 0xd3
+	mpFixExt1  = 0xd4
+	mpFixExt2  = 0xd5
+	mpFixExt4  = 0xd6
+	mpFixExt8  = 0xd7
+	mpFixExt16 = 0xd8
+	mpStr8     = 0xd9
+	mpStr16    = 0xda
+	mpStr32    = 0xdb
+	mpArray16  = 0xdc
+	mpArray32  = 0xdd
+	mpMap16    = 0xde
+	mpMap32    = 0xdf
+	mpFixMap   = 0x80
+	mpFixArray = 0x90
+	mpFixStr   = 0xa0
+)
+
+// NewMsgpackStreamer creates a new streamer that will produce MessagePack when
+// receiving values.
+//
+// Binary values are written using the MessagePack bin format. Timestamp values use
+// the MessagePack timestamp extension type and Timespan values and references use the
+// MsgpackExtTimespan and MsgpackExtReference extension types.
+func NewMsgpackStreamer(out io.Writer) px.ValueConsumer {
+	return &msgpackStreamer{out: out, buf: make([]byte, 16), open: make([]*msgpackHead, 0, 8)}
+}
+
+// A msgpackHead is the head of an array or hash. MessagePack requires that the number of
+// elements is known up front and the length given to AddArray and AddHash is just a hint,
+// so the head is created when the array or hash is complete.
+type msgpackHead struct {
+	pos   int
+	count int
+	bytes []byte
+}
+
+// A msgpackStreamer writes the elements of a top level array or hash to a body buffer and keeps
+// the heads of the nested arrays and hashes separately together with their position in that
+// buffer. The heads and the body are merged when the top level array or hash is complete so
+// that each byte is copied only once regardless of the nesting depth.
+type msgpackStreamer struct {
+	out   io.Writer
+	buf   []byte
+	body  bytes.Buffer
+	heads []*msgpackHead
+	open  []*msgpackHead
+}
+
+func (m *msgpackStreamer) AddArray(len int, doer px.Doer) {
+	h := m.collect(doer)
+	h.bytes = m.head(h.count, mpFixArray, 15, mpArray16, mpArray32)
+	m.flush()
+}
+
+func (m *msgpackStreamer) AddHash(len int, doer px.Doer) {
+	h := m.collect(doer)
+	h.bytes = m.head(h.count/2, mpFixMap, 15, mpMap16, mpMap32)
+	m.flush()
+}
+
+func (m *msgpackStreamer) Add(element px.Value) {
+	switch e := element.(type) {
+	case px.StringValue:
+		s := e.String()
+		m.writeHead(len(s), mpFixStr, 31, mpStr16, mpStr32, mpStr8)
+		m.write([]byte(s))
+	case px.Integer:
+		m.writeInt(e.Int())
+	case px.Float:
+		m.buf[0] = mpFloat64
+		binary.BigEndian.PutUint64(m.buf[1:], math.Float64bits(e.Float()))
+		m.writeHeadBytes(m.buf[:9])
+	case px.Boolean:
+		if e.Bool() {
+			m.writeByte(mpTrue)
+		} else {
+			m.writeByte(mpFalse)
+		}
+	case *types.Binary:
+		bs := e.Bytes()
+		switch n := len(bs); {
+		case n <= math.MaxUint8:
+			m.buf[0] = mpBin8
+			m.buf[1] = byte(n)
+			m.writeHeadBytes(m.buf[:2])
+		case n <= math.MaxUint16:
+			m.buf[0] = mpBin16
+			binary.BigEndian.PutUint16(m.buf[1:], uint16(n))
+			m.writeHeadBytes(m.buf[:3])
+		default:
+			m.buf[0] = mpBin32
+			binary.BigEndian.PutUint32(m.buf[1:], uint32(n))
+			m.writeHeadBytes(m.buf[:5])
+		}
+		m.write(bs)
+	case *types.Timestamp:
+		m.writeTimestamp(time.Time(*e))
+	case types.Timespan:
+		m.buf[0] = mpFixExt8
+		m.buf[1] = MsgpackExtTimespan
+		binary.BigEndian.PutUint64(m.buf[2:], uint64(e.Duration()))
+		m.writeHeadBytes(m.buf[:10])
+	default:
+		m.writeByte(mpNil)
+	}
+}
+
+func (m *msgpackStreamer) AddRef(ref int) {
+	m.buf[0] = mpFixExt4
+	m.buf[1] = MsgpackExtReference
+	binary.BigEndian.PutUint32(m.buf[2:], uint32(ref))
+	m.writeHeadBytes(m.buf[:6])
+}
+
+func (m *msgpackStreamer) CanDoBinary() bool {
+	return true
+}
+
+func (m *msgpackStreamer) CanDoComplexKeys() bool {
+	return true
+}
+
+func (m *msgpackStreamer) CanDoTime() bool {
+	return true
+}
+
+func (m *msgpackStreamer) StringDedupThreshold() int {
+	return 20
+}
+
+// collect calls the doer with a new head pushed onto the stack of open heads and returns that
+// head once the doer returns.
+func (m *msgpackStreamer) collect(doer px.Doer) *msgpackHead {
+	m.count()
+	h := &msgpackHead{pos: m.body.Len()}
+	m.heads = append(m.heads, h)
+	m.open = append(m.open, h)
+	doer()
+	m.open = m.open[:len(m.open)-1]
+	return h
+}
+
+// flush writes the heads and the body to the output once the top level array or hash is complete
+func (m *msgpackStreamer) flush() {
+	if len(m.open) > 0 {
+		return
+	}
+	bs := m.body.Bytes()
+	pos := 0
+	for _, h := range m.heads {
+		assertOk(m.out.Write(bs[pos:h.pos]))
+		assertOk(m.out.Write(h.bytes))
+		pos = h.pos
+	}
+	assertOk(m.out.Write(bs[pos:]))
+	m.body.Reset()
+	m.heads = m.heads[:0]
+}
+
+// count increases the element count of the innermost open array or hash by one
+func (m *msgpackStreamer) count() {
+	if top := len(m.open) - 1; top >= 0 {
+		m.open[top].count++
+	}
+}
+
+// writeHeadBytes writes the first bytes of a new element
+func (m *msgpackStreamer) writeHeadBytes(bs []byte) {
+	m.count()
+	m.write(bs)
+}
+
+// write writes bytes that belong to the last element
+func (m *msgpackStreamer) write(bs []byte) {
+	if len(m.open) == 0 {
+		assertOk(m.out.Write(bs))
+	} else {
+		m.body.Write(bs)
+	}
+}
+
+func (m *msgpackStreamer) writeByte(b byte) {
+	m.buf[0] = b
+	m.writeHeadBytes(m.buf[:1])
+}
+
+// writeHead writes the head of a sized entity using a fixed format when possible. A string can
+// also use an 8 bit length which is then passed as an optional last argument
+func (m *msgpackStreamer) writeHead(n int, fix byte, fixMax int, f16, f32 byte, f8 ...byte) {
+	m.writeHeadBytes(m.head(n, fix, fixMax, f16, f32, f8...))
+}
+
+// head returns a new slice with the head of a sized entity. See writeHead
+func (m *msgpackStreamer) head(n int, fix byte, fixMax int, f16, f32 byte, f8 ...byte) []byte {
+	var b []byte
+	switch {
+	case n <= fixMax:
+		b = []byte{fix | byte(n)}
+	case len(f8) > 0 && n <= math.MaxUint8:
+		b = []byte{f8[0], byte(n)}
+	case n <= math.MaxUint16:
+		b = make([]byte, 3)
+		b[0] = f16
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+	default:
+		b = make([]byte, 5)
+		b[0] = f32
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+	}
+	return b
+}
+
+func (m *msgpackStreamer) writeInt(i int64) {
+	b := m.buf
+	switch {
+	case i >= 0 && i <= 0x7f, i < 0 && i >= -32:
+		b[0] = byte(i)
+		b = b[:1]
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		b[0] = mpInt8
+		b[1] = byte(i)
+		b = b[:2]
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		b[0] = mpInt16
+		binary.BigEndian.PutUint16(b[1:], uint16(i))
+		b = b[:3]
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		b[0] = mpInt32
+		binary.BigEndian.PutUint32(b[1:], uint32(i))
+		b = b[:5]
+	default:
+		b[0] = mpInt64
+		binary.BigEndian.PutUint64(b[1:], uint64(i))
+		b = b[:9]
+	}
+	m.writeHeadBytes(b)
+}
+
+func (m *msgpackStreamer) writeTimestamp(t time.Time) {
+	sec := t.Unix()
+	nsec := uint64(t.Nanosecond())
+	b := m.buf
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		b[0] = mpFixExt4
+		b[1] = 0xff
+		binary.BigEndian.PutUint32(b[2:], uint32(sec))
+		b = b[:6]
+	case sec>>34 == 0:
+		b[0] = mpFixExt8
+		b[1] = 0xff
+		binary.BigEndian.PutUint64(b[2:], nsec<<34|uint64(sec))
+		b = b[:10]
+	default:
+		b = append(b[:0], mpExt8, 12, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[3:], uint32(nsec))
+		binary.BigEndian.PutUint64(b[7:], uint64(sec))
+		b = b[:15]
+	}
+	m.writeHeadBytes(b)
+}
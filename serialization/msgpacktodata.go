@@ -0,0 +1,212 @@
+package serialization
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// MsgpackToData reads one MessagePack value from the given reader and streams the
+// values to the given ValueConsumer.
+//
+// The bin format is streamed as Binary values. The extension types MsgpackExtTimestamp,
+// MsgpackExtTimespan, and MsgpackExtReference are streamed as Timestamp, Timespan, and
+// calls to AddRef. Other extension types are streamed as Binary values.
+func MsgpackToData(in io.Reader, consumer px.ValueConsumer) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(issue.Reported); ok {
+				panic(r)
+			}
+			panic(px.Error(px.InvalidMsgpack, issue.H{`detail`: r}))
+		}
+	}()
+	br, ok := in.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(in)
+	}
+	mr := &msgpackReader{in: br, consumer: consumer, buf: make([]byte, 8)}
+	mr.limiter, _ = consumer.(sizeLimiter)
+	mr.readValue()
+}
+
+type msgpackReader struct {
+	in       *bufio.Reader
+	consumer px.ValueConsumer
+	limiter  sizeLimiter
+	buf      []byte
+}
+
+func (mr *msgpackReader) readValue() {
+	c := mr.consumer
+	b := mr.readByte()
+	switch {
+	case b <= 0x7f:
+		c.Add(types.WrapInteger(int64(b)))
+	case b >= 0xe0:
+		c.Add(types.WrapInteger(int64(int8(b))))
+	case b&0xf0 == mpFixMap:
+		mr.readHash(int(b & 0x0f))
+	case b&0xf0 == mpFixArray:
+		mr.readArray(int(b & 0x0f))
+	case b&0xe0 == mpFixStr:
+		c.Add(types.WrapString(string(mr.readString(`String`, int(b&0x1f)))))
+	default:
+		switch b {
+		case mpNil:
+			c.Add(px.Undef)
+		case mpFalse:
+			c.Add(types.BooleanFalse)
+		case mpTrue:
+			c.Add(types.BooleanTrue)
+		case mpBin8, mpBin16, mpBin32:
+			c.Add(types.WrapBinary(mr.readString(`Binary`, mr.readLength(b-mpBin8))))
+		case mpExt8, mpExt16, mpExt32:
+			n := mr.readLength(b - mpExt8)
+			mr.readExt(n)
+		case mpFixExt1, mpFixExt2, mpFixExt4, mpFixExt8, mpFixExt16:
+			mr.readExt(1 << (b - mpFixExt1))
+		case mpFloat32:
+			c.Add(types.WrapFloat(float64(math.Float32frombits(uint32(mr.readUint(4))))))
+		case mpFloat64:
+			c.Add(types.WrapFloat(math.Float64frombits(mr.readUint(8))))
+		case mpUint8, mpUint16, mpUint32, mpUint64:
+			n := mr.readUint(1 << (b - mpUint8))
+			if n > math.MaxInt64 {
+				panic(fmt.Errorf("integer %d is out of range", n))
+			}
+			c.Add(types.WrapInteger(int64(n)))
+		case mpInt8:
+			c.Add(types.WrapInteger(int64(int8(mr.readUint(1)))))
+		case mpInt16:
+			c.Add(types.WrapInteger(int64(int16(mr.readUint(2)))))
+		case mpInt32:
+			c.Add(types.WrapInteger(int64(int32(mr.readUint(4)))))
+		case mpInt64:
+			c.Add(types.WrapInteger(int64(mr.readUint(8))))
+		case mpStr8, mpStr16, mpStr32:
+			c.Add(types.WrapString(string(mr.readString(`String`, mr.readLength(b-mpStr8)))))
+		case mpArray16, mpArray32:
+			mr.readArray(mr.readLength(b - mpArray16 + 1))
+		case mpMap16, mpMap32:
+			mr.readHash(mr.readLength(b - mpMap16 + 1))
+		default:
+			panic(fmt.Errorf("illegal format byte 0x%x", b))
+		}
+	}
+}
+
+func (mr *msgpackReader) readArray(n int) {
+	mr.consumer.AddArray(capacityHint(uint64(n)), func() {
+		for i := 0; i < n; i++ {
+			mr.readValue()
+		}
+	})
+}
+
+func (mr *msgpackReader) readHash(n int) {
+	mr.consumer.AddHash(capacityHint(uint64(n)), func() {
+		for i := 0; i < n; i++ {
+			mr.readValue()
+			mr.readValue()
+		}
+	})
+}
+
+func (mr *msgpackReader) readExt(n int) {
+	et := int8(mr.readByte())
+	var data []byte
+	switch et {
+	case MsgpackExtReference, MsgpackExtTimespan, MsgpackExtTimestamp:
+		if n > 12 {
+			panic(fmt.Errorf("illegal length %d of extension type %d", n, et))
+		}
+		data = readFull(mr.in, int64(n))
+	default:
+		data = mr.readString(`Binary`, n)
+	}
+	c := mr.consumer
+	switch et {
+	case MsgpackExtReference:
+		if n != 4 {
+			panic(fmt.Errorf("illegal length %d of reference extension", n))
+		}
+		c.AddRef(int(binary.BigEndian.Uint32(data)))
+	case MsgpackExtTimespan:
+		if n != 8 {
+			panic(fmt.Errorf("illegal length %d of timespan extension", n))
+		}
+		c.Add(types.WrapTimespan(time.Duration(binary.BigEndian.Uint64(data))))
+	case MsgpackExtTimestamp:
+		var t time.Time
+		switch n {
+		case 4:
+			t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+		case 8:
+			v := binary.BigEndian.Uint64(data)
+			t = time.Unix(int64(v&0x3ffffffff), int64(v>>34))
+		case 12:
+			t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+		default:
+			panic(fmt.Errorf("illegal length %d of timestamp extension", n))
+		}
+		c.Add(types.WrapTimestamp(t.UTC()))
+	default:
+		c.Add(types.WrapBinary(data))
+	}
+}
+
+func (mr *msgpackReader) readByte() byte {
+	b, err := mr.in.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		panic(err)
+	}
+	return b
+}
+
+// readString reads n bytes after asserting that the consumer accepts a value of the given type
+// and size
+func (mr *msgpackReader) readString(typeName string, n int) []byte {
+	if mr.limiter != nil {
+		mr.limiter.assertSize(typeName, n)
+	}
+	return readFull(mr.in, int64(n))
+}
+
+// readLength reads a length where the sizeIndex 0, 1, and 2 denotes a length of
+// 8, 16, or 32 bits.
+func (mr *msgpackReader) readLength(sizeIndex byte) int {
+	n := mr.readUint(1 << sizeIndex)
+	if n > math.MaxInt32 {
+		panic(fmt.Errorf("length %d is out of range", n))
+	}
+	return int(n)
+}
+
+// readUint reads a big endian unsigned integer of n bytes
+func (mr *msgpackReader) readUint(n int) uint64 {
+	b := mr.buf[:n]
+	if _, err := io.ReadFull(mr.in, b); err != nil {
+		panic(err)
+	}
+	switch n {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b))
+	default:
+		return binary.BigEndian.Uint64(b)
+	}
+}
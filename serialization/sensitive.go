@@ -0,0 +1,124 @@
+package serialization
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// A SensitiveCipher encrypts and decrypts the contents of Sensitive values. It is passed to NewSerializer
+// and NewDeserializer wrapped in a Runtime value using the `sensitive_cipher` option.
+type SensitiveCipher interface {
+	// KeyId returns the identifier of the key that Encrypt uses. The identifier is written together with
+	// the encrypted data.
+	KeyId() string
+
+	// Encrypt encrypts the given plain text
+	Encrypt(plainText []byte) ([]byte, error)
+
+	// Decrypt decrypts cipher text that was encrypted using the key with the given identifier
+	Decrypt(keyId string, cipherText []byte) ([]byte, error)
+}
+
+type aesGcmCipher struct {
+	keyId string
+	aead  cipher.AEAD
+}
+
+// NewAesGcmCipher returns a SensitiveCipher that uses AES in Galois Counter Mode. The key must be 16, 24,
+// or 32 bytes long to select AES-128, AES-192, or AES-256. A random nonce is generated for each encryption
+// and stored in front of the cipher text. The key identifier is used as additional authenticated data.
+func NewAesGcmCipher(keyId string, key []byte) SensitiveCipher {
+	block, err := aes.NewCipher(key)
+	if err == nil {
+		var aead cipher.AEAD
+		if aead, err = cipher.NewGCM(block); err == nil {
+			return &aesGcmCipher{keyId: keyId, aead: aead}
+		}
+	}
+	panic(px.Error(px.IllegalArgument, issue.H{`function`: `NewAesGcmCipher`, `index`: 1, `arg`: err.Error()}))
+}
+
+func (a *aesGcmCipher) KeyId() string {
+	return a.keyId
+}
+
+func (a *aesGcmCipher) Encrypt(plainText []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(plainText)+a.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plainText, []byte(a.keyId)), nil
+}
+
+func (a *aesGcmCipher) Decrypt(keyId string, cipherText []byte) ([]byte, error) {
+	if keyId != a.keyId {
+		return nil, fmt.Errorf(`key '%s' is not known by this cipher`, keyId)
+	}
+	ns := a.aead.NonceSize()
+	if len(cipherText) < ns {
+		return nil, fmt.Errorf(`cipher text is too short`)
+	}
+	return a.aead.Open(nil, cipherText[:ns], cipherText[ns:], []byte(keyId))
+}
+
+// sensitiveCipher returns the SensitiveCipher found under the `sensitive_cipher` key of the
+// given options or nil if no such key exists
+func sensitiveCipher(function string, options px.OrderedMap) SensitiveCipher {
+	cv, ok := options.Get4(`sensitive_cipher`)
+	if !ok {
+		return nil
+	}
+	if rt, ok := cv.(*types.RuntimeValue); ok {
+		if sc, ok := rt.Interface().(SensitiveCipher); ok {
+			return sc
+		}
+	}
+	panic(px.Error(px.IllegalArgument, issue.H{
+		`function`: function, `index`: 1, `arg`: `sensitive_cipher must be a Runtime value that wraps a SensitiveCipher`}))
+}
+
+// encryptSensitive serializes the contents of the given value into JSON and encrypts the result
+func (sc *context) encryptSensitive(value *types.Sensitive) []byte {
+	b := bytes.NewBufferString(``)
+	cfg := *sc.config
+	cfg.richData = true
+	cfg.Convert(value.Unwrap(), NewJsonStreamer(b))
+	ct, err := cfg.cipher.Encrypt(b.Bytes())
+	if err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+	return ct
+}
+
+// decryptSensitive decrypts the given data and deserializes the contents of a Sensitive value from the result
+func (ds *dsContext) decryptSensitive(keyId string, data px.Value) px.Value {
+	if ds.cipher == nil {
+		panic(px.Error(px.UnableToDecryptSensitive, issue.H{`key`: keyId, `detail`: `no sensitive_cipher was given`}))
+	}
+	bin, ok := data.(*types.Binary)
+	if !ok {
+		panic(px.Error(px.UnableToDecryptSensitive, issue.H{`key`: keyId, `detail`: `encrypted data is not a Binary`}))
+	}
+	pt, err := ds.cipher.Decrypt(keyId, bin.Bytes())
+	if err != nil {
+		panic(px.Error(px.UnableToDecryptSensitive, issue.H{`key`: keyId, `detail`: err.Error()}))
+	}
+	cd := &dsContext{
+		context:         ds.context,
+		newTypes:        make([]px.Type, 0, 11),
+		converted:       make(map[px.Value]px.Value, 11),
+		allowUnresolved: ds.allowUnresolved,
+		cipher:          ds.cipher,
+		dsLimits:        ds.dsLimits}
+	cd.Init()
+	JsonToData(``, bytes.NewReader(pt), cd)
+	return cd.Value()
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/internal/testutil"
 	"github.com/lyraproj/pcore/pcore"
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/serialization"
@@ -220,6 +223,36 @@ func ExampleNewJsonStreamer() {
 	// Output: {"__ptype":"SemVer","__pvalue":"1.0.0"}
 }
 
+func ExampleNewJsonStreamer2_indent() {
+	pcore.Do(func(ctx px.Context) {
+		buf := bytes.NewBufferString(``)
+		v := px.Wrap(ctx, map[string]interface{}{`a`: []interface{}{1, 2.5}, `b`: map[string]interface{}{}})
+		serialization.NewSerializer(ctx, px.EmptyMap).Convert(v,
+			serialization.NewJsonStreamer2(buf, px.SingletonMap(`indent`, types.WrapInteger(2))))
+		fmt.Println(buf)
+	})
+	// Output:
+	// {
+	//   "a": [
+	//     1,
+	//     2.5
+	//   ],
+	//   "b": {}
+	// }
+}
+
+func ExampleNewJsonStreamer2_canonical() {
+	pcore.Do(func(ctx px.Context) {
+		buf := bytes.NewBufferString(``)
+		sv := types.WrapString(`a string that is long enough to be deduplicated`)
+		v := types.WrapStringToValueMap(map[string]px.Value{`z`: sv, `a`: types.WrapFloat(1.0), `m`: sv})
+		serialization.NewSerializer(ctx, px.EmptyMap).Convert(v,
+			serialization.NewJsonStreamer2(buf, px.SingletonMap(`canonical`, types.BooleanTrue)))
+		fmt.Println(buf)
+	})
+	// Output: {"a":1.0,"m":"a string that is long enough to be deduplicated","z":"a string that is long enough to be deduplicated"}
+}
+
 func ExampleJsonToData() {
 	pcore.Do(func(ctx px.Context) {
 		buf := bytes.NewBufferString(`{"__ptype":"SemVer","__pvalue":"1.0.0"}`)
@@ -229,3 +262,258 @@ func ExampleJsonToData() {
 	})
 	// Output: {'__ptype' => 'SemVer', '__pvalue' => '1.0.0'}
 }
+
+func ExampleNewCborStreamer() {
+	pcore.Do(func(ctx px.Context) {
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, px.EmptyMap).Convert(
+			types.WrapValues([]px.Value{types.WrapInteger(-2), types.WrapString(`a`), types.WrapBinary([]byte{1, 2})}), serialization.NewCborStreamer(buf))
+		fmt.Printf("%x\n", buf.Bytes())
+	})
+	// Output: 9f216161420102ff
+}
+
+func ExampleCborToData() {
+	pcore.Do(func(ctx px.Context) {
+		bin := types.WrapBinary([]byte(`binary data`))
+		ver, _ := semver.NewVersion(1, 0, 0)
+		v := types.WrapValues([]px.Value{
+			bin,
+			bin,
+			types.WrapSensitive(types.WrapString(`secret`)),
+			types.WrapHash([]*types.HashEntry{types.WrapHashEntry(types.WrapInteger(1), types.WrapSemVer(ver))})})
+		fmt.Println(v)
+
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, px.EmptyMap).Convert(v, serialization.NewCborStreamer(buf))
+
+		fc := serialization.NewDeserializer(ctx, px.EmptyMap)
+		serialization.CborToData(buf, fc)
+		v2 := fc.Value().(px.List)
+		fmt.Println(v2)
+		fmt.Println(v2.At(0) == v2.At(1))
+	})
+	// Output:
+	// [Binary('YmluYXJ5IGRhdGE='), Binary('YmluYXJ5IGRhdGE='), Sensitive [value redacted], {1 => SemVer('1.0.0')}]
+	// [Binary('YmluYXJ5IGRhdGE='), Binary('YmluYXJ5IGRhdGE='), Sensitive [value redacted], {1 => SemVer('1.0.0')}]
+	// true
+}
+
+func ExampleCborToData_truncated() {
+	// A declared length is not trusted. A truncated stream is an error, not an allocation of the declared size.
+	for _, in := range [][]byte{
+		{0x5a, 0x7f, 0xff, 0xff, 0xff, 0x01},
+		{0x9a, 0x7f, 0xff, 0xff, 0xff, 0x01},
+	} {
+		r := testutil.Reported(func() { serialization.CborToData(bytes.NewReader(in), types.NewCollector()) })
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	}
+	// Output:
+	// PCORE_INVALID_CBOR unexpected EOF
+	// PCORE_INVALID_CBOR unexpected EOF
+}
+
+func ExampleNewMsgpackStreamer() {
+	pcore.Do(func(ctx px.Context) {
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, px.EmptyMap).Convert(
+			types.WrapStringToInterfaceMap(ctx, map[string]interface{}{`a`: []interface{}{-2, 300, true, nil}}), serialization.NewMsgpackStreamer(buf))
+		fmt.Printf("%x\n", buf.Bytes())
+	})
+	// Output: 81a16194fed1012cc3c0
+}
+
+func ExampleMsgpackToData() {
+	pcore.Do(func(ctx px.Context) {
+		ts := types.WrapTimestamp(time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC))
+		tp := types.WrapTimespan(90 * time.Second)
+		lv := types.WrapString(`a string that is long enough to be deduplicated`)
+		v := types.WrapValues([]px.Value{ts, tp, lv, lv, ts, types.WrapBinary([]byte{1, 2, 3})})
+		fmt.Println(v)
+
+		for _, dedup := range []int64{serialization.NoDedup, serialization.NoKeyDedup, serialization.MaxDedup} {
+			buf := bytes.NewBufferString(``)
+			serialization.NewSerializer(ctx, px.SingletonMap(`dedup_level`, types.WrapInteger(dedup))).Convert(v, serialization.NewMsgpackStreamer(buf))
+			n := buf.Len()
+
+			fc := serialization.NewDeserializer(ctx, px.EmptyMap)
+			serialization.MsgpackToData(buf, fc)
+			fmt.Println(n, v.Equals(fc.Value(), nil))
+		}
+	})
+	// Output:
+	// [2019-01-02T03:04:05.000000006 UTC, 0-00:01:30.0, 'a string that is long enough to be deduplicated', 'a string that is long enough to be deduplicated', 2019-01-02T03:04:05.000000006 UTC, Binary('AQID')]
+	// 134 true
+	// 87 true
+	// 87 true
+}
+
+func ExampleNewMsgpackStreamer_nested() {
+	// JsonToData doesn't know the number of elements up front so the streamer must count them
+	js := `{"a":[[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17],{"b":[[["deep"]]]}],"c":[]}`
+	buf := bytes.NewBufferString(``)
+	serialization.JsonToData(`in.json`, bytes.NewBufferString(js), serialization.NewMsgpackStreamer(buf))
+	fmt.Printf("%x\n", buf.Bytes())
+
+	c := types.NewCollector()
+	serialization.MsgpackToData(buf, c)
+	fmt.Println(c.Value())
+	// Output:
+	// 82a16192dc00110102030405060708090a0b0c0d0e0f101181a162919191a464656570a16390
+	// {'a' => [[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17], {'b' => [[['deep']]]}], 'c' => []}
+}
+
+func ExampleMsgpackToData_truncated() {
+	// A declared length is not trusted. A truncated stream is an error, not an allocation of the declared size.
+	for _, in := range [][]byte{
+		{0xc6, 0x7f, 0xff, 0xff, 0xff, 0x01},
+		{0xdd, 0x7f, 0xff, 0xff, 0xff, 0x01},
+	} {
+		r := testutil.Reported(func() { serialization.MsgpackToData(bytes.NewReader(in), types.NewCollector()) })
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	}
+	// Output:
+	// PCORE_INVALID_MSGPACK unexpected EOF
+	// PCORE_INVALID_MSGPACK unexpected EOF
+}
+
+func ExampleReadJsonDocuments() {
+	pcore.Do(func(ctx px.Context) {
+		in := bytes.NewBufferString(`{"__ptype":"SemVer","__pvalue":"1.0.0"}
+["a string that is long enough", {"__pref":1}]
+["another string that is long", {"__pref":1}]
+`)
+		serialization.ReadJsonDocuments(ctx, `/tmp/docs.json`, in, px.EmptyMap, func(v px.Value, index int) {
+			fmt.Println(index, v)
+		})
+	})
+	// Output:
+	// 0 1.0.0
+	// 1 ['a string that is long enough', 'a string that is long enough']
+	// 2 ['another string that is long', 'another string that is long']
+}
+
+func ExampleReadJsonDocuments_invalid() {
+	pcore.Do(func(ctx px.Context) {
+		in := bytes.NewBufferString("{\"a\":1}\n{\"b\":2}\n{\"c\" 3}\n")
+		r := testutil.Reported(func() {
+			serialization.ReadJsonDocuments(ctx, `/tmp/docs.json`, in, px.EmptyMap, func(v px.Value, index int) {
+				fmt.Println(index, v)
+			})
+		})
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	})
+	// Output:
+	// 0 {'a' => 1}
+	// 1 {'b' => 2}
+	// PCORE_INVALID_JSON document 2, offset 20: invalid character '3' after object key
+}
+
+func ExampleNewSerializer_redactSensitive() {
+	pcore.Do(func(ctx px.Context) {
+		buf := bytes.NewBufferString(``)
+		v := types.WrapStringToValueMap(map[string]px.Value{`password`: types.WrapSensitive(types.WrapString(`secret`))})
+		serialization.NewSerializer(ctx, px.SingletonMap(`redact_sensitive`, types.BooleanTrue)).Convert(v, serialization.NewJsonStreamer(buf))
+		fmt.Println(buf)
+	})
+	// Output: {"password":{"__ptype":"Sensitive","__pvalue":"[redacted]"}}
+}
+
+func ExampleNewSerializer_encryptSensitive() {
+	pcore.Do(func(ctx px.Context) {
+		key := []byte(`0123456789abcdef0123456789abcdef`)
+		options := px.SingletonMap(`sensitive_cipher`, types.WrapRuntime(serialization.NewAesGcmCipher(`k1`, key)))
+
+		buf := bytes.NewBufferString(``)
+		v := types.WrapStringToValueMap(map[string]px.Value{`password`: types.WrapSensitive(types.WrapString(`secret`))})
+		serialization.NewSerializer(ctx, options).Convert(v, serialization.NewJsonStreamer(buf))
+		fmt.Println(bytes.Contains(buf.Bytes(), []byte(`secret`)))
+
+		ds := serialization.NewDeserializer(ctx, options)
+		serialization.JsonToData(`/tmp/secret.json`, buf, ds)
+		fmt.Println(ds.Value().(px.OrderedMap).Get5(`password`, px.Undef).(*types.Sensitive).Unwrap())
+	})
+	// Output:
+	// false
+	// secret
+}
+
+func ExampleNewDeserializer_missingCipher() {
+	pcore.Do(func(ctx px.Context) {
+		key := []byte(`0123456789abcdef`)
+		buf := bytes.NewBufferString(``)
+		serialization.NewSerializer(ctx, px.SingletonMap(`sensitive_cipher`, types.WrapRuntime(serialization.NewAesGcmCipher(`k1`, key)))).Convert(
+			types.WrapSensitive(types.WrapString(`secret`)), serialization.NewJsonStreamer(buf))
+
+		r := testutil.Reported(func() {
+			ds := serialization.NewDeserializer(ctx, px.EmptyMap)
+			serialization.JsonToData(`/tmp/secret.json`, buf, ds)
+			fmt.Println(ds.Value())
+		})
+		fmt.Println(r.Code(), r.Argument(`detail`))
+	})
+	// Output: PCORE_UNABLE_TO_DECRYPT_SENSITIVE no sensitive_cipher was given
+}
+
+func ExampleNewDeserializer_limits() {
+	pcore.Do(func(ctx px.Context) {
+		deserialize := func(json string, options px.OrderedMap) {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Println(r.(issue.Reported).Code())
+				}
+			}()
+			ds := serialization.NewDeserializer(ctx, options)
+			serialization.JsonToData(`/tmp/input.json`, bytes.NewBufferString(json), ds)
+			fmt.Println(ds.Value())
+		}
+		deserialize(`[[[1]]]`, px.SingletonMap(`max_depth`, types.WrapInteger(3)))
+		deserialize(`[[[[1]]]]`, px.SingletonMap(`max_depth`, types.WrapInteger(3)))
+		deserialize(`{"a":[1,2,3]}`, px.SingletonMap(`max_elements`, types.WrapInteger(5)))
+		deserialize(`["abc","abcd"]`, px.SingletonMap(`max_size`, types.WrapInteger(3)))
+
+		allowed := px.SingletonMap(`allowed_types`, types.WrapStrings([]string{`SemVer`}))
+		deserialize(`{"__ptype":"SemVer","__pvalue":"1.0.0"}`, allowed)
+		deserialize(`{"__ptype":"Binary","__pvalue":"AQID"}`, allowed)
+		deserialize(`{"__ptype":"Timestamp","__pvalue":"2019-01-01T00:00:00.000 UTC"}`, allowed)
+	})
+	// Output:
+	// [[[1]]]
+	// PCORE_DEPTH_LIMIT_EXCEEDED
+	// PCORE_ELEMENT_LIMIT_EXCEEDED
+	// PCORE_SIZE_LIMIT_EXCEEDED
+	// 1.0.0
+	// AQID
+	// PCORE_TYPE_NOT_ALLOWED
+}
+
+func ExampleNewDeserializer_declaredSize() {
+	pcore.Do(func(ctx px.Context) {
+		// The declared sizes are checked before anything is read so the truncated input is never reached
+		limit := px.SingletonMap(`max_size`, types.WrapInteger(1024))
+		for _, read := range []func(){
+			func() {
+				serialization.CborToData(bytes.NewReader([]byte{0x7a, 0x7f, 0xff, 0xff, 0xff}), serialization.NewDeserializer(ctx, limit))
+			},
+			func() {
+				serialization.MsgpackToData(bytes.NewReader([]byte{0xc6, 0x7f, 0xff, 0xff, 0xff}), serialization.NewDeserializer(ctx, limit))
+			},
+		} {
+			r := testutil.Reported(read)
+			fmt.Println(r.Code(), r.Argument(`type`), r.Argument(`size`))
+		}
+	})
+	// Output:
+	// PCORE_SIZE_LIMIT_EXCEEDED String 2147483647
+	// PCORE_SIZE_LIMIT_EXCEEDED Binary 2147483647
+}
+
+func ExampleNewDeserializer_invalidReference() {
+	pcore.Do(func(ctx px.Context) {
+		r := testutil.Reported(func() {
+			serialization.JsonToData(`/tmp/input.json`, bytes.NewBufferString(`[1,{"__pref":5}]`), serialization.NewDeserializer(ctx, px.EmptyMap))
+		})
+		fmt.Println(r.Code(), r.Argument(`ref`), r.Argument(`count`))
+	})
+	// Output: PCORE_INVALID_REFERENCE 5 2
+}
@@ -23,10 +23,12 @@ type Serializer interface {
 }
 
 type rdSerializer struct {
-	context       px.Context
-	richData      bool
-	messagePrefix string
-	dedupLevel    int
+	context         px.Context
+	richData        bool
+	messagePrefix   string
+	dedupLevel      int
+	redactSensitive bool
+	cipher          SensitiveCipher
 }
 
 type context struct {
@@ -39,10 +41,20 @@ type context struct {
 }
 
 // NewSerializer returns a new Serializer
+//
+// The contents of Sensitive values are written in clear text unless one of the following options is given:
+//
+// redact_sensitive: Boolean, default false. When true, the contents are replaced by the string PcoreSensitiveRedacted.
+//
+// sensitive_cipher: Runtime value that wraps a SensitiveCipher. When given, the contents are serialized to JSON and
+// then encrypted. The result is written as a Binary under the PcoreValueKey together with the identifier of the key
+// under the PcoreKeyIdKey. A Deserializer must be given the same option in order to decrypt the value.
 func NewSerializer(ctx px.Context, options px.OrderedMap) Serializer {
 	t := &rdSerializer{context: ctx}
 	t.richData = options.Get5(`rich_data`, types.BooleanTrue).(px.Boolean).Bool()
 	t.messagePrefix = options.Get5(`message_prefix`, px.EmptyString).String()
+	t.redactSensitive = options.Get5(`redact_sensitive`, types.BooleanFalse).(px.Boolean).Bool()
+	t.cipher = sensitiveCipher(`NewSerializer`, options)
 	if !options.Get5(`local_reference`, types.BooleanTrue).(px.Boolean).Bool() {
 		// local_reference explicitly set to false
 		t.dedupLevel = NoDedup
@@ -57,6 +69,8 @@ var valueKey = types.WrapString(PcoreValueKey)
 var defaultType = types.WrapString(PcoreTypeDefault)
 var binaryType = types.WrapString(PcoreTypeBinary)
 var sensitiveType = types.WrapString(PcoreTypeSensitive)
+var keyIdKey = types.WrapString(PcoreKeyIdKey)
+var redacted = types.WrapString(PcoreSensitiveRedacted)
 var hashKey = types.WrapString(PcoreTypeHash)
 
 func (t *rdSerializer) Convert(value px.Value, consumer px.ValueConsumer) {
@@ -137,15 +151,33 @@ func (sc *context) toData(level int, value px.Value) {
 		})
 	case *types.Sensitive:
 		sc.process(value, func() {
-			if sc.config.richData {
+			switch {
+			case !sc.config.richData:
+				sc.unknownToStringWithWarning(level, value)
+			case sc.config.cipher != nil:
+				ct := types.WrapBinary(sc.encryptSensitive(value))
+				sc.addHash(3, func() {
+					sc.toData(2, typeKey)
+					sc.toData(1, sensitiveType)
+					sc.toData(2, keyIdKey)
+					sc.toData(1, types.WrapString(sc.config.cipher.KeyId()))
+					sc.toData(2, valueKey)
+					sc.toData(1, ct)
+				})
+			case sc.config.redactSensitive:
+				sc.addHash(2, func() {
+					sc.toData(2, typeKey)
+					sc.toData(1, sensitiveType)
+					sc.toData(2, valueKey)
+					sc.toData(1, redacted)
+				})
+			default:
 				sc.addHash(2, func() {
 					sc.toData(2, typeKey)
 					sc.toData(1, sensitiveType)
 					sc.toData(2, valueKey)
 					sc.withPath(valueKey, func() { sc.toData(1, value.Unwrap()) })
 				})
-			} else {
-				sc.unknownToStringWithWarning(level, value)
 			}
 		})
 	case *types.Binary:
@@ -165,6 +197,16 @@ func (sc *context) toData(level int, value px.Value) {
 				}
 			}
 		})
+	case *types.Timestamp, types.Timespan:
+		if tc, ok := sc.consumer.(px.TimeValueConsumer); ok && tc.CanDoTime() {
+			sc.process(value, func() {
+				sc.addData(value)
+			})
+		} else if sc.config.richData {
+			sc.valueToDataHash(value)
+		} else {
+			sc.unknownToStringWithWarning(1, value)
+		}
 	default:
 		if sc.config.richData {
 			sc.valueToDataHash(value)
@@ -0,0 +1,292 @@
+// Package tsgen generates TypeScript declarations from pcore types.
+//
+// Generate produces one declaration per type in a TypeSet. The declarations describe the data that a
+// Serializer produces when it streams instances of the types to JSON with rich data disabled. Two
+// constants, typeSetName and typeSetVersion, identify the TypeSet that the declarations were generated
+// from so that a consumer can verify that it is in sync with its producer.
+//
+// An Object type maps to an interface that extends the interface of its parent. Each attribute maps to a
+// property. Constants map to readonly properties and attributes that have a value, including the implicit
+// undef value of an Optional attribute, map to optional properties. Derived attributes are not included.
+// A `description` tag in the TagsAnnotation of an Object type or an attribute is written as a JSDoc
+// comment above the interface or the property.
+//
+// Other types in the TypeSet map to type aliases. Attribute types and aliased types are mapped as follows:
+//
+// Undef maps to null, Boolean to boolean, Integer, Float, and Numeric to number, and String and Pattern
+// to string. Enum maps to a union of string literals and Variant to a union of the mapped types.
+// Optional[T] maps to the union of T and null and NotUndef[T] maps to T.
+//
+// Array[T] maps to T[], Tuple to a tuple type with optional and rest elements that reflect its size, Hash
+// to an object type with an index signature, and Struct to an object literal type.
+//
+// Timestamp, Timespan, SemVer, SemVerRange, URI, Regexp, Binary, and Type map to string since that is
+// how they are represented when rich data is disabled. Object types that are not members of the TypeSet
+// are declared too, using their fully qualified name without separators. All other types map to unknown.
+package tsgen
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// Generate writes TypeScript declarations for all types of the given TypeSet to the given writer.
+func Generate(c px.Context, ts px.TypeSet, out io.Writer) {
+	g := &generator{c: c, names: make(map[string]string)}
+
+	var members []string
+	ts.Types().EachPair(func(k, v px.Value) {
+		n := k.String()
+		members = append(members, n)
+		g.names[v.(px.Type).Name()] = n
+		g.types = append(g.types, v.(px.Type))
+	})
+
+	b := utils.NewIndenter()
+	b.Printf("// Generated by pcore tsgen from the TypeSet %s. Do not edit.\n\n", ts.Name())
+	b.Printf("export const typeSetName = %s;\n", quote(ts.Name()))
+	b.Printf("export const typeSetVersion = %s;\n", quote(ts.Version().String()))
+
+	// The slice of types grows when Object types that are not members are found
+	for i := 0; i < len(g.types); i++ {
+		t := g.types[i]
+		n := g.names[t.Name()]
+		if i < len(members) {
+			n = members[i]
+		}
+		if ot, ok := t.(px.ObjectType); ok {
+			g.writeInterface(b, n, ot)
+		} else {
+			g.writeAlias(b, n, t)
+		}
+	}
+	if _, err := io.WriteString(out, b.String()); err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+}
+
+type generator struct {
+	c     px.Context
+	types []px.Type
+	names map[string]string
+}
+
+func (g *generator) writeAlias(b *utils.Indenter, name string, t px.Type) {
+	if at, ok := t.(*types.TypeAliasType); ok {
+		t = at.ResolvedType()
+	}
+	b.NewLine()
+	b.Printf("export type %s = %s;", name, g.tsType(t))
+	b.NewLine()
+}
+
+func (g *generator) writeInterface(b *utils.Indenter, name string, t px.ObjectType) {
+	b.NewLine()
+	g.writeDoc(b, t)
+	b.Printf("export interface %s", name)
+	if pt, ok := t.Parent().(px.ObjectType); ok {
+		b.Printf(" extends %s", g.objectName(pt))
+	}
+	b.Append(" {")
+	ib := b.Indent()
+	ih := t.(px.PuppetObject).InitHash()
+	for _, key := range []string{`attributes`, `constants`} {
+		ah, ok := ih.Get4(key)
+		if !ok {
+			continue
+		}
+		ah.(px.OrderedMap).EachKey(func(k px.Value) {
+			m, ok := t.Member(k.String())
+			if !ok {
+				return
+			}
+			a, ok := m.(px.Attribute)
+			if !ok || a.Kind() == `derived` {
+				return
+			}
+			ib.NewLine()
+			g.writeDoc(ib, a)
+			if a.Kind() == `constant` {
+				ib.Append(`readonly `)
+			}
+			ib.Append(propertyName(a.Name()))
+			if a.HasValue() && a.Kind() != `constant` {
+				ib.Append(`?`)
+			}
+			ib.Printf(": %s;", g.tsType(a.Type()))
+		})
+	}
+	b.NewLine()
+	b.Append("}")
+	b.NewLine()
+}
+
+// writeDoc writes the description of the given annotatable as a doc comment
+func (g *generator) writeDoc(b *utils.Indenter, a px.Annotatable) {
+	d := types.Description(g.c, a)
+	if d == `` {
+		return
+	}
+	b.Append(`/**`)
+	for _, l := range strings.Split(strings.Replace(d, `*/`, `*\/`, -1), "\n") {
+		b.NewLine()
+		b.Append(` * `)
+		b.Append(l)
+	}
+	b.NewLine()
+	b.Append(` */`)
+	b.NewLine()
+}
+
+// objectName returns the name of the declaration for the given Object type. The type is added to the
+// declarations unless it has been added already.
+func (g *generator) objectName(t px.ObjectType) string {
+	if n, ok := g.names[t.Name()]; ok {
+		return n
+	}
+	n := strings.Replace(t.Name(), `::`, ``, -1)
+	g.names[t.Name()] = n
+	g.types = append(g.types, t)
+	return n
+}
+
+func (g *generator) tsType(t px.Type) string {
+	return strings.Join(g.members(t), ` | `)
+}
+
+// members returns the members of the union that the given type maps to. A type that doesn't map to a union
+// has one member.
+func (g *generator) members(t px.Type) []string {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		if n, ok := g.names[t.Name()]; ok {
+			return []string{n}
+		}
+		return g.members(t.ResolvedType())
+	case px.ObjectType:
+		if t.Name() == `` {
+			return []string{`unknown`}
+		}
+		return []string{g.objectName(t)}
+	case *types.UndefType:
+		return []string{`null`}
+	case *types.OptionalType:
+		return union(g.members(t.ContainedType()), []string{`null`})
+	case *types.NotUndefType:
+		return g.members(t.ContainedType())
+	case *types.BooleanType:
+		return []string{`boolean`}
+	case *types.IntegerType, *types.FloatType, *types.NumericType:
+		return []string{`number`}
+	case *types.EnumType:
+		ss := t.Strings()
+		if len(ss) == 0 {
+			return []string{`string`}
+		}
+		ls := make([]string, len(ss))
+		for i, s := range ss {
+			ls[i] = quote(s)
+		}
+		return union(ls)
+	case px.StringType, *types.PatternType, *types.TimestampType, *types.TimespanType, *types.SemVerType,
+		*types.SemVerRangeType, *types.UriType, *types.RegexpType, *types.BinaryType, *types.TypeType:
+		return []string{`string`}
+	case *types.VariantType:
+		vs := t.Types()
+		ms := make([][]string, len(vs))
+		for i, v := range vs {
+			ms[i] = g.members(v)
+		}
+		return union(ms...)
+	case *types.ArrayType:
+		return []string{arrayOf(g.members(t.ElementType()))}
+	case *types.TupleType:
+		return []string{g.tupleType(t)}
+	case *types.HashType:
+		return []string{fmt.Sprintf(`{ [key: string]: %s }`, g.tsType(t.ValueType()))}
+	case *types.StructType:
+		es := t.Elements()
+		if len(es) == 0 {
+			return []string{`{}`}
+		}
+		ps := make([]string, len(es))
+		for i, se := range es {
+			o := ``
+			if se.Optional() {
+				o = `?`
+			}
+			ps[i] = fmt.Sprintf(`%s%s: %s`, propertyName(se.Name()), o, g.tsType(se.Value()))
+		}
+		return []string{`{ ` + strings.Join(ps, `; `) + ` }`}
+	}
+	return []string{`unknown`}
+}
+
+func (g *generator) tupleType(t *types.TupleType) string {
+	tts := t.Types()
+	if len(tts) == 0 {
+		return `[]`
+	}
+	min := int(t.Size().Min())
+	es := make([]string, 0, len(tts)+1)
+	for i, tt := range tts {
+		ms := g.members(tt)
+		e := strings.Join(ms, ` | `)
+		if i >= min {
+			if len(ms) > 1 {
+				e = `(` + e + `)`
+			}
+			e += `?`
+		}
+		es = append(es, e)
+	}
+	if t.Size().Max() > int64(len(tts)) {
+		es = append(es, `...`+arrayOf(g.members(tts[len(tts)-1])))
+	}
+	return `[` + strings.Join(es, `, `) + `]`
+}
+
+// union returns the members of the union of the given unions. Duplicates are removed.
+func union(ms ...[]string) []string {
+	seen := make(map[string]bool)
+	us := make([]string, 0)
+	for _, m := range ms {
+		for _, s := range m {
+			if !seen[s] {
+				seen[s] = true
+				us = append(us, s)
+			}
+		}
+	}
+	return us
+}
+
+// arrayOf returns an array type with elements of the union of the given members
+func arrayOf(ms []string) string {
+	if len(ms) > 1 {
+		return `(` + strings.Join(ms, ` | `) + `)[]`
+	}
+	return ms[0] + `[]`
+}
+
+var identifier = regexp.MustCompile(`\A[A-Za-z_$][A-Za-z0-9_$]*\z`)
+
+// propertyName returns the given name, quoted unless it is a valid identifier
+func propertyName(n string) string {
+	if identifier.MatchString(n) {
+		return n
+	}
+	return quote(n)
+}
+
+// quote returns a single quoted TypeScript string literal
+func quote(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + `'`
+}
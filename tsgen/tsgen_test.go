@@ -0,0 +1,70 @@
+package tsgen_test
+
+import (
+	"os"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/tsgen"
+)
+
+func ExampleGenerate() {
+	pcore.Do(func(c px.Context) {
+		ts := c.ParseType(`TypeSet[{
+      name => 'My::Shop',
+      pcore_version => '1.0.0',
+      version => '2.1.0',
+      types => {
+        Sku => Pattern[/\A[A-Z]{3}-\d+\z/],
+        Item => {
+          annotations => { TagsAnnotation => { tags => { description => 'An item that can be ordered' }}},
+          attributes => {
+            sku => Sku,
+            price => Float[0.0],
+            tags => { type => Array[String], value => [] },
+            dimensions => Optional[Tuple[Integer, Integer, Optional[Integer], 2, 3]]
+          }
+        },
+        Book => Item{
+          attributes => {
+            title => String,
+            format => Enum[hardcover, paperback, ebook],
+            labels => Array[Enum['new | used', signed]],
+            related => Array[Variant[Book, Sku]],
+            meta => Hash[String, Struct[{ source => String, Optional[confidence] => Float }]]
+          },
+          constants => {
+            category => 'book'
+          }
+        }
+      }
+    }]`).(px.ResolvableType).Resolve(c).(px.TypeSet)
+		tsgen.Generate(c, ts, os.Stdout)
+	})
+	// Output:
+	// // Generated by pcore tsgen from the TypeSet My::Shop. Do not edit.
+	//
+	// export const typeSetName = 'My::Shop';
+	// export const typeSetVersion = '2.1.0';
+	//
+	// export type Sku = string;
+	//
+	// /**
+	//  * An item that can be ordered
+	//  */
+	// export interface Item {
+	//   sku: Sku;
+	//   price: number;
+	//   tags?: string[];
+	//   dimensions?: [number, number, (number | null)?] | null;
+	// }
+	//
+	// export interface Book extends Item {
+	//   title: string;
+	//   format: 'hardcover' | 'paperback' | 'ebook';
+	//   labels: ('new | used' | 'signed')[];
+	//   related: (Book | Sku)[];
+	//   meta: { [key: string]: { source: string; confidence?: number } };
+	//   readonly category: string;
+	// }
+}
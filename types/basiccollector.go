@@ -56,6 +56,12 @@ func (hm *BasicCollector) Add(element px.Value) {
 	hm.values = append(hm.values, element)
 }
 
+// ValueCount returns the number of values that have been added, i.e. the number of values that a reference
+// can refer to
+func (hm *BasicCollector) ValueCount() int {
+	return len(hm.values)
+}
+
 func (hm *BasicCollector) AddRef(ref int) {
 	top := len(hm.stack) - 1
 	hm.stack[top] = append(hm.stack[top], hm.values[ref])
@@ -0,0 +1,166 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/lyraproj/pcore/px"
+)
+
+// Tags that precede each value in the digest encoding
+const (
+	dgUndef     = byte('u')
+	dgDefault   = byte('d')
+	dgTrue      = byte('t')
+	dgFalse     = byte('f')
+	dgInteger   = byte('i')
+	dgFloat     = byte('n')
+	dgString    = byte('s')
+	dgBinary    = byte('b')
+	dgSensitive = byte('S')
+	dgArray     = byte('a')
+	dgHash      = byte('h')
+	dgUnordered = byte('H')
+	dgType      = byte('T')
+	dgObject    = byte('o')
+	dgOther     = byte('v')
+	dgRecursion = byte('r')
+)
+
+func init() {
+	px.Digest = digest
+}
+
+func digest(value px.Value, algorithm func() hash.Hash, unordered bool) []byte {
+	if algorithm == nil {
+		algorithm = sha256.New
+	}
+	h := algorithm()
+	(&digester{algorithm: algorithm, unordered: unordered}).write(h, value)
+	return h.Sum(nil)
+}
+
+type digester struct {
+	algorithm func() hash.Hash
+	unordered bool
+	buf       [9]byte
+
+	// containers that are currently being written
+	stack []px.Value
+}
+
+func (d *digester) write(w hash.Hash, v px.Value) {
+	switch v := v.(type) {
+	case *UndefValue:
+		d.writeTag(w, dgUndef)
+	case *DefaultValue:
+		d.writeTag(w, dgDefault)
+	case booleanValue:
+		if v.Bool() {
+			d.writeTag(w, dgTrue)
+		} else {
+			d.writeTag(w, dgFalse)
+		}
+	case integerValue:
+		d.writeUint(w, dgInteger, uint64(v))
+	case floatValue:
+		f := float64(v)
+		if f == 0 {
+			// Ensure that -0.0 and 0.0 are encoded the same way
+			f = 0
+		}
+		d.writeUint(w, dgFloat, math.Float64bits(f))
+	case stringValue:
+		d.writeBytes(w, dgString, []byte(v))
+	case *Binary:
+		d.writeBytes(w, dgBinary, v.Bytes())
+	case *Sensitive:
+		d.writeTag(w, dgSensitive)
+		d.write(w, v.Unwrap())
+	case *Array:
+		d.writeContainer(w, v, func() {
+			d.writeUint(w, dgArray, uint64(v.Len()))
+			v.Each(func(e px.Value) { d.write(w, e) })
+		})
+	case *Hash:
+		d.writeContainer(w, v, func() { d.writeHash(w, v) })
+	case px.Type:
+		// The expanded form ensures that different definitions of a named type are digested differently
+		d.writeBytes(w, dgType, []byte(px.ToString2(v, Expanded)))
+	case px.PuppetObject:
+		d.writeContainer(w, v, func() {
+			d.writeTag(w, dgObject)
+			d.write(w, v.PType())
+			d.writeHash(w, v.InitHash().(*Hash))
+		})
+	default:
+		d.writeBytes(w, dgOther, []byte(v.PType().Name()))
+		d.writeBytes(w, dgString, []byte(v.String()))
+	}
+}
+
+// writeContainer calls the given function unless the given container is already being written, in
+// which case the recursion is written as the position of the container on the stack.
+func (d *digester) writeContainer(w hash.Hash, v px.Value, f func()) {
+	if reflect.TypeOf(v).Kind() != reflect.Ptr {
+		// Only pointers can be recursive
+		f()
+		return
+	}
+	for i, c := range d.stack {
+		if c == v {
+			d.writeUint(w, dgRecursion, uint64(i))
+			return
+		}
+	}
+	d.stack = append(d.stack, v)
+	f()
+	d.stack = d.stack[:len(d.stack)-1]
+}
+
+// writeHash writes the entries of the given hash. Unordered hashes are written as the sorted
+// digests of each entry.
+func (d *digester) writeHash(w hash.Hash, v *Hash) {
+	if !d.unordered {
+		d.writeUint(w, dgHash, uint64(v.Len()))
+		v.EachPair(func(k, e px.Value) {
+			d.write(w, k)
+			d.write(w, e)
+		})
+		return
+	}
+	ds := make([][]byte, 0, v.Len())
+	v.EachPair(func(k, e px.Value) {
+		h := d.algorithm()
+		d.write(h, k)
+		d.write(h, e)
+		ds = append(ds, h.Sum(nil))
+	})
+	sort.Slice(ds, func(i, j int) bool { return bytes.Compare(ds[i], ds[j]) < 0 })
+	d.writeUint(w, dgUnordered, uint64(len(ds)))
+	for _, ed := range ds {
+		w.Write(ed)
+	}
+}
+
+func (d *digester) writeTag(w hash.Hash, tag byte) {
+	d.buf[0] = tag
+	w.Write(d.buf[:1])
+}
+
+func (d *digester) writeUint(w hash.Hash, tag byte, n uint64) {
+	d.buf[0] = tag
+	binary.BigEndian.PutUint64(d.buf[1:], n)
+	w.Write(d.buf[:])
+}
+
+// writeBytes writes the tag followed by the length of the given bytes and then the bytes
+func (d *digester) writeBytes(w hash.Hash, tag byte, bs []byte) {
+	d.writeUint(w, tag, uint64(len(bs)))
+	w.Write(bs)
+}
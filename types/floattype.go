@@ -6,6 +6,7 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/lyraproj/issue/issue"
@@ -295,7 +296,12 @@ func (fv floatValue) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
 	case 'd', 'x', 'X', 'o', 'b', 'B':
 		integerValue(fv.Int()).ToString(b, px.NewFormatContext(DefaultIntegerType(), f, s.Indentation()), g)
 	case 'p':
-		f.ApplyStringFlags(b, floatGFormat(defaultFormatP, float64(fv)), false)
+		// The exactFloats property is set when the string must read back as the same value
+		if ef, ok := s.Property(`exactFloats`); ok && ef == `true` {
+			f.ApplyStringFlags(b, exactFloat(float64(fv)), false)
+		} else {
+			f.ApplyStringFlags(b, floatGFormat(defaultFormatP, float64(fv)), false)
+		}
 	case 'e', 'E', 'f':
 		_, err := fmt.Fprintf(b, f.OrigFormat(), float64(fv))
 		if err != nil {
@@ -316,6 +322,16 @@ func (fv floatValue) ToString(b io.Writer, s px.FormatContext, g px.RDetect) {
 	}
 }
 
+// exactFloat returns the shortest representation that reads back as the given value. It always contains a
+// decimal point or an exponent so that it isn't read back as an Integer.
+func exactFloat(value float64) string {
+	str := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(str, `.eIN`) {
+		str += `.0`
+	}
+	return str
+}
+
 func floatGFormat(f px.Format, value float64) string {
 	str := fmt.Sprintf(f.WithoutWidth().OrigFormat(), value)
 	sc := byte('e')
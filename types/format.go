@@ -121,6 +121,7 @@ func init() {
 	px.NewFormat = newFormat
 
 	px.PrettyExpanded = px.Pretty.WithProperties(map[string]string{`expanded`: `true`})
+	px.ExactFloats = ExactFloats
 }
 
 var DefaultArrayFormat = basicFormat('a', `,`, '[', nil)
@@ -170,6 +171,19 @@ var None = newFormatContext2(DefaultIndentation, DefaultFormats, nil)
 
 var Expanded = newFormatContext2(DefaultIndentation, DefaultFormats, map[string]string{`expanded`: `true`})
 
+// ExactFloats is like None but writes floats in program format using the shortest representation that reads
+// back as the same value
+var ExactFloats = newFormatContext2(DefaultIndentation, px.FormatMap(WrapHash([]*HashEntry{
+	WrapHashEntry(DefaultObjectType(), DefaultObjectFormat),
+	WrapHashEntry(DefaultTypeType(), DefaultObjectFormat),
+	WrapHashEntry(DefaultFloatType(), DefaultProgramFormat),
+	WrapHashEntry(DefaultNumericType(), simpleFormat('d')),
+	WrapHashEntry(DefaultArrayType(), DefaultArrayFormat),
+	WrapHashEntry(DefaultHashType(), DefaultHashFormat),
+	WrapHashEntry(DefaultBinaryType(), simpleFormat('B')),
+	WrapHashEntry(DefaultAnyType(), DefaultAnyFormat),
+})), map[string]string{`exactFloats`: `true`})
+
 var Program = newFormatContext2(DefaultIndentation, px.FormatMap(singleMap(DefaultAnyType(), DefaultObjectFormat)), nil)
 
 func newFormatContext(t px.Type, format px.Format, indentation px.Indentation) px.FormatContext {
@@ -0,0 +1,337 @@
+package types
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/utils"
+)
+
+// An inferrer infers a type from sample values using the options given to px.InferType
+type inferrer struct {
+	enumLimit   int
+	structLimit int
+	generalize  bool
+}
+
+var inferOptionsType = NewStructType([]*StructElement{
+	newStructElement3(`enum_limit`, NewIntegerType(0, math.MaxInt64), true),
+	newStructElement3(`struct_limit`, NewIntegerType(0, math.MaxInt64), true),
+	newStructElement3(`generalize`, DefaultBooleanType(), true),
+})
+
+func inferType(values []px.Value, options px.OrderedMap) px.Type {
+	if options == nil {
+		options = px.EmptyMap
+	}
+	px.AssertInstance(`InferType options`, inferOptionsType, options)
+	in := &inferrer{
+		enumLimit:   int(options.Get5(`enum_limit`, WrapInteger(8)).(px.Integer).Int()),
+		structLimit: int(options.Get5(`struct_limit`, WrapInteger(32)).(px.Integer).Int()),
+		generalize:  options.Get5(`generalize`, BooleanFalse).(px.Boolean).Bool(),
+	}
+	var t px.Type
+	for _, v := range values {
+		t = in.merge(t, in.valueType(v))
+	}
+	if t == nil {
+		return anyTypeDefault
+	}
+	return in.finish(t)
+}
+
+// valueType returns the type of a single value. Collections are typed by merging the types of their
+// elements. The element type of an empty collection is Unit.
+func (in *inferrer) valueType(v px.Value) px.Type {
+	switch v := v.(type) {
+	case *Array:
+		var et px.Type
+		v.Each(func(e px.Value) { et = in.merge(et, in.valueType(e)) })
+		if et == nil {
+			et = unitTypeDefault
+		}
+		return NewArrayType(et, NewIntegerType(int64(v.Len()), int64(v.Len())))
+	case *Hash:
+		if v.Len() <= in.structLimit && v.AllKeysAreStrings() && !v.Any(func(e px.Value) bool {
+			return e.(px.MapEntry).Key().String() == ``
+		}) {
+			es := make([]*StructElement, 0, v.Len())
+			v.EachPair(func(k, ev px.Value) { es = append(es, newStructElement3(k.String(), in.valueType(ev), false)) })
+			return &StructType{elements: es}
+		}
+		var kt, vt px.Type
+		v.EachPair(func(k, ev px.Value) {
+			kt = in.merge(kt, in.valueType(k))
+			vt = in.merge(vt, in.valueType(ev))
+		})
+		if kt == nil {
+			kt, vt = unitTypeDefault, unitTypeDefault
+		}
+		return NewHashType(kt, vt, NewIntegerType(int64(v.Len()), int64(v.Len())))
+	default:
+		return px.DetailedValueType(v)
+	}
+}
+
+// merge returns a type that both a and b are assignable to. A nil type is the type of no values at all.
+func (in *inferrer) merge(a, b px.Type) px.Type {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.Equals(b, nil) {
+		return a
+	}
+
+	// Unit is the element type of empty collections
+	if _, ok := a.(*UnitType); ok {
+		return b
+	}
+	if _, ok := b.(*UnitType); ok {
+		return a
+	}
+
+	if _, ok := a.(*UndefType); ok {
+		return optional(b)
+	}
+	if _, ok := b.(*UndefType); ok {
+		return optional(a)
+	}
+	if ot, ok := a.(*OptionalType); ok {
+		return optional(in.merge(ot.typ, b))
+	}
+	if ot, ok := b.(*OptionalType); ok {
+		return optional(in.merge(a, ot.typ))
+	}
+
+	if vt, ok := a.(*VariantType); ok {
+		return in.mergeIntoVariant(vt.types, b)
+	}
+	if vt, ok := b.(*VariantType); ok {
+		return in.mergeIntoVariant([]px.Type{a}, vt.types...)
+	}
+
+	if kind(a) != kind(b) {
+		return NewVariantType(a, b)
+	}
+
+	switch at := a.(type) {
+	case *StructType:
+		if bt, ok := b.(*StructType); ok {
+			return in.mergeStructs(at, bt)
+		}
+		return in.mergeHashes(in.toHash(at), b.(*HashType))
+	case *HashType:
+		if bt, ok := b.(*StructType); ok {
+			return in.mergeHashes(at, in.toHash(bt))
+		}
+		return in.mergeHashes(at, b.(*HashType))
+	case *ArrayType:
+		bt := b.(*ArrayType)
+		return NewArrayType(in.merge(at.typ, bt.typ), commonType(at.size, bt.size).(*IntegerType))
+	case *vcStringType, *scStringType, *stringType, *EnumType:
+		return in.mergeStrings(a, b)
+	}
+
+	t := commonType(a, b)
+	if !isAbstract(t) {
+		return t
+	}
+	if t = px.Generalize(a); t.Equals(px.Generalize(b), nil) {
+		// E.g. Boolean[true] and Boolean[false]
+		return t
+	}
+	return NewVariantType(a, b)
+}
+
+// mergeIntoVariant merges each of the given types into the type of the same kind in the given variant
+// types or adds it as a new variant type when there's no such type
+func (in *inferrer) mergeIntoVariant(vts []px.Type, ts ...px.Type) px.Type {
+	vts = append(make([]px.Type, 0, len(vts)+len(ts)), vts...)
+next:
+	for _, t := range ts {
+		for i, vt := range vts {
+			if kind(vt) == kind(t) {
+				vts[i] = in.merge(vt, t)
+				continue next
+			}
+		}
+		vts = append(vts, t)
+	}
+	return NewVariantType(vts...)
+}
+
+// mergeStructs merges the elements that have the same name. Elements that are present in only one of the
+// structs become optional. The result is a Hash when the number of elements exceeds the struct limit.
+func (in *inferrer) mergeStructs(a, b *StructType) px.Type {
+	es := make([]*StructElement, 0, len(a.elements)+len(b.elements))
+	bm := b.HashedMembers()
+	for _, ae := range a.elements {
+		if be, ok := bm[ae.name]; ok {
+			es = append(es, newStructElement3(ae.name, in.merge(ae.value, be.value), ae.Optional() || be.Optional()))
+		} else {
+			es = append(es, newStructElement3(ae.name, ae.value, true))
+		}
+	}
+	am := a.HashedMembers()
+	for _, be := range b.elements {
+		if _, ok := am[be.name]; !ok {
+			es = append(es, newStructElement3(be.name, be.value, true))
+		}
+	}
+	if len(es) > in.structLimit {
+		return in.mergeHashes(in.toHash(a), in.toHash(b))
+	}
+	return &StructType{elements: es}
+}
+
+func (in *inferrer) mergeHashes(a, b *HashType) px.Type {
+	return NewHashType(in.merge(a.keyType, b.keyType), in.merge(a.valueType, b.valueType), commonType(a.size, b.size).(*IntegerType))
+}
+
+// mergeStrings merges two string types. Sets of strings are kept as an Enum as long as they don't exceed
+// the enum limit. Other strings are typed by their size.
+func (in *inferrer) mergeStrings(a, b px.Type) px.Type {
+	if avs, ok := stringValues(a); ok {
+		if bvs, ok := stringValues(b); ok {
+			vs := utils.Unique(append(append(make([]string, 0, len(avs)+len(bvs)), avs...), bvs...))
+			if len(vs) <= in.enumLimit {
+				return NewEnumType(vs, false)
+			}
+			return NewStringType(stringSize(NewEnumType(vs, false)), ``)
+		}
+	}
+	return NewStringType(commonType(stringSize(a), stringSize(b)).(*IntegerType), ``)
+}
+
+// toHash returns the Hash type that corresponds to the given Struct type
+func (in *inferrer) toHash(t *StructType) *HashType {
+	var kt, vt px.Type
+	required := 0
+	for _, e := range t.elements {
+		kt = in.merge(kt, NewStringType(nil, e.name))
+		vt = in.merge(vt, e.value)
+		if !e.Optional() {
+			required++
+		}
+	}
+	if kt == nil {
+		kt, vt = unitTypeDefault, unitTypeDefault
+	}
+	return NewHashType(kt, vt, NewIntegerType(int64(required), int64(len(t.elements))))
+}
+
+// finish replaces the types that are only used while merging, e.g. a single string and Unit, and
+// generalizes scalar types when requested
+func (in *inferrer) finish(t px.Type) px.Type {
+	switch t := t.(type) {
+	case *UnitType:
+		return anyTypeDefault
+	case *OptionalType:
+		return NewOptionalType(in.finish(t.typ))
+	case *VariantType:
+		ts := make([]px.Type, len(t.types))
+		for i, vt := range t.types {
+			ts[i] = in.finish(vt)
+		}
+		return NewVariantType(ts...)
+	case *StructType:
+		if len(t.elements) == 0 {
+			return in.finish(in.toHash(t))
+		}
+		es := make([]*StructElement, len(t.elements))
+		for i, e := range t.elements {
+			es[i] = NewStructElement(e.key, in.finish(e.value))
+		}
+		return NewStructType(es)
+	case *ArrayType:
+		return NewArrayType(in.finish(t.typ), in.finishSize(t.size))
+	case *HashType:
+		return NewHashType(in.finish(t.keyType), in.finish(t.valueType), in.finishSize(t.size))
+	case *vcStringType:
+		if in.enumLimit > 0 {
+			return NewEnumType([]string{t.value}, false)
+		}
+		if !in.generalize {
+			return NewStringType(stringSize(t), ``)
+		}
+	case *EnumType:
+		return t
+	}
+	if in.generalize && isAssignable(scalarTypeDefault, t) {
+		return px.Generalize(t)
+	}
+	return t
+}
+
+func (in *inferrer) finishSize(size *IntegerType) *IntegerType {
+	if in.generalize {
+		return nil
+	}
+	return size
+}
+
+// kind returns a key that is equal for the types that are merged rather than made variants of a Variant
+func kind(t px.Type) string {
+	switch t.(type) {
+	case *vcStringType, *scStringType, *stringType, *EnumType:
+		return `String`
+	case *StructType, *HashType:
+		return `Hash`
+	default:
+		return reflect.TypeOf(t).String()
+	}
+}
+
+// isAbstract answers if the given type is one of the abstract types that commonType returns when it
+// finds no better type
+func isAbstract(t px.Type) bool {
+	switch t {
+	case numericTypeDefault, scalarDataTypeDefault, scalarTypeDefault, dataTypeDefault, richDataTypeDefault, anyTypeDefault:
+		return true
+	}
+	return false
+}
+
+func optional(t px.Type) px.Type {
+	if _, ok := t.(*OptionalType); ok {
+		return t
+	}
+	return NewOptionalType(t)
+}
+
+// stringValues returns the strings of a String type with a value or of a case sensitive Enum
+func stringValues(t px.Type) ([]string, bool) {
+	switch t := t.(type) {
+	case *vcStringType:
+		return []string{t.value}, true
+	case *EnumType:
+		if !t.caseInsensitive {
+			return t.values, true
+		}
+	}
+	return nil, false
+}
+
+// stringSize returns the range of the sizes of the strings that are instances of the given string type
+func stringSize(t px.Type) *IntegerType {
+	if vs, ok := stringValues(t); ok && len(vs) > 0 {
+		min, max := int64(len(vs[0])), int64(len(vs[0]))
+		for _, v := range vs[1:] {
+			if l := int64(len(v)); l < min {
+				min = l
+			} else if l > max {
+				max = l
+			}
+		}
+		return NewIntegerType(min, max)
+	}
+	if st, ok := t.(*scStringType); ok {
+		return st.size
+	}
+	return PositiveIntegerType()
+}
+
+func init() {
+	px.InferType = inferType
+}
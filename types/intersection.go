@@ -0,0 +1,653 @@
+package types
+
+import (
+	"math"
+
+	"github.com/lyraproj/pcore/px"
+)
+
+// intersect returns a type that only the values that are instances of both a and b are instances of
+func intersect(a px.Type, b px.Type) px.Type {
+	if isUnit(a) || isUnit(b) {
+		return unitTypeDefault
+	}
+	if isAssignable(a, b) {
+		return b
+	}
+	if isAssignable(b, a) {
+		return a
+	}
+
+	// Intersection is commutative so types that are decomposed are moved to the left
+	switch b.(type) {
+	case *TypeAliasType, *VariantType, *OptionalType, *NotUndefType, *CollectionType:
+		switch a.(type) {
+		case *TypeAliasType, *VariantType, *OptionalType, *NotUndefType:
+		default:
+			a, b = b, a
+		}
+	}
+
+	switch at := a.(type) {
+	case *TypeAliasType:
+		return intersect(at.ResolvedType(), b)
+
+	case *VariantType:
+		ts := make([]px.Type, 0, len(at.types))
+		for _, t := range at.types {
+			if it := intersect(t, b); !isUnit(it) {
+				ts = append(ts, it)
+			}
+		}
+		return variantOrUnit(ts)
+
+	case *OptionalType:
+		t := intersect(at.typ, b)
+		if !isAssignable(b, undefTypeDefault) {
+			return t
+		}
+		if isUnit(t) {
+			return undefTypeDefault
+		}
+		return NewOptionalType(t)
+
+	case *NotUndefType:
+		return notUndef(intersect(at.typ, b))
+
+	case *CollectionType:
+		return intersectSize(b, at.size)
+
+	case *IntegerType:
+		if bt, ok := b.(*IntegerType); ok {
+			if r, ok := intersectRange(at, bt); ok {
+				return r
+			}
+		}
+
+	case *FloatType:
+		if bt, ok := b.(*FloatType); ok {
+			if min, max := math.Max(at.min, bt.min), math.Min(at.max, bt.max); min <= max {
+				return NewFloatType(min, max)
+			}
+		}
+
+	case *TimestampType:
+		if bt, ok := b.(*TimestampType); ok {
+			min, max := at.min, at.max
+			if bt.min.After(min) {
+				min = bt.min
+			}
+			if bt.max.Before(max) {
+				max = bt.max
+			}
+			if !min.After(max) {
+				return NewTimestampType(min, max)
+			}
+		}
+
+	case *TimespanType:
+		if bt, ok := b.(*TimespanType); ok {
+			min, max := at.min, at.max
+			if bt.min > min {
+				min = bt.min
+			}
+			if bt.max < max {
+				max = bt.max
+			}
+			if min <= max {
+				return NewTimespanType(min, max)
+			}
+		}
+
+	case *vcStringType, *scStringType, *stringType, *EnumType, *PatternType:
+		if isStringKind(b) {
+			return intersectStrings(a, b)
+		}
+
+	case *ArrayType:
+		switch bt := b.(type) {
+		case *ArrayType:
+			return intersectArrays(at, bt)
+		case *TupleType:
+			return intersectTuples(arrayAsTuple(at), bt)
+		}
+
+	case *TupleType:
+		switch bt := b.(type) {
+		case *ArrayType:
+			return intersectTuples(at, arrayAsTuple(bt))
+		case *TupleType:
+			return intersectTuples(at, bt)
+		}
+
+	case *HashType:
+		switch bt := b.(type) {
+		case *HashType:
+			return intersectHashes(at, bt)
+		case *StructType:
+			return intersectStructAndHash(bt, at)
+		}
+
+	case *StructType:
+		switch bt := b.(type) {
+		case *HashType:
+			return intersectStructAndHash(at, bt)
+		case *StructType:
+			return intersectStructs(at, bt)
+		}
+
+	case *TypeType:
+		if bt, ok := b.(*TypeType); ok {
+			if t := intersect(at.typ, bt.typ); !isUnit(t) {
+				return NewTypeType(t)
+			}
+		}
+	}
+
+	// The intersection of types of a known kind that is not computed above is empty. Other intersections
+	// are approximated by the type that is of a known kind, or by a when neither of them is
+	if disjoint(a, b) {
+		return unitTypeDefault
+	}
+	if valueKind(a) == vkNone && valueKind(b) != vkNone {
+		return b
+	}
+	return a
+}
+
+// Kinds of values that are returned by valueKind
+const (
+	vkNone = iota
+	vkUndef
+	vkDefault
+	vkBoolean
+	vkInteger
+	vkFloat
+	vkString
+	vkRegexp
+	vkTimestamp
+	vkTimespan
+	vkArray
+	vkHash
+	vkType
+	vkBinary
+	vkSemVerRange
+
+	// Kinds for which intersect doesn't compute the intersection of two types of the same kind
+	vkSemVer
+	vkUri
+	vkSensitive
+)
+
+// valueKind returns the kind of all instances of the given type, or vkNone when the instances can be of
+// different kinds
+func valueKind(t px.Type) int {
+	switch t.(type) {
+	case *UndefType:
+		return vkUndef
+	case *DefaultType:
+		return vkDefault
+	case *BooleanType:
+		return vkBoolean
+	case *IntegerType:
+		return vkInteger
+	case *FloatType:
+		return vkFloat
+	case *vcStringType, *scStringType, *stringType, *EnumType, *PatternType:
+		return vkString
+	case *RegexpType:
+		return vkRegexp
+	case *TimestampType:
+		return vkTimestamp
+	case *TimespanType:
+		return vkTimespan
+	case *ArrayType, *TupleType:
+		return vkArray
+	case *HashType, *StructType:
+		return vkHash
+	case *TypeType:
+		return vkType
+	case *BinaryType:
+		return vkBinary
+	case *SemVerRangeType:
+		return vkSemVerRange
+	case *SemVerType:
+		return vkSemVer
+	case *UriType:
+		return vkUri
+	case *SensitiveType:
+		return vkSensitive
+	}
+	return vkNone
+}
+
+// disjoint answers whether two types that aren't assignable to each other and whose intersection isn't
+// computed by intersect have no instances in common. That is the case when both types are of different known
+// kinds or of the same kind for which intersect computes intersections, or when one of them is of a known
+// kind and the other is a union of kinds that doesn't include it.
+func disjoint(a, b px.Type) bool {
+	ak, bk := valueKind(a), valueKind(b)
+	switch {
+	case ak != vkNone && bk != vkNone:
+		return ak != bk || ak < vkSemVer
+	case ak != vkNone:
+		return isKindUnion(b)
+	case bk != vkNone:
+		return isKindUnion(a)
+	}
+	return false
+}
+
+// isKindUnion answers whether the given type is an abstract type that accepts all values of some kinds
+func isKindUnion(t px.Type) bool {
+	switch t.(type) {
+	case *NumericType, *ScalarDataType, *ScalarType:
+		return true
+	}
+	return false
+}
+
+// intersectStrings intersects two string types. The intersection of a set of strings and another type
+// is the strings that are instances of that type.
+func intersectStrings(a, b px.Type) px.Type {
+	avs, aci, aok := stringValueSet(a)
+	bvs, bci, bok := stringValueSet(b)
+	switch {
+	case aok && (!aci || !bok || bci):
+		return filterStrings(avs, aci, b, true)
+	case bok:
+		return filterStrings(bvs, bci, a, true)
+	}
+
+	if _, ok := a.(*PatternType); ok {
+		return a
+	}
+	if _, ok := b.(*PatternType); ok {
+		return b
+	}
+	if r, ok := intersectRange(stringSize(a), stringSize(b)); ok {
+		return NewStringType(r, ``)
+	}
+	return unitTypeDefault
+}
+
+// filterStrings returns an Enum with the given strings that are instances (or not, depending on keep) of
+// the given type
+func filterStrings(vs []string, caseInsensitive bool, t px.Type, keep bool) px.Type {
+	kept := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if isInstance(t, stringValue(v)) == keep {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		return unitTypeDefault
+	}
+	return NewEnumType(kept, caseInsensitive)
+}
+
+func intersectArrays(a, b *ArrayType) px.Type {
+	sz, ok := intersectRange(a.size, b.size)
+	if !ok {
+		return unitTypeDefault
+	}
+	et := intersect(a.typ, b.typ)
+	if isUnit(et) {
+		return emptyOrUnit(sz, NewArrayType(unitTypeDefault, IntegerTypeZero))
+	}
+	return NewArrayType(et, sz)
+}
+
+func intersectHashes(a, b *HashType) px.Type {
+	sz, ok := intersectRange(a.size, b.size)
+	if !ok {
+		return unitTypeDefault
+	}
+	kt := intersect(a.keyType, b.keyType)
+	vt := intersect(a.valueType, b.valueType)
+	if isUnit(kt) || isUnit(vt) {
+		return emptyOrUnit(sz, emptyHashType())
+	}
+	return NewHashType(kt, vt, sz)
+}
+
+// intersectTuples intersects the element types at each position. A position where that intersection is
+// empty limits the size of the tuple.
+func intersectTuples(a, b *TupleType) px.Type {
+	sz, ok := intersectRange(a.givenOrActualSize, b.givenOrActualSize)
+	if !ok {
+		return unitTypeDefault
+	}
+	n := len(a.types)
+	if len(b.types) > n {
+		n = len(b.types)
+	}
+	ts := make([]px.Type, 0, n)
+	for i := 0; i < n && int64(i) < sz.max; i++ {
+		t := intersect(tupleTypeAt(a, i), tupleTypeAt(b, i))
+		if isUnit(t) {
+			if int64(i) < sz.min {
+				return unitTypeDefault
+			}
+			sz = NewIntegerType(sz.min, int64(i))
+			break
+		}
+		ts = append(ts, t)
+	}
+	if sz.max == 0 {
+		return EmptyTupleType()
+	}
+	if sz.min == sz.max && sz.max == int64(len(ts)) {
+		return NewTupleType(ts, nil)
+	}
+	return NewTupleType(ts, sz)
+}
+
+// intersectStructs intersects the value types of elements with the same name. Since a Struct doesn't
+// allow keys other than those of its elements, elements that aren't present in both structs must be
+// optional and are then omitted.
+func intersectStructs(a, b *StructType) px.Type {
+	bm := b.HashedMembers()
+	es := make([]*StructElement, 0, len(a.elements))
+	for _, ae := range a.elements {
+		be, ok := bm[ae.name]
+		if !ok {
+			if !ae.Optional() {
+				return unitTypeDefault
+			}
+			continue
+		}
+		optional := ae.Optional() && be.Optional()
+		vt := intersect(ae.value, be.value)
+		if isUnit(vt) {
+			if !optional {
+				return unitTypeDefault
+			}
+			continue
+		}
+		es = append(es, newStructElement3(ae.name, vt, optional))
+	}
+	am := a.HashedMembers()
+	for _, be := range b.elements {
+		if _, ok := am[be.name]; !ok && !be.Optional() {
+			return unitTypeDefault
+		}
+	}
+	return structOrEmptyHash(es)
+}
+
+func intersectStructAndHash(s *StructType, h *HashType) px.Type {
+	es := make([]*StructElement, 0, len(s.elements))
+	required := 0
+	for _, e := range s.elements {
+		vt := intersect(e.value, h.valueType)
+		if isUnit(vt) || !isInstance(h.keyType, stringValue(e.name)) {
+			if !e.Optional() {
+				return unitTypeDefault
+			}
+			continue
+		}
+		if !e.Optional() {
+			required++
+		}
+		es = append(es, newStructElement3(e.name, vt, e.Optional()))
+	}
+	if int64(required) > h.size.max || int64(len(es)) < h.size.min {
+		return unitTypeDefault
+	}
+	return structOrEmptyHash(es)
+}
+
+// intersectSize intersects the given collection type with a Collection of the given size
+func intersectSize(t px.Type, size *IntegerType) px.Type {
+	switch t := t.(type) {
+	case *ArrayType:
+		return intersectArrays(t, NewArrayType(anyTypeDefault, size))
+	case *HashType:
+		return intersectHashes(t, NewHashType(anyTypeDefault, anyTypeDefault, size))
+	case *TupleType:
+		return intersectTuples(t, NewTupleType([]px.Type{anyTypeDefault}, size))
+	case *StructType:
+		return intersectStructAndHash(t, NewHashType(anyTypeDefault, anyTypeDefault, size))
+	case *CollectionType:
+		if r, ok := intersectRange(t.size, size); ok {
+			return NewCollectionType(r)
+		}
+	}
+	return unitTypeDefault
+}
+
+// subtract returns a type that only the values that are instances of a but not of b are instances of
+func subtract(a px.Type, b px.Type) px.Type {
+	if isUnit(a) || isAssignable(b, a) {
+		return unitTypeDefault
+	}
+	if isUnit(b) || isUnit(intersect(a, b)) {
+		return a
+	}
+
+	switch at := a.(type) {
+	case *TypeAliasType:
+		return subtract(at.ResolvedType(), b)
+
+	case *VariantType:
+		ts := make([]px.Type, 0, len(at.types))
+		for _, t := range at.types {
+			if st := subtract(t, b); !isUnit(st) {
+				ts = append(ts, st)
+			}
+		}
+		return variantOrUnit(ts)
+
+	case *OptionalType:
+		t := subtract(at.typ, b)
+		if isAssignable(b, undefTypeDefault) {
+			return t
+		}
+		if isUnit(t) {
+			return undefTypeDefault
+		}
+		return NewOptionalType(t)
+
+	case *NotUndefType:
+		return notUndef(subtract(at.typ, b))
+	}
+
+	switch bt := b.(type) {
+	case *TypeAliasType:
+		return subtract(a, bt.ResolvedType())
+
+	case *VariantType:
+		t := a
+		for _, vt := range bt.types {
+			if t = subtract(t, vt); isUnit(t) {
+				break
+			}
+		}
+		return t
+
+	case *OptionalType:
+		return notUndef(subtract(a, bt.typ))
+
+	case *UndefType:
+		return notUndef(a)
+	}
+
+	switch at := a.(type) {
+	case *IntegerType:
+		if bt, ok := b.(*IntegerType); ok {
+			return variantOrUnit(subtractRange(at, bt))
+		}
+
+	case *FloatType:
+		if bt, ok := b.(*FloatType); ok {
+			ts := make([]px.Type, 0, 2)
+			if at.min < bt.min {
+				ts = append(ts, NewFloatType(at.min, math.Nextafter(bt.min, math.Inf(-1))))
+			}
+			if at.max > bt.max {
+				ts = append(ts, NewFloatType(math.Nextafter(bt.max, math.Inf(1)), at.max))
+			}
+			return variantOrUnit(ts)
+		}
+
+	case *vcStringType, *EnumType:
+		vs, ci, _ := stringValueSet(a)
+		return filterStrings(vs, ci, b, false)
+
+	case *stringType, *scStringType:
+		if bt, ok := b.(*scStringType); ok {
+			rs := subtractRange(at.(px.SizedType).Size().(*IntegerType), bt.size)
+			for i, r := range rs {
+				rs[i] = NewStringType(r.(*IntegerType), ``)
+			}
+			return variantOrUnit(rs)
+		}
+
+	case *ArrayType:
+		// Arrays of a size that is excluded can only be removed when all their elements are excluded
+		if bt, ok := b.(*ArrayType); ok && isAssignable(bt.typ, at.typ) {
+			rs := subtractRange(at.size, bt.size)
+			for i, r := range rs {
+				rs[i] = NewArrayType(at.typ, r.(*IntegerType))
+			}
+			return variantOrUnit(rs)
+		}
+
+	case *HashType:
+		if bt, ok := b.(*HashType); ok && isAssignable(bt.keyType, at.keyType) && isAssignable(bt.valueType, at.valueType) {
+			rs := subtractRange(at.size, bt.size)
+			for i, r := range rs {
+				rs[i] = NewHashType(at.keyType, at.valueType, r.(*IntegerType))
+			}
+			return variantOrUnit(rs)
+		}
+	}
+	return a
+}
+
+// intersectRange returns the intersection of two integer ranges and true, or nil and false when the ranges
+// don't overlap
+func intersectRange(a, b *IntegerType) (*IntegerType, bool) {
+	min, max := a.min, a.max
+	if b.min > min {
+		min = b.min
+	}
+	if b.max < max {
+		max = b.max
+	}
+	if min > max {
+		return nil, false
+	}
+	return NewIntegerType(min, max), true
+}
+
+// subtractRange returns the zero, one, or two ranges that remain when b is removed from a
+func subtractRange(a, b *IntegerType) []px.Type {
+	rs := make([]px.Type, 0, 2)
+	if a.min < b.min {
+		rs = append(rs, NewIntegerType(a.min, b.min-1))
+	}
+	if a.max > b.max {
+		rs = append(rs, NewIntegerType(b.max+1, a.max))
+	}
+	return rs
+}
+
+// notUndef returns the given type without undef
+func notUndef(t px.Type) px.Type {
+	switch t := t.(type) {
+	case *UndefType:
+		return unitTypeDefault
+	case *OptionalType:
+		return notUndef(t.typ)
+	case *VariantType:
+		ts := make([]px.Type, 0, len(t.types))
+		for _, vt := range t.types {
+			if nt := notUndef(vt); !isUnit(nt) {
+				ts = append(ts, nt)
+			}
+		}
+		return variantOrUnit(ts)
+	}
+	if !isUnit(t) && isAssignable(t, undefTypeDefault) {
+		return NewNotUndefType(t)
+	}
+	return t
+}
+
+func isUnit(t px.Type) bool {
+	_, ok := t.(*UnitType)
+	return ok
+}
+
+func isStringKind(t px.Type) bool {
+	switch t.(type) {
+	case *vcStringType, *scStringType, *stringType, *EnumType, *PatternType:
+		return true
+	}
+	return false
+}
+
+// stringValueSet returns the strings of a String type with a value or of an Enum with values, and
+// whether or not the strings are case insensitive
+func stringValueSet(t px.Type) ([]string, bool, bool) {
+	switch t := t.(type) {
+	case *vcStringType:
+		return []string{t.value}, false, true
+	case *EnumType:
+		if len(t.values) > 0 {
+			return t.values, t.caseInsensitive, true
+		}
+	}
+	return nil, false, false
+}
+
+func variantOrUnit(ts []px.Type) px.Type {
+	if len(ts) == 0 {
+		return unitTypeDefault
+	}
+	return NewVariantType(UniqueTypes(ts)...)
+}
+
+// emptyOrUnit returns the given type for empty collections when the given size allows empty collections,
+// and Unit otherwise
+func emptyOrUnit(size *IntegerType, empty px.Type) px.Type {
+	if size.min == 0 {
+		return empty
+	}
+	return unitTypeDefault
+}
+
+func emptyHashType() *HashType {
+	return NewHashType(unitTypeDefault, unitTypeDefault, IntegerTypeZero)
+}
+
+func structOrEmptyHash(es []*StructElement) px.Type {
+	if len(es) == 0 {
+		return emptyHashType()
+	}
+	return NewStructType(es)
+}
+
+func arrayAsTuple(t *ArrayType) *TupleType {
+	return NewTupleType([]px.Type{t.typ}, t.size)
+}
+
+// tupleTypeAt returns the type of the element at the given position. The last type applies to all
+// positions after it.
+func tupleTypeAt(t *TupleType, i int) px.Type {
+	switch {
+	case len(t.types) == 0:
+		return anyTypeDefault
+	case i < len(t.types):
+		return t.types[i]
+	default:
+		return t.types[len(t.types)-1]
+	}
+}
+
+func init() {
+	px.Intersect = intersect
+	px.Subtract = subtract
+}
@@ -444,6 +444,32 @@ func (r *reflector) TypeSetFromReflect(typeSetName string, version semver.Versio
 	return NewTypeSet(px.RuntimeNameAuthority, typeSetName, WrapHash(es))
 }
 
+func newGoTypeSet(typeSetName string, version semver.Version, aliases map[string]string, rTypes ...reflect.Type) px.TypeSet {
+	types := make([]*HashEntry, 0, len(rTypes))
+	prefix := typeSetName + `::`
+	for _, rt := range rTypes {
+		obj := AllocObjectType()
+		obj.name = typeName(prefix, aliases, rt)
+		if pt := ParentType(rt); pt != nil {
+			obj.parent = NewTypeReferenceType(typeName(prefix, aliases, pt))
+		}
+		obj.initHashExpression = newTaggedType(rt, nil)
+
+		// Register the mapping up front so that the types can reference each other regardless of order
+		registerMapping(obj, rt)
+		types = append(types, WrapHashEntry2(obj.name[strings.LastIndex(obj.name, `::`)+2:], obj))
+	}
+
+	es := make([]*HashEntry, 0)
+	es = append(es, WrapHashEntry2(px.KeyPcoreUri, stringValue(string(px.PcoreUri))))
+	es = append(es, WrapHashEntry2(px.KeyPcoreVersion, WrapSemVer(px.PcoreVersion)))
+	es = append(es, WrapHashEntry2(KeyVersion, WrapSemVer(version)))
+	es = append(es, WrapHashEntry2(KeyTypes, WrapHash(types)))
+	ts := NewTypeSet(px.RuntimeNameAuthority, typeSetName, WrapHash(es))
+	registerResolvableType(ts.(px.ResolvableType))
+	return ts
+}
+
 func ParentType(t reflect.Type) reflect.Type {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -84,6 +84,16 @@ func newStructElement2(key string, value px.Type) *StructElement {
 	return NewStructElement(stringValue(key), value)
 }
 
+// newStructElement3 creates an element with the given name that is optional or required regardless of
+// whether or not the value type accepts undef
+func newStructElement3(name string, value px.Type, optional bool) *StructElement {
+	var kt px.Type = NewStringType(nil, name)
+	if optional {
+		kt = NewOptionalType(kt)
+	}
+	return NewStructElement(kt, value)
+}
+
 func DefaultStructType() *StructType {
 	return structTypeDefault
 }
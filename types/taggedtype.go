@@ -59,6 +59,15 @@ func NewTagsAnnotation(tags px.OrderedMap) px.TagsAnnotation {
 	return &tagsAnnotation{tags}
 }
 
+// Description returns the `description` tag of the TagsAnnotation of the given annotatable, or an empty
+// string when it has no such tag
+func Description(c px.Context, a px.Annotatable) string {
+	if ta, ok := a.Annotations(c).Get(TagsAnnotationType); ok {
+		return ta.(px.TagsAnnotation).Tag(`description`)
+	}
+	return ``
+}
+
 func (c *tagsAnnotation) Equals(value interface{}, guard px.Guard) bool {
 	if oc, ok := value.(*tagsAnnotation); ok {
 		return c.tags.Equals(oc.tags, guard)
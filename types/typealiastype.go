@@ -163,7 +163,11 @@ func (t *TypeAliasType) ToString(b io.Writer, s px.FormatContext, g px.RDetect)
 	if t.name == `UnresolvedAlias` {
 		utils.WriteString(b, `TypeAlias`)
 	} else {
-		utils.WriteString(b, t.name)
+		name := t.name
+		if ts, ok := s.Property(`typeSet`); ok {
+			name = stripTypeSetName(ts, name)
+		}
+		utils.WriteString(b, name)
 		if !(f.IsAlt() && f.FormatChar() == 'b') {
 			return
 		}
@@ -415,6 +415,7 @@ func init() {
 	px.NewGoObjectType = newGoObjectType
 	px.NewNamedType = newNamedType
 	px.NewGoType = newGoType
+	px.NewGoTypeSet = newGoTypeSet
 	px.RegisterResolvableType = registerResolvableType
 	px.NewGoConstructor = newGoConstructor
 	px.NewGoConstructor2 = newGoConstructor2
@@ -61,3 +61,25 @@ func TestIsAssignable(t *testing.T) {
 		t.Error(`Unit not assignable to Any`)
 	}
 }
+
+func ExampleWrapRegexp() {
+	for _, p := range []string{`\A[A-Z]{3}-\d+\z`, `a\\b`, `a/b`} {
+		r := types.WrapRegexp(p)
+		fmt.Println(r, types.Parse(r.String()).Equals(r, nil))
+	}
+	// Output:
+	// /\A[A-Z]{3}-\d+\z/ true
+	// /a\\b/ true
+	// /a\/b/ true
+}
+
+func ExampleExactFloats() {
+	for _, f := range []float64{2, 0.1, 1e21, 1.0 / 3} {
+		fmt.Println(px.ToString2(types.WrapFloat(f), types.ExactFloats))
+	}
+	// Output:
+	// 2.0
+	// 0.1
+	// 1e+21
+	// 0.3333333333333333
+}
@@ -128,9 +128,18 @@ func CapitalizeSegments(segment string) string {
 	return ``
 }
 
+// RegexpQuote writes the given regular expression as a regexp literal, i.e. enclosed in slashes. Escape
+// sequences in the expression are retained verbatim so that the literal is parsed back into an equal
+// expression.
 func RegexpQuote(b io.Writer, str string) {
 	WriteByte(b, '/')
+	escaped := false
 	for _, c := range str {
+		if escaped {
+			escaped = false
+			WriteRune(b, c)
+			continue
+		}
 		switch c {
 		case '\t':
 			WriteString(b, `\t`)
@@ -141,7 +150,9 @@ func RegexpQuote(b io.Writer, str string) {
 		case '/':
 			WriteString(b, `\/`)
 		case '\\':
-			WriteString(b, `\\`)
+			// Written together with the next character
+			escaped = true
+			WriteByte(b, '\\')
 		default:
 			if c < 0x20 {
 				_, err := fmt.Fprintf(b, `\u{%X}`, c)
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexpQuote(t *testing.T) {
+	for _, tc := range [][2]string{
+		{`^[a-z]+$`, `/^[a-z]+$/`},
+		{`\A[A-Z]{3}-\d+\z`, `/\A[A-Z]{3}-\d+\z/`},
+		{`a\\b`, `/a\\b/`},
+		{`a/b\/c`, `/a\/b\/c/`},
+		{"a\tb\n", `/a\tb\n/`},
+	} {
+		b := bytes.NewBufferString(``)
+		RegexpQuote(b, tc[0])
+		require.Equal(t, tc[1], b.String())
+	}
+}
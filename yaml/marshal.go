@@ -0,0 +1,240 @@
+package yaml
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	ym "gopkg.in/yaml.v3"
+)
+
+// Marshal serializes the given value into a YAML document using a serialization.Serializer
+// with default options and a Streamer with default options.
+func Marshal(c px.Context, value px.Value) []byte {
+	b := bytes.NewBufferString(``)
+	serialization.NewSerializer(c, px.EmptyMap).Convert(value, NewStreamer(b, px.EmptyMap))
+	return b.Bytes()
+}
+
+type streamer struct {
+	out      io.Writer
+	flow     bool
+	typeTags bool
+	indent   int
+	nodes    []*ym.Node
+	stack    []*ym.Node
+	root     *ym.Node
+}
+
+// NewStreamer creates a new streamer that will produce a YAML document when receiving values.
+// The document is written to the given writer when the top level value is complete.
+//
+// References are written as aliases of anchors that are added to the referenced nodes. All
+// strings are quoted when needed so that they are read back as strings by Unmarshal.
+//
+// Valid options are:
+//
+// style: 'block' or 'flow', default 'block'. Determines the style used for arrays and hashes.
+//
+// indent: Integer, default 2. The number of spaces used for each indentation level.
+//
+// type_tags: Boolean, default true. Write hashes that represent serialized rich data, i.e. hashes
+// with a __ptype key that maps to a type name, as nodes tagged with that type name. A hash with
+// a __pvalue key is written as the value of that key, other hashes are written without the __ptype
+// key. Example: {__ptype: Timestamp, __pvalue: '2019-01-01T00:00:00.000000000 UTC'} is written as
+// !Timestamp '2019-01-01T00:00:00.000000000 UTC'.
+func NewStreamer(out io.Writer, options px.OrderedMap) px.ValueConsumer {
+	style := options.Get5(`style`, types.WrapString(`block`)).String()
+	switch style {
+	case `block`, `flow`:
+	default:
+		panic(px.Error(px.IllegalArgument, issue.H{`function`: `yaml.NewStreamer`, `index`: 1, `arg`: `style must be 'block' or 'flow'`}))
+	}
+	return &streamer{
+		out:      out,
+		flow:     style == `flow`,
+		typeTags: options.Get5(`type_tags`, types.BooleanTrue).(px.Boolean).Bool(),
+		indent:   int(options.Get5(`indent`, types.WrapInteger(2)).(px.Integer).Int()),
+		nodes:    make([]*ym.Node, 0, 64),
+		stack:    make([]*ym.Node, 0, 8)}
+}
+
+func (s *streamer) AddArray(len int, doer px.Doer) {
+	s.addCollection(&ym.Node{Kind: ym.SequenceNode, Tag: `!!seq`, Content: make([]*ym.Node, 0, len)}, doer)
+}
+
+func (s *streamer) AddHash(len int, doer px.Doer) {
+	s.addCollection(&ym.Node{Kind: ym.MappingNode, Tag: `!!map`, Content: make([]*ym.Node, 0, len*2)}, doer)
+}
+
+func (s *streamer) Add(element px.Value) {
+	s.nodes = append(s.nodes, s.addNode(scalarNode(element)))
+	s.flushIfDone()
+}
+
+func (s *streamer) AddRef(ref int) {
+	n := s.nodes[ref]
+	if n.Anchor == `` {
+		n.Anchor = `ref` + strconv.Itoa(ref)
+	}
+	s.addNode(&ym.Node{Kind: ym.AliasNode, Value: n.Anchor, Alias: n})
+	s.flushIfDone()
+}
+
+func (s *streamer) CanDoBinary() bool {
+	return true
+}
+
+func (s *streamer) CanDoComplexKeys() bool {
+	return true
+}
+
+func (s *streamer) StringDedupThreshold() int {
+	return 20
+}
+
+func (s *streamer) addCollection(n *ym.Node, doer px.Doer) {
+	if s.flow {
+		n.Style = ym.FlowStyle
+	}
+	s.nodes = append(s.nodes, s.addNode(n))
+	s.stack = append(s.stack, n)
+	doer()
+	s.stack = s.stack[:len(s.stack)-1]
+	s.flushIfDone()
+}
+
+func (s *streamer) addNode(n *ym.Node) *ym.Node {
+	top := len(s.stack) - 1
+	if top < 0 {
+		s.root = n
+	} else {
+		p := s.stack[top]
+		p.Content = append(p.Content, n)
+	}
+	return n
+}
+
+func (s *streamer) flushIfDone() {
+	if len(s.stack) > 0 {
+		return
+	}
+	if s.typeTags {
+		s.root = typeTags(s.root)
+	}
+	e := ym.NewEncoder(s.out)
+	e.SetIndent(s.indent)
+	err := e.Encode(s.root)
+	if err == nil {
+		err = e.Close()
+	}
+	if err != nil {
+		panic(px.Error(px.Failure, issue.H{`message`: err.Error()}))
+	}
+	s.root = nil
+	s.nodes = s.nodes[:0]
+}
+
+// typeTags replaces all mappings in the given node and its children that represent serialized rich
+// data with nodes tagged with a type name.
+func typeTags(n *ym.Node) *ym.Node {
+	if n.Kind != ym.SequenceNode && n.Kind != ym.MappingNode {
+		return n
+	}
+	for i, cn := range n.Content {
+		n.Content[i] = typeTags(cn)
+	}
+	if n.Kind == ym.MappingNode {
+		n = typeTagged(n)
+	}
+	return n
+}
+
+func typeTagged(n *ym.Node) *ym.Node {
+	cs := n.Content
+	if len(cs) < 2 || !isString(cs[0], serialization.PcoreTypeKey) {
+		return n
+	}
+	tn := cs[1]
+	if tn.Kind == ym.AliasNode {
+		tn = tn.Alias
+	}
+	if tn.Kind != ym.ScalarNode || tn.Tag != `!!str` {
+		// Type is not given as a name
+		return n
+	}
+	for _, c := range cs {
+		if c.Anchor != `` {
+			// Aliases elsewhere refer to contents of this mapping so it must stay intact
+			return n
+		}
+	}
+
+	var r *ym.Node
+	switch {
+	case len(cs) == 4 && isString(cs[2], serialization.PcoreValueKey):
+		r = cs[3]
+		if r.Kind == ym.AliasNode {
+			if r.Alias.Kind != ym.ScalarNode {
+				return n
+			}
+			c := *r.Alias
+			c.Anchor = ``
+			r = &c
+		}
+	case len(cs) == 2 && tn.Value == serialization.PcoreTypeDefault:
+		r = &ym.Node{Kind: ym.ScalarNode}
+	default:
+		r = &ym.Node{Kind: ym.MappingNode, Style: n.Style, Content: cs[2:]}
+	}
+	r.Tag = `!` + tn.Value
+	r.Anchor = n.Anchor
+	return r
+}
+
+func isString(n *ym.Node, s string) bool {
+	return n.Kind == ym.ScalarNode && n.Tag == `!!str` && n.Value == s
+}
+
+func scalarNode(v px.Value) *ym.Node {
+	n := &ym.Node{Kind: ym.ScalarNode}
+	switch v := v.(type) {
+	case px.StringValue:
+		// The encoder will quote the string if it can be mistaken for something else
+		n.Tag = `!!str`
+		n.Value = v.String()
+	case px.Integer:
+		n.Tag = `!!int`
+		n.Value = strconv.FormatInt(v.Int(), 10)
+	case px.Float:
+		n.Tag = `!!float`
+		n.Value = formatFloat(v.Float())
+	case px.Boolean:
+		n.Tag = `!!bool`
+		n.Value = strconv.FormatBool(v.Bool())
+	case *types.Binary:
+		n.Tag = `!!binary`
+		n.Value = v.SerializationString()
+	default:
+		n.Tag = `!!null`
+		n.Value = `null`
+	}
+	return n
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return `.inf`
+	case math.IsInf(f, -1):
+		return `-.inf`
+	case math.IsNaN(f):
+		return `.nan`
+	}
+	return px.ToString2(types.WrapFloat(f), px.ExactFloats)
+}
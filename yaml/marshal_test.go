@@ -0,0 +1,102 @@
+package yaml_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/pcore/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		v := yaml.Unmarshal(c, []byte(text))
+		require.Equal(t, "foo:\n  bar:\n  - 1\n  - 2\n  - hello: sub\nbar:\n- 1\n- 2\n", string(yaml.Marshal(c, v)))
+	})
+}
+
+func TestMarshal_quotedStrings(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		v := types.WrapValues([]px.Value{
+			types.WrapString(`true`),
+			types.WrapString(`12`),
+			types.WrapString(`1.5`),
+			types.WrapString(`null`),
+			types.WrapString(`~`),
+			types.WrapString(`a: b`),
+			types.WrapString(`- x`),
+			types.WrapString(" leading and trailing "),
+			types.WrapString("two\nlines\n"),
+			types.WrapString(``),
+			types.WrapInteger(12),
+			types.WrapFloat(2),
+			types.BooleanTrue,
+			px.Undef,
+		})
+		require.True(t, v.Equals(yaml.Unmarshal(c, yaml.Marshal(c, v)), nil))
+	})
+}
+
+func TestNewStreamer_anchors(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		a := types.WrapStringToInterfaceMap(c, map[string]interface{}{`a`: `b`})
+		v := types.WrapValues([]px.Value{a, a})
+		b := bytes.NewBufferString(``)
+		serialization.NewSerializer(c, px.EmptyMap).Convert(v, yaml.NewStreamer(b, px.EmptyMap))
+		require.Equal(t, "- &ref1\n  a: b\n- *ref1\n", b.String())
+		require.True(t, v.Equals(yaml.Unmarshal(c, b.Bytes()), nil))
+	})
+}
+
+func TestNewStreamer_flow(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		v := yaml.Unmarshal(c, []byte(text))
+		b := bytes.NewBufferString(``)
+		serialization.NewSerializer(c, px.EmptyMap).Convert(v, yaml.NewStreamer(b, px.SingletonMap(`style`, types.WrapString(`flow`))))
+		require.Equal(t, "{foo: {bar: [1, 2, {hello: sub}]}, bar: [1, 2]}\n", b.String())
+	})
+}
+
+func TestMarshal_typeTags(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, px.NewObjectType(`My::Type`, `{attributes => {a => Integer, b => Optional[String]}}`))
+		v := yaml.Unmarshal(c, []byte(`
+ts: !Timestamp 2019-01-01T00:00:00Z
+secret: !Sensitive hush
+dflt: !Default
+obj: !My::Type {a: 1}
+ver: !SemVer 1.0.0
+type: !Type Struct[a => String]
+`))
+		require.Equal(t,
+			`{'ts' => 2019-01-01T00:00:00.000000000 UTC, 'secret' => Sensitive [value redacted], 'dflt' => default, `+
+				`'obj' => My::Type('a' => 1), 'ver' => SemVer('1.0.0'), 'type' => Struct[{'a' => String}]}`, v.String())
+
+		y := yaml.Marshal(c, v)
+		require.Equal(t, `ts: !Timestamp 2019-01-01T00:00:00.000000000 UTC
+secret: !Sensitive hush
+dflt: !Default
+obj: !My::Type
+  a: 1
+ver: !SemVer 1.0.0
+type: !Type Struct[{'a' => String}]
+`, string(y))
+		require.Equal(t, v.String(), yaml.Unmarshal(c, y).String())
+	})
+}
+
+func TestUnmarshal_foreignTags(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		v := yaml.Unmarshal(c, []byte(`
+id: !Ref MyBucket
+arn: !GetAtt [MyBucket, Arn]
+other: !No::Such::Type {a: 1}
+ver: !SemVer 1.0.0
+`))
+		require.Equal(t, `{'id' => 'MyBucket', 'arn' => ['MyBucket', 'Arn'], 'other' => {'a' => 1}, 'ver' => SemVer('1.0.0')}`, v.String())
+	})
+}
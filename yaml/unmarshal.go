@@ -56,9 +56,14 @@ func UnmarshalWithPositions(c px.Context, data []byte) *Value {
 }
 
 func wrapNode(c px.Context, n *ym.Node) (v px.Value) {
+	if t, ok := typeTag(c, n.Tag); ok {
+		return wrapTagged(c, t, n)
+	}
 	switch n.Kind {
 	case ym.DocumentNode:
 		v = wrapNode(c, n.Content[0])
+	case ym.AliasNode:
+		v = wrapNode(c, n.Alias)
 	case ym.SequenceNode:
 		ms := n.Content
 		es := make([]px.Value, len(ms))
@@ -81,9 +86,14 @@ func wrapNode(c px.Context, n *ym.Node) (v px.Value) {
 }
 
 func wrapNodeWithPosition(c px.Context, n *ym.Node) (v *Value) {
+	if t, ok := typeTag(c, n.Tag); ok {
+		return &Value{wrapTagged(c, t, n), n.Line, n.Column}
+	}
 	switch n.Kind {
 	case ym.DocumentNode:
 		v = wrapNodeWithPosition(c, n.Content[0])
+	case ym.AliasNode:
+		v = &Value{wrapNodeWithPosition(c, n.Alias).Value, n.Line, n.Column}
 	case ym.SequenceNode:
 		ms := n.Content
 		es := make([]px.Value, len(ms))
@@ -105,6 +115,50 @@ func wrapNodeWithPosition(c px.Context, n *ym.Node) (v *Value) {
 	return
 }
 
+// typeTag returns the pcore type named by the given tag and true if the tag is a local tag, i.e. a tag
+// that starts with a single '!', and the type is known. Other local tags, such as the !Ref of an AWS
+// CloudFormation template, are ignored.
+func typeTag(c px.Context, tag string) (t px.Type, ok bool) {
+	if !(len(tag) > 1 && tag[0] == '!' && tag[1] != '!' && tag[1] != '<') {
+		return nil, false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isReported := r.(issue.Reported); !isReported {
+				panic(r)
+			}
+			t, ok = nil, false
+		}
+	}()
+	t = c.ParseType(tag[1:])
+	_, unresolved := t.(*types.TypeReferenceType)
+	return t, !unresolved
+}
+
+// wrapTagged creates an instance of the given type, named by the tag of the given node. A scalar node is
+// passed as a string argument to the type's constructor, a sequence as an Array, and a mapping as
+// a Hash or, when the type is an Object type without a hash constructor, as positional arguments.
+func wrapTagged(c px.Context, t px.Type, n *ym.Node) px.Value {
+	if _, ok := t.(*types.DefaultType); ok {
+		return types.WrapDefault()
+	}
+
+	un := *n
+	un.Tag = ``
+	switch n.Kind {
+	case ym.MappingNode:
+		args := wrapNode(c, &un).(*types.Hash)
+		if ot, ok := t.(px.ObjectType); ok && !ot.HasHashConstructor() {
+			return px.New(c, t, ot.AttributesInfo().PositionalFromHash(args)...)
+		}
+		return px.New(c, t, args)
+	case ym.SequenceNode:
+		return px.New(c, t, wrapNode(c, &un))
+	default:
+		return px.New(c, t, types.WrapString(n.Value))
+	}
+}
+
 func wrapScalar(c px.Context, n *ym.Node) px.Value {
 	var v px.Value
 	switch n.Tag {
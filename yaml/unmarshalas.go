@@ -0,0 +1,194 @@
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// UnmarshalAs reads YAML from data and coerces the result into an instance of the given type using
+// types.CoerceTo.
+//
+// The coercion and validation is made in one pass that doesn't stop at the first mismatch. If one or
+// more mismatches are found, this function panics with a px.TypeMismatches error. The `issues`
+// argument of that error contains one px.TypeMismatch error per mismatch, each with a location that
+// denotes the line and column of the offending YAML node.
+func UnmarshalAs(c px.Context, data []byte, typ px.Type) px.Value {
+	td := &typedDecoder{c: c}
+	v := td.coerce(nil, typ, UnmarshalWithPositions(c, data))
+	if len(td.issues) > 0 {
+		panic(px.Error(px.TypeMismatches, issue.H{`count`: len(td.issues), `issues`: td.issues}))
+	}
+	return v
+}
+
+type typedDecoder struct {
+	c      px.Context
+	issues []issue.Reported
+}
+
+func (td *typedDecoder) coerce(path []string, typ px.Type, v *Value) px.Value {
+	uv := v.Unwrap()
+	if typ.IsInstance(uv, nil) {
+		return uv
+	}
+
+	switch t := typ.(type) {
+	case *types.TypeAliasType:
+		return td.coerce(path, t.ResolvedType(), v)
+	case *types.OptionalType:
+		return td.coerce(path, t.ContainedType(), v)
+	case *types.NotUndefType:
+		if _, ok := uv.(*types.UndefValue); !ok {
+			return td.coerce(path, t.ContainedType(), v)
+		}
+	case *types.VariantType:
+		// First variant that can be coerced without mismatches wins
+		for _, vt := range t.Types() {
+			vd := &typedDecoder{c: td.c}
+			if cv := vd.coerce(path, vt, v); len(vd.issues) == 0 {
+				return cv
+			}
+		}
+	case *types.ArrayType:
+		if a, ok := v.Value.(*types.Array); ok {
+			et := t.ElementType()
+			cv := mapElements(a, func(e *Value, i int) px.Value {
+				return td.coerce(withPath(path, strconv.Itoa(i)), et, e)
+			})
+			if !t.Size().IsInstance3(a.Len()) {
+				td.mismatch(path, t, cv, v)
+			}
+			return cv
+		}
+	case *types.TupleType:
+		ts := t.Types()
+		if a, ok := v.Value.(*types.Array); ok && len(ts) > 0 {
+			cv := mapElements(a, func(e *Value, i int) px.Value {
+				ti := i
+				if ti >= len(ts) {
+					ti = len(ts) - 1
+				}
+				return td.coerce(withPath(path, strconv.Itoa(i)), ts[ti], e)
+			})
+			if !t.Size().IsInstance3(a.Len()) {
+				td.mismatch(path, t, cv, v)
+			}
+			return cv
+		}
+	case *types.HashType:
+		if h, ok := v.Value.(*types.Hash); ok {
+			kt := t.KeyType()
+			vt := t.ValueType()
+			cv := h.MapEntries(func(e px.MapEntry) px.MapEntry {
+				kv := td.coerce(withPath(path, `key`), kt, e.Key().(*Value))
+				return types.WrapHashEntry(kv, td.coerce(withPath(path, kv.String()), vt, e.Value().(*Value)))
+			})
+			if !t.Size().IsInstance3(h.Len()) {
+				td.mismatch(path, t, cv, v)
+			}
+			return cv
+		}
+	case *types.StructType:
+		if h, ok := v.Value.(*types.Hash); ok {
+			hm := t.HashedMembers()
+			cv := h.MapEntries(func(e px.MapEntry) px.MapEntry {
+				k := e.Key().(*Value)
+				uk := k.Unwrap()
+				if se, ok := hm[uk.String()]; ok {
+					if _, ok = uk.(px.StringValue); ok {
+						return types.WrapHashEntry(uk, td.coerce(withPath(path, uk.String()), se.Value(), e.Value().(*Value)))
+					}
+				}
+				td.report(k, path, fmt.Sprintf(`unrecognized key '%s'`, uk))
+				return types.WrapHashEntry(uk, e.Value().(*Value).Unwrap())
+			})
+			for _, se := range t.Elements() {
+				if !se.Optional() {
+					if _, ok := h.Get4(se.Name()); !ok {
+						td.report(v, path, fmt.Sprintf(`expects a value for key '%s'`, se.Name()))
+					}
+				}
+			}
+			return cv
+		}
+	case px.ObjectType:
+		if h, ok := v.Value.(*types.Hash); ok {
+			ai := t.AttributesInfo()
+			np := ai.NameToPos()
+			attrs := ai.Attributes()
+			count := len(td.issues)
+			cv := h.MapEntries(func(e px.MapEntry) px.MapEntry {
+				k := e.Key().(*Value)
+				uk := k.Unwrap()
+				if i, ok := np[uk.String()]; ok {
+					return types.WrapHashEntry(uk, td.coerce(withPath(path, uk.String()), attrs[i].Type(), e.Value().(*Value)))
+				}
+				td.report(k, path, fmt.Sprintf(`unrecognized key '%s'`, uk))
+				return types.WrapHashEntry(uk, e.Value().(*Value).Unwrap())
+			})
+			for _, a := range attrs {
+				if !a.HasValue() {
+					if _, ok := h.Get4(a.Name()); !ok {
+						td.report(v, path, fmt.Sprintf(`expects a value for key '%s'`, a.Name()))
+					}
+				}
+			}
+			if count == len(td.issues) {
+				return td.protect(v, func() px.Value { return px.New(td.c, t, cv) })
+			}
+			return cv
+		}
+	}
+
+	if !types.CanCoerce(typ, uv) {
+		td.mismatch(path, typ, uv, v)
+		return uv
+	}
+	return td.protect(v, func() px.Value { return types.CoerceTo(td.c, strings.Join(path, `/`), typ, uv) })
+}
+
+// protect calls the given function and returns its result. A px.TypeMismatch error raised by the
+// function is recorded using the position of the given value.
+func (td *typedDecoder) protect(v *Value, f func() px.Value) (cv px.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			ri, ok := r.(issue.Reported)
+			if !ok {
+				panic(r)
+			}
+			td.issues = append(td.issues, ri.WithLocation(issue.NewLocation(``, v.Line, v.Column)))
+			cv = v.Unwrap()
+		}
+	}()
+	return f()
+}
+
+// mismatch reports a mismatch between the given type and the coerced value relative to the given path
+func (td *typedDecoder) mismatch(path []string, t px.Type, cv px.Value, v *Value) {
+	td.report(v, nil, px.DescribeMismatch2(strings.Join(path, `/`), t, px.DetailedValueType(cv)))
+}
+
+func (td *typedDecoder) report(v *Value, path []string, detail string) {
+	if len(path) > 0 {
+		detail = strings.Join(path, `/`) + ` ` + detail
+	}
+	td.issues = append(td.issues, px.Error2(issue.NewLocation(``, v.Line, v.Column), px.TypeMismatch, issue.H{`detail`: detail}))
+}
+
+func mapElements(a *types.Array, f func(e *Value, i int) px.Value) px.Value {
+	es := make([]px.Value, a.Len())
+	a.EachWithIndex(func(e px.Value, i int) { es[i] = f(e.(*Value), i) })
+	return types.WrapValues(es)
+}
+
+func withPath(path []string, n string) []string {
+	np := make([]string, len(path)+1)
+	copy(np, path)
+	np[len(path)] = n
+	return np
+}
@@ -0,0 +1,52 @@
+package yaml_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/internal/testutil"
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalAs(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		v := yaml.UnmarshalAs(c, []byte(`
+name: app
+port: '8080'
+started: 2019-01-01T00:00:00Z
+tags: [a, b]
+`), c.ParseType(`Struct[name => String, port => Integer, started => Timestamp, tags => Array[String], Optional[debug] => Boolean]`))
+		require.Equal(t, `{'name' => 'app', 'port' => 8080, 'started' => 2019-01-01T00:00:00.000000000 UTC, 'tags' => ['a', 'b']}`, v.String())
+	})
+}
+
+func TestUnmarshalAs_mismatches(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		r := testutil.Reported(func() {
+			yaml.UnmarshalAs(c, []byte(`
+port: eighty
+ports: [80, x]
+extra: true
+`), c.ParseType(`Struct[name => String, port => Integer, ports => Array[Integer]]`))
+		})
+		require.Equal(t, issue.Code(px.TypeMismatches), r.Code())
+		issues := r.Argument(`issues`).([]issue.Reported)
+		require.Equal(t, 4, len(issues))
+		require.Equal(t, `Type mismatch: port expects an Integer value, got String (line: 2, column: 7)`, issues[0].Error())
+		require.Equal(t, `Type mismatch: ports/1 expects an Integer value, got String (line: 3, column: 13)`, issues[1].Error())
+		require.Equal(t, `Type mismatch: unrecognized key 'extra' (line: 4, column: 1)`, issues[2].Error())
+		require.Equal(t, `Type mismatch: expects a value for key 'name' (line: 2, column: 1)`, issues[3].Error())
+		require.Contains(t, r.Error(), `Found 4 type mismatch(es):`)
+	})
+}
+
+func TestUnmarshalAs_object(t *testing.T) {
+	pcore.Do(func(c px.Context) {
+		px.AddTypes(c, px.NewObjectType(`My::Server`, `{attributes => {host => String, port => {type => Integer, value => 80}}}`))
+		v := yaml.UnmarshalAs(c, []byte("- host: example.com\n- {host: example.org, port: '8080'}\n"), c.ParseType(`Array[My::Server]`))
+		require.Equal(t, `[My::Server('host' => 'example.com'), My::Server('host' => 'example.org', 'port' => 8080)]`, v.String())
+	})
+}